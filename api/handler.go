@@ -1,11 +1,14 @@
 package api
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"github.com/akhettar/app-features-manager/features"
 	"github.com/akhettar/app-features-manager/model"
 	"github.com/akhettar/app-features-manager/repository"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -13,6 +16,10 @@ import (
 	echoSwagger "github.com/swaggo/echo-swagger"
 	"net/http"
 	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -28,9 +35,28 @@ var (
 
 	// CustomerID header
 	CustomerID = "CUSTOMER_ID"
+
+	// AcceptLanguage header
+	AcceptLanguage = "Accept-Language"
+
+	// SessionID header
+	SessionID = "X-Session-ID"
+
+	// VariantsParam the query parameter opting into the toggles holding the variants of the flags
+	VariantsParam = "variants"
+
+	// DebugParam the query parameter opting into the reasons the flags were forced off
+	DebugParam = "debug"
+
+	// FlagPropertyPrefix the prefix of the headers holding custom properties the flags are evaluated against, e.g.
+	// "X-Flag-Country: fr" for the property "country"
+	FlagPropertyPrefix = "X-Flag-"
 )
 
 const (
+	// InternalErrorMessage the message of the internal server errors, the cause being logged rather than returned
+	InternalErrorMessage = "Internal server error"
+
 	// ServiceName the service name used by the vault config.
 	ServiceName = "app-status-api"
 
@@ -60,17 +86,29 @@ const (
 
 	// IdentityKey the key id defined in the JWT token
 	IdentityKey = "id"
+
+	// SubjectKey the standard subject claim of the JWT token, used when the token carries no id
+	SubjectKey = "sub"
+
+	// AnonymousActor the actor recorded in the audit trail when the JWT token identifies nobody
+	AnonymousActor = "anonymous"
+
+	// AppsKey the claim of the JWT token listing the apps the token is allowed to manage
+	AppsKey = "apps"
+
+	// AllApps the value of the apps claim granting access to every app
+	AllApps = "*"
 )
 
 // AppVersionHandler the app status handler
 type AppVersionHandler struct {
 	repository.Repository
-	features.UnleashService
+	features.Provider
 }
 
 // NewAppStatusHandler creates an instance of AppVersionHandler
-func NewAppStatusHandler(repo repository.Repository, unleashClient features.UnleashService) *AppVersionHandler {
-	return &AppVersionHandler{repo, unleashClient}
+func NewAppStatusHandler(repo repository.Repository, provider features.Provider) *AppVersionHandler {
+	return &AppVersionHandler{repo, provider}
 }
 
 func hello(c echo.Context) error {
@@ -81,12 +119,18 @@ func hello(c echo.Context) error {
 func (handler *AppVersionHandler) CreateRouter() *echo.Echo {
 
 	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(middleware.RequestID())
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 
 	// register validator
 	validate := validator.New()
 	validate.RegisterStructValidation(model.ReleaseRequestStructLevelValidation, model.ReleaseRequest{})
+	validate.RegisterStructValidation(model.ThresholdsRequestStructLevelValidation, model.ThresholdsRequest{})
+	validate.RegisterStructValidation(model.StatusUpdateRequestStructLevelValidation, model.StatusUpdateRequest{})
+	validate.RegisterStructValidation(model.RollbackRequestStructLevelValidation, model.RollbackRequest{})
+	validate.RegisterStructValidation(model.FlagRequestStructLevelValidation, model.FlagRequest{})
 	e.Validator = &model.ReleaseRequestValidator{validate}
 
 	// JWT Auth middleware
@@ -98,48 +142,190 @@ func (handler *AppVersionHandler) CreateRouter() *echo.Echo {
 		SigningKey: []byte(key),
 	})
 
-	// Define the routes
-	e.GET("/status/version/:version/:platform", handler.getAppFeatures, middlewareFunc)
-	e.POST("/status", handler.publishAppStatus, middlewareFunc)
+	// Define the routes, served for the default app as well as for each app under /apps/{appId}
+	handler.routes(e, middlewareFunc)
+	handler.routes(e.Group("/apps/:"+model.AppID, validateApp), middlewareFunc)
 	e.GET("/health", handler.Health)
+	e.GET("/cache", handler.CacheStats, middlewareFunc, authorizeApp)
+	e.GET("/impressions", handler.ImpressionStats, middlewareFunc, authorizeApp)
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 	return e
 }
 
+// router the routes registry of either the echo instance or a group of routes
+type router interface {
+	Add(method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) *echo.Route
+}
+
+// Registers the routes of the status API. Every route requires a valid JWT token, and the routes managing the
+// releases additionally require the token to be granted access to the app.
+func (handler *AppVersionHandler) routes(r router, auth echo.MiddlewareFunc) {
+	query := func(method, path string, h func(*AppVersionHandler, echo.Context) error) {
+		r.Add(method, path, handler.bind(h), auth)
+	}
+	manage := func(method, path string, h func(*AppVersionHandler, echo.Context) error) {
+		r.Add(method, path, handler.bind(h), auth, authorizeApp)
+	}
+	query(http.MethodGet, "/status/version/:version/:platform", (*AppVersionHandler).getAppFeatures)
+	manage(http.MethodPost, "/status", (*AppVersionHandler).publishAppStatus)
+	manage(http.MethodPost, "/status/thresholds", (*AppVersionHandler).publishThresholds)
+	manage(http.MethodGet, "/status/thresholds/:platform", (*AppVersionHandler).getThresholds)
+	manage(http.MethodGet, "/status/releases", (*AppVersionHandler).listReleases)
+	manage(http.MethodGet, "/status/releases/:platform/:version", (*AppVersionHandler).getReleaseHistory)
+	manage(http.MethodPut, "/status/releases/:platform/:version", (*AppVersionHandler).updateReleaseStatus)
+	manage(http.MethodDelete, "/status/releases/:platform/:version", (*AppVersionHandler).deleteRelease)
+	manage(http.MethodGet, "/status/ranges/:platform", (*AppVersionHandler).getReleaseHistory)
+	manage(http.MethodPut, "/status/ranges/:platform", (*AppVersionHandler).updateReleaseStatus)
+	manage(http.MethodDelete, "/status/ranges/:platform", (*AppVersionHandler).deleteRelease)
+	manage(http.MethodGet, "/status/transitions", (*AppVersionHandler).listTransitions)
+	manage(http.MethodPost, "/status/rollback", (*AppVersionHandler).rollback)
+	manage(http.MethodGet, "/audit", (*AppVersionHandler).getAudit)
+	manage(http.MethodGet, "/flags", (*AppVersionHandler).listFlags)
+	manage(http.MethodGet, "/flags/:"+model.FlagName, (*AppVersionHandler).getFlag)
+	manage(http.MethodPut, "/flags/:"+model.FlagName, (*AppVersionHandler).saveFlag)
+	manage(http.MethodDelete, "/flags/:"+model.FlagName, (*AppVersionHandler).deleteFlag)
+}
+
+// Binds the handler method to the data and the flags of the app addressed by the request
+func (handler *AppVersionHandler) bind(method func(*AppVersionHandler, echo.Context) error) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return method(handler.forApp(c), c)
+	}
+}
+
+// Returns the handler scoped to the app addressed by the request, or the handler itself for the default app
+func (handler *AppVersionHandler) forApp(c echo.Context) *AppVersionHandler {
+	app := c.Param(model.AppID)
+	if app == "" {
+		return handler
+	}
+	return &AppVersionHandler{handler.Repository.ForApp(app), handler.Provider.ForApp(app)}
+}
+
+// Rejects the requests addressing an invalid app identifier
+func validateApp(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, err := model.App(c.Param(model.AppID)).Value(); err != nil {
+			return errorResponse(err.Error(), http.StatusBadRequest, c)
+		}
+		return next(c)
+	}
+}
+
+// Restricts the management of the app addressed by the request to the tokens granted access to it by their apps
+// claim, either by listing the app or the wildcard. Tokens without the claim can only manage the default app.
+func authorizeApp(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		app := c.Param(model.AppID)
+		granted, restricted := grantedApps(c)
+		if !restricted && app == "" {
+			return next(c)
+		}
+		for _, g := range granted {
+			if g == AllApps || (g == app && app != "") {
+				return next(c)
+			}
+		}
+		if app == "" {
+			app = "the default app"
+		}
+		return errorResponse(fmt.Sprintf("The token is not allowed to manage %s", app), http.StatusForbidden, c)
+	}
+}
+
 // @Summary Get App Status
 // @ID get-app-status
-// @Description Query app status for a given app release version
+// @Description Query app status for a given app release version. When the version was not published explicitly
+// @Description its status is resolved from the published version range policies, the most specific range winning,
+// @Description and failing that derived from the version thresholds of the platform. The upgrade message published
+// @Description with the status is localized from the Accept-Language header, falling back to less specific languages
+// @Description (e.g. fr-CA then fr) and finally to the default message. The flags are evaluated against the version
+// @Description and platform of the app, the customer, session, address and locale of the request, and the custom
+// @Description properties given by the X-Flag- headers, e.g. "X-Flag-Country: fr". The flags are flagged stale when
+// @Description evaluated from the last toggles known or from their defaults, the flag provider being unreachable.
+// @Description The flags which could not be evaluated in time are left out of the flags, their errors being returned
+// @Description in flagErrors. The flags whose prerequisites are not all enabled are disabled, the reason being returned
+// @Description in reasons when debugging.
 // @Accept  json
 // @Produce  json
 // @Param version path string true "app version"
 // @Param platform path string true "App platform IOS, Android"
+// @Param at query string false "Query the status the version had at this RFC3339 timestamp"
+// @Param Accept-Language header string false "Preferred languages of the upgrade message, e.g. fr-CA, fr;q=0.9, en;q=0.8"
+// @Param CUSTOMER_ID header string false "Customer the flags are evaluated for"
+// @Param X-Session-ID header string false "Session the flags are evaluated for"
+// @Param variants query bool false "Return the toggles holding the variant and payload of every flag along with the flags"
+// @Param debug query bool false "Return the reasons the flags were forced off along with the flags"
 // @Success 200 {object} model.ReleaseResponse	"ok"
 // @Failure 400 {object} model.ErrorResponse "Bad request"
 // @Failure 404 {object} model.ReleaseResponse	"not found"
 // @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
 // @Router /status/version/{version}/{platform} [get]
 func (handler *AppVersionHandler) getAppFeatures(c echo.Context) error {
+	ctx := c.Request().Context()
 	version := c.Param(model.AppVersion)
 	platform, err := model.Platform(c.Param(model.AppPlatform)).Value()
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
 	}
 	log.Printf("Received request to retrieve app status for given version \"%v\" and platform  \"%v", version, platform)
 	httpResponse := http.StatusOK
-	result, err := handler.Find(version, platform)
+	var result model.ReleaseResponse
+	if at := c.QueryParam("at"); at != "" {
+		t, parseErr := time.Parse(time.RFC3339, at)
+		if parseErr != nil {
+			return errorResponse("The at parameter must be an RFC3339 timestamp", http.StatusBadRequest, c)
+		}
+		result, err = handler.FindAt(ctx, version, platform, t)
+	} else {
+		result, err = handler.Find(ctx, version, platform)
+	}
 	if err != nil {
-		if err.Error() == repository.NotFoundErrorMessage {
+		if errors.Is(err, repository.ErrNotFound) {
 			httpResponse = http.StatusNotFound
 			result = model.ReleaseResponse{
 				Status: model.Supported,
 			}
 		} else {
-			return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: http.StatusInternalServerError, Message: err.Error()})
+			return err
 		}
 	}
+	result.Localize(c.Request().Header.Get(AcceptLanguage))
+	c.Response().Header().Add(echo.HeaderVary, AcceptLanguage)
 
-	// Fetch all the features
-	result.Flags = handler.FetchFeatureFlags(c.Request().Header.Get(CustomerID))
+	// Fetch all the features, with their variants for the clients opting into them
+	evaluation := evaluationContext(version, platform, c)
+	var evaluationErr error
+	variants, _ := strconv.ParseBool(c.QueryParam(VariantsParam))
+	debug, _ := strconv.ParseBool(c.QueryParam(DebugParam))
+	if variants || debug {
+		var toggles features.Toggles
+		toggles, evaluationErr = handler.FetchToggles(ctx, evaluation)
+		result.Flags = toggles.Flags()
+		if variants {
+			result.Toggles = toggles
+		}
+		if debug {
+			result.Reasons = toggles.Reasons()
+		}
+	} else {
+		result.Flags, evaluationErr = handler.FetchFeatureFlags(ctx, evaluation)
+	}
+	if evaluationErr != nil {
+		log.Warnf("Failed to evaluate the flags of version %q on %q: %v", version, platform, evaluationErr)
+		var failures features.EvaluationError
+		if errors.As(evaluationErr, &failures) {
+			result.FlagErrors = make(map[string]string, len(failures))
+			for flag, err := range failures {
+				result.FlagErrors[flag] = err.Error()
+			}
+		}
+	}
+	if provider, ok := handler.Provider.(interface{ Stale() bool }); ok {
+		result.Stale = provider.Stale()
+	}
 
 	// return response to the client
 	return c.JSON(httpResponse, result)
@@ -147,20 +333,23 @@ func (handler *AppVersionHandler) getAppFeatures(c echo.Context) error {
 
 // @Summary Publish App status
 // @ID publish-app-status
-// @Description Publish a new app status
+// @Description Publish a new app status for either a single version or a semantic version range
 // @Accept  json
 // @Produce  json
 // @Param status-request body model.ReleaseRequest true "New App Status"
 // @Success 204
 // @Failure 400 {object} model.ErrorResponse "Bad request"
 // @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
 // @Router /status/ [post]
 func (handler *AppVersionHandler) publishAppStatus(c echo.Context) error {
+	ctx := c.Request().Context()
 
 	// unmarshal the request
 	request := new(model.ReleaseRequest)
 
-	if err := c.Bind(&request); err != nil {
+	if err := c.Bind(request); err != nil {
 		log.Error(err.Error())
 		return errorResponse("Failed to parse json request", http.StatusBadRequest, c)
 	}
@@ -168,13 +357,573 @@ func (handler *AppVersionHandler) publishAppStatus(c echo.Context) error {
 	if err := c.Validate(request); err != nil {
 		return errorResponse(err.Error(), http.StatusBadRequest, c)
 	}
-	log.Infof("Received request to publish app status for given version \"%v\" and platform  \"%v", request.Version, request.Platform)
+	log.Infof("Received request to publish app status for given version \"%v\", range \"%v\" and platform  \"%v",
+		request.Version, request.Range, request.Platform)
 
 	// persist the release
-	err := handler.Insert(&model.ReleaseDAO{Version: request.Version, Platform: request.Platform, Released: time.Now(), Status: request.Status})
+	release := model.ReleaseDAO{Version: request.Version, Range: request.Range, Platform: request.Platform,
+		Released: time.Now(), Status: request.Status, Schedule: request.Schedule, Messaging: request.Messaging}
+	previous := handler.currentStatus(ctx, release.Key())
+	err := handler.Insert(ctx, &release)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: http.StatusInternalServerError, Message: "Insert failed"})
+		return err
 	}
+	handler.audit(c, model.AuditDAO{Action: model.AuditPublish, Version: release.Version, Range: release.Range,
+		Platform: release.Platform, PreviousStatus: previous, NewStatus: release.Status, Schedule: release.Schedule,
+		Reason: request.Reason})
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Publish version thresholds
+// @ID publish-thresholds
+// @Description Declare the minimum supported, minimum recommended and latest versions of a platform
+// @Accept  json
+// @Produce  json
+// @Param thresholds-request body model.ThresholdsRequest true "New version thresholds"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
+// @Router /status/thresholds [post]
+func (handler *AppVersionHandler) publishThresholds(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	// unmarshal the request
+	request := new(model.ThresholdsRequest)
+
+	if err := c.Bind(request); err != nil {
+		log.Error(err.Error())
+		return errorResponse("Failed to parse json request", http.StatusBadRequest, c)
+	}
+
+	if err := c.Validate(request); err != nil {
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
+	}
+	log.Infof("Received request to publish version thresholds for platform \"%v\"", request.Platform)
+
+	// persist the thresholds
+	var previous *model.Thresholds
+	if thresholds, err := handler.FindThresholds(ctx, request.Platform, time.Now()); err == nil {
+		previous = &thresholds.Thresholds
+	}
+	err := handler.InsertThresholds(ctx, model.ThresholdsDAO{Platform: request.Platform, Thresholds: request.Thresholds,
+		Messaging: request.Messaging, Released: time.Now()})
+	if err != nil {
+		return err
+	}
+	handler.audit(c, model.AuditDAO{Action: model.AuditThresholds, Platform: request.Platform,
+		PreviousThresholds: previous, NewThresholds: &request.Thresholds, Reason: request.Reason})
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Get version thresholds
+// @ID get-thresholds
+// @Description Query the version thresholds currently declared for a platform
+// @Produce  json
+// @Param platform path string true "App platform IOS, Android"
+// @Success 200 {object} model.ThresholdsRequest "ok"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 404 {object} model.ErrorResponse "not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
+// @Router /status/thresholds/{platform} [get]
+func (handler *AppVersionHandler) getThresholds(c echo.Context) error {
+	ctx := c.Request().Context()
+	platform, err := model.Platform(c.Param(model.AppPlatform)).Value()
+	if err != nil {
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
+	}
+	thresholds, err := handler.FindThresholds(ctx, platform, time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errorResponse("No version thresholds declared for platform "+platform, http.StatusNotFound, c)
+		}
+		return err
+	}
+	return c.JSON(http.StatusOK, model.ThresholdsRequest{Platform: thresholds.Platform, Thresholds: thresholds.Thresholds,
+		Messaging: thresholds.Messaging})
+}
+
+// @Summary List releases
+// @ID list-releases
+// @Description List the releases in their current state, the most recently released first
+// @Produce  json
+// @Param platform query string false "App platform IOS, Android"
+// @Param status query string false "Current status of the releases"
+// @Param page query int false "Page number starting from 1"
+// @Param limit query int false "Number of releases per page, at most 100"
+// @Param order query string false "Sort order of the released date: asc or desc"
+// @Success 200 {object} model.ReleaseListResponse "ok"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
+// @Router /status/releases [get]
+func (handler *AppVersionHandler) listReleases(c echo.Context) error {
+	ctx := c.Request().Context()
+	var filter model.ReleaseFilter
+
+	if platform := c.QueryParam(model.AppPlatform); platform != "" {
+		if _, err := model.Platform(platform).Value(); err != nil {
+			return errorResponse(err.Error(), http.StatusBadRequest, c)
+		}
+		filter.Platform = platform
+	}
+	if status := c.QueryParam("status"); status != "" {
+		if _, err := model.Status(status).Value(); err != nil {
+			return errorResponse(err.Error(), http.StatusBadRequest, c)
+		}
+		filter.Status = status
+	}
+	page, limit, err := pagination(c)
+	if err != nil {
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
+	}
+	filter.Page, filter.Limit = page, limit
+	switch c.QueryParam("order") {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		return errorResponse("Supported values:asc,desc", http.StatusBadRequest, c)
+	}
+
+	releases, total, err := handler.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+	response := model.ReleaseListResponse{Releases: make([]model.ReleaseRecord, 0, len(releases)), Page: filter.Page,
+		Limit: filter.Limit, Total: total}
+	for _, release := range releases {
+		response.Releases = append(response.Releases, release.Record())
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Get release history
+// @ID get-release-history
+// @Description Query the current status of a release along with every state it was published with. Range
+// @Description policies are addressed by /status/ranges/{platform}?range={range}.
+// @Produce  json
+// @Param platform path string true "App platform IOS, Android"
+// @Param version path string true "app version"
+// @Success 200 {object} model.ReleaseHistoryResponse "ok"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 404 {object} model.ErrorResponse "not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
+// @Router /status/releases/{platform}/{version} [get]
+func (handler *AppVersionHandler) getReleaseHistory(c echo.Context) error {
+	ctx := c.Request().Context()
+	key, err := releaseKey(c)
+	if err != nil {
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
+	}
+	history, err := handler.History(ctx, key)
+	if err != nil {
+		return releaseError(err, key, c)
+	}
+
+	response := model.ReleaseHistoryResponse{Version: key.Version, Range: key.Range, Platform: key.Platform,
+		Status: history[len(history)-1].StatusAt(time.Now()), History: make([]model.ReleaseRecord, 0, len(history))}
+	for _, release := range history {
+		response.History = append(response.History, release.Record())
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Update release status
+// @ID update-release-status
+// @Description Publish a new status for an existing release, keeping its history. The schedule of the release is
+// @Description replaced by the one given, if any. Range policies are addressed by /status/ranges/{platform}?range={range}.
+// @Accept  json
+// @Produce  json
+// @Param platform path string true "App platform IOS, Android"
+// @Param version path string true "app version"
+// @Param status-request body model.StatusUpdateRequest true "New status"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 404 {object} model.ErrorResponse "not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
+// @Router /status/releases/{platform}/{version} [put]
+func (handler *AppVersionHandler) updateReleaseStatus(c echo.Context) error {
+	ctx := c.Request().Context()
+	key, err := releaseKey(c)
+	if err != nil {
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
+	}
+
+	// unmarshal the request
+	request := new(model.StatusUpdateRequest)
+	if err := c.Bind(request); err != nil {
+		log.Error(err.Error())
+		return errorResponse("Failed to parse json request", http.StatusBadRequest, c)
+	}
+	if err := c.Validate(request); err != nil {
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
+	}
+
+	// only existing releases can be updated
+	history, err := handler.History(ctx, key)
+	if err != nil {
+		return releaseError(err, key, c)
+	}
+	log.Infof("Received request to update the status of release \"%s\" to \"%v\"", key, request.Status)
+
+	messaging := request.Messaging
+	if messaging.IsZero() {
+		messaging = history[len(history)-1].Messaging
+	}
+	err = handler.Insert(ctx, &model.ReleaseDAO{Version: key.Version, Range: key.Range, Platform: key.Platform,
+		Released: time.Now(), Status: request.Status, Schedule: request.Schedule, Messaging: messaging})
+	if err != nil {
+		return err
+	}
+	handler.audit(c, model.AuditDAO{Action: model.AuditUpdate, Version: key.Version, Range: key.Range, Platform: key.Platform,
+		PreviousStatus: history[len(history)-1].StatusAt(time.Now()), NewStatus: request.Status, Schedule: request.Schedule,
+		Reason: request.Reason})
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Delete release
+// @ID delete-release
+// @Description Delete a release along with its whole history. Range policies are addressed by
+// @Description /status/ranges/{platform}?range={range}.
+// @Param platform path string true "App platform IOS, Android"
+// @Param version path string true "app version"
+// @Param reason query string false "Reason of the deletion recorded in the audit trail"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 404 {object} model.ErrorResponse "not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
+// @Router /status/releases/{platform}/{version} [delete]
+func (handler *AppVersionHandler) deleteRelease(c echo.Context) error {
+	ctx := c.Request().Context()
+	key, err := releaseKey(c)
+	if err != nil {
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
+	}
+	log.Infof("Received request to delete release \"%s\"", key)
+
+	previous := handler.currentStatus(ctx, key)
+	if err := handler.Delete(ctx, key); err != nil {
+		return releaseError(err, key, c)
+	}
+	handler.audit(c, model.AuditDAO{Action: model.AuditDelete, Version: key.Version, Range: key.Range, Platform: key.Platform,
+		PreviousStatus: previous, Reason: c.QueryParam("reason")})
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Rollback
+// @ID rollback
+// @Description Restore a release, or every release and the version thresholds of a platform when neither a version
+// @Description nor a range is given, to the state they had at the given time. The restored states are published as
+// @Description new records so that the history is kept, and releases first published afterwards are skipped. The
+// @Description transitions the restored releases were scheduled with are kept when still to come.
+// @Accept  json
+// @Produce  json
+// @Param rollback-request body model.RollbackRequest true "Rollback"
+// @Success 200 {object} model.RollbackResponse "ok"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 404 {object} model.ErrorResponse "not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
+// @Router /status/rollback [post]
+func (handler *AppVersionHandler) rollback(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	// unmarshal the request
+	request := new(model.RollbackRequest)
+	if err := c.Bind(request); err != nil {
+		log.Error(err.Error())
+		return errorResponse("Failed to parse json request", http.StatusBadRequest, c)
+	}
+	if err := c.Validate(request); err != nil {
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
+	}
+	log.Infof("Received request to rollback platform \"%v\", version \"%v\", range \"%v\" to %v",
+		request.Platform, request.Version, request.Range, request.To)
+
+	wholePlatform := request.Version == "" && request.Range == ""
+	keys := []model.ReleaseKey{{Platform: request.Platform, Version: request.Version, Range: request.Range}}
+	if wholePlatform {
+		var err error
+		if keys, err = handler.platformReleases(ctx, request.Platform); err != nil {
+			return err
+		}
+	}
+
+	response := model.RollbackResponse{Restored: []model.ReleaseRecord{}, Skipped: []model.ReleaseRecord{}}
+	for _, key := range keys {
+		history, err := handler.History(ctx, key)
+		if err != nil {
+			return releaseError(err, key, c)
+		}
+		current := history[len(history)-1]
+		past, ok := model.StateAt(history, request.To)
+		if !ok {
+			response.Skipped = append(response.Skipped, current.Record())
+			continue
+		}
+
+		now := time.Now()
+		restored := model.ReleaseDAO{Version: key.Version, Range: key.Range, Platform: key.Platform,
+			Status: past.StatusAt(request.To), Schedule: past.Upcoming(now), Messaging: past.Messaging, Released: now}
+		if restored.Status == current.StatusAt(now) && sameSchedule(restored.Schedule, current.Upcoming(now)) &&
+			reflect.DeepEqual(restored.Messaging, current.Messaging) {
+			continue
+		}
+		if err := handler.Insert(ctx, &restored); err != nil {
+			return err
+		}
+		handler.audit(c, model.AuditDAO{Action: model.AuditRollback, Version: key.Version, Range: key.Range,
+			Platform: key.Platform, PreviousStatus: current.StatusAt(now), NewStatus: restored.Status,
+			Schedule: restored.Schedule, Reason: request.Reason})
+		response.Restored = append(response.Restored, restored.Record())
+	}
+
+	if wholePlatform {
+		past, err := handler.FindThresholds(ctx, request.Platform, request.To)
+		if err == nil {
+			var previous *model.Thresholds
+			if current, err := handler.FindThresholds(ctx, request.Platform, time.Now()); err == nil {
+				previous = &current.Thresholds
+			}
+			if previous == nil || *previous != past.Thresholds {
+				err := handler.InsertThresholds(ctx, model.ThresholdsDAO{Platform: request.Platform, Thresholds: past.Thresholds,
+					Messaging: past.Messaging, Released: time.Now()})
+				if err != nil {
+					return err
+				}
+				handler.audit(c, model.AuditDAO{Action: model.AuditRollback, Platform: request.Platform,
+					PreviousThresholds: previous, NewThresholds: &past.Thresholds, Reason: request.Reason})
+				response.Thresholds = &past.Thresholds
+			}
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary List upcoming transitions
+// @ID list-transitions
+// @Description List the status transitions scheduled for the releases in their current state, the soonest first
+// @Produce  json
+// @Param platform query string false "App platform IOS, Android"
+// @Param until query string false "End of the time window (inclusive), RFC3339 timestamp"
+// @Success 200 {object} model.TransitionListResponse "ok"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
+// @Router /status/transitions [get]
+func (handler *AppVersionHandler) listTransitions(c echo.Context) error {
+	ctx := c.Request().Context()
+	platform := c.QueryParam(model.AppPlatform)
+	if platform != "" {
+		if _, err := model.Platform(platform).Value(); err != nil {
+			return errorResponse(err.Error(), http.StatusBadRequest, c)
+		}
+	}
+	var until time.Time
+	if value := c.QueryParam("until"); value != "" {
+		var err error
+		if until, err = time.Parse(time.RFC3339, value); err != nil {
+			return errorResponse("The until parameter must be an RFC3339 timestamp", http.StatusBadRequest, c)
+		}
+	}
+
+	now := time.Now()
+	releases, err := handler.Scheduled(ctx, platform, now)
+	if err != nil {
+		return err
+	}
+	response := model.TransitionListResponse{Transitions: []model.ScheduledTransition{}}
+	for _, release := range releases {
+		for _, transition := range release.Transitions(now) {
+			if until.IsZero() || !transition.Effective.After(until) {
+				response.Transitions = append(response.Transitions, transition)
+			}
+		}
+	}
+	sort.SliceStable(response.Transitions, func(i, j int) bool {
+		return response.Transitions[i].Effective.Before(response.Transitions[j].Effective)
+	})
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Get audit trail
+// @ID get-audit
+// @Description Query the audit trail of the changes made through the API, the most recent first
+// @Produce  json
+// @Param version query string false "app version"
+// @Param range query string false "version range"
+// @Param platform query string false "App platform IOS, Android"
+// @Param flag query string false "Name of the flag"
+// @Param actor query string false "Identity of the author of the changes"
+// @Param from query string false "Start of the time window, RFC3339 timestamp"
+// @Param to query string false "End of the time window (exclusive), RFC3339 timestamp"
+// @Param page query int false "Page number starting from 1"
+// @Param limit query int false "Number of records per page, at most 100"
+// @Success 200 {object} model.AuditListResponse "ok"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
+// @Router /audit [get]
+func (handler *AppVersionHandler) getAudit(c echo.Context) error {
+	ctx := c.Request().Context()
+	filter := model.AuditFilter{Version: c.QueryParam(model.AppVersion), Range: c.QueryParam(model.AppRange),
+		Platform: c.QueryParam(model.AppPlatform), Flag: c.QueryParam(model.FlagName), Actor: c.QueryParam("actor")}
+
+	var err error
+	for param, t := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.QueryParam(param); value != "" {
+			if *t, err = time.Parse(time.RFC3339, value); err != nil {
+				return errorResponse(fmt.Sprintf("The %s parameter must be an RFC3339 timestamp", param), http.StatusBadRequest, c)
+			}
+		}
+	}
+	if filter.Page, filter.Limit, err = pagination(c); err != nil {
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
+	}
+
+	records, total, err := handler.FindAudit(ctx, filter)
+	if err != nil {
+		return err
+	}
+	response := model.AuditListResponse{Records: make([]model.AuditRecord, 0, len(records)), Page: filter.Page,
+		Limit: filter.Limit, Total: total}
+	for _, record := range records {
+		response.Records = append(response.Records, record.Record())
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary List flags
+// @ID list-flags
+// @Description List the definitions of the flags evaluated by the service itself with the local provider, sorted by
+// @Description name
+// @Produce  json
+// @Success 200 {object} model.FlagListResponse "ok"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
+// @Router /flags [get]
+func (handler *AppVersionHandler) listFlags(c echo.Context) error {
+	flags, err := handler.FindFlags(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	response := model.FlagListResponse{Flags: make([]model.FlagRecord, 0, len(flags))}
+	for _, flag := range flags {
+		response.Flags = append(response.Flags, flag.Record())
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Get flag
+// @ID get-flag
+// @Description Query the definition of a flag evaluated by the service itself with the local provider
+// @Produce  json
+// @Param flag path string true "Name of the flag"
+// @Success 200 {object} model.FlagRecord "ok"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 404 {object} model.ErrorResponse "not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
+// @Router /flags/{flag} [get]
+func (handler *AppVersionHandler) getFlag(c echo.Context) error {
+	name, err := flagName(c)
+	if err != nil {
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
+	}
+	flag, err := handler.FindFlag(c.Request().Context(), name)
+	if err != nil {
+		return flagError(err, name, c)
+	}
+	return c.JSON(http.StatusOK, flag.Record())
+}
+
+// @Summary Define flag
+// @ID save-flag
+// @Description Define a flag evaluated by the service itself with the local provider, replacing its previous
+// @Description definition. The flag is enabled for the requests matching any of its strategies, or for every request
+// @Description when it has none. A strategy matches the requests meeting every criterion it sets: the percentage of
+// @Description the customers or of the sessions rolled out to, the customers listed, the platforms and the range of
+// @Description the app versions, and the time window.
+// @Accept  json
+// @Produce  json
+// @Param flag path string true "Name of the flag"
+// @Param flag-request body model.FlagRequest true "Definition of the flag"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
+// @Router /flags/{flag} [put]
+func (handler *AppVersionHandler) saveFlag(c echo.Context) error {
+	ctx := c.Request().Context()
+	name, err := flagName(c)
+	if err != nil {
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
+	}
+
+	// unmarshal the request
+	request := new(model.FlagRequest)
+	if err := c.Bind(request); err != nil {
+		log.Error(err.Error())
+		return errorResponse("Failed to parse json request", http.StatusBadRequest, c)
+	}
+	if err := c.Validate(request); err != nil {
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
+	}
+	log.Infof("Received request to define the flag \"%s\"", name)
+
+	err = handler.SaveFlag(ctx, model.FlagDAO{Name: name, Description: request.Description, Enabled: request.Enabled,
+		Strategies: request.Strategies, Variant: request.Variant, Updated: time.Now()})
+	if err != nil {
+		return err
+	}
+	handler.invalidateFlags()
+	handler.audit(c, model.AuditDAO{Action: model.AuditFlag, Flag: name, Reason: request.Reason})
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Delete flag
+// @ID delete-flag
+// @Description Delete the definition of a flag evaluated by the service itself with the local provider
+// @Param flag path string true "Name of the flag"
+// @Param reason query string false "Reason of the deletion recorded in the audit trail"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 404 {object} model.ErrorResponse "not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Data store unavailable"
+// @Failure 504 {object} model.ErrorResponse "Data store timeout"
+// @Router /flags/{flag} [delete]
+func (handler *AppVersionHandler) deleteFlag(c echo.Context) error {
+	name, err := flagName(c)
+	if err != nil {
+		return errorResponse(err.Error(), http.StatusBadRequest, c)
+	}
+	log.Infof("Received request to delete the flag \"%s\"", name)
+
+	if err := handler.DeleteFlag(c.Request().Context(), name); err != nil {
+		return flagError(err, name, c)
+	}
+	handler.invalidateFlags()
+	handler.audit(c, model.AuditDAO{Action: model.AuditDeleteFlag, Flag: name, Reason: c.QueryParam("reason")})
 	return c.NoContent(http.StatusNoContent)
 }
 
@@ -188,9 +937,270 @@ func (handler *AppVersionHandler) Health(c echo.Context) error {
 	return c.String(200, "Success")
 }
 
+// @Summary Cache statistics
+// @ID cache-stats
+// @Description Query the statistics of the cache of the app statuses since start up. Like the admin routes, it takes a
+// @Description token allowed to manage the default app.
+// @Produce  json
+// @Success 200 {object} model.CacheStats
+// @Failure 401 {object} model.ErrorResponse "Missing or invalid token"
+// @Failure 403 {object} model.ErrorResponse "The token is not allowed to manage the default app"
+// @Failure 404 {object} model.ErrorResponse "The app statuses are not cached"
+// @Router /cache [get]
+func (handler *AppVersionHandler) CacheStats(c echo.Context) error {
+	cache, ok := handler.Repository.(interface{ Stats() model.CacheStats })
+	if !ok {
+		return errorResponse("The app statuses are not cached", http.StatusNotFound, c)
+	}
+	return c.JSON(http.StatusOK, cache.Stats())
+}
+
+// @Summary Flag impressions
+// @ID impression-stats
+// @Description Query the number of evaluations of each flag since start up, by result and variant, along with the
+// @Description impressions sent to the sink. The flags of the apps are namespaced by their identifier. Like the admin
+// @Description routes, it takes a token allowed to manage the default app.
+// @Produce  json
+// @Success 200 {object} model.ImpressionStats
+// @Failure 401 {object} model.ErrorResponse "Missing or invalid token"
+// @Failure 403 {object} model.ErrorResponse "The token is not allowed to manage the default app"
+// @Failure 404 {object} model.ErrorResponse "The evaluations of the flags are not recorded"
+// @Router /impressions [get]
+func (handler *AppVersionHandler) ImpressionStats(c echo.Context) error {
+	impressions, ok := handler.Provider.(interface{ Stats() model.ImpressionStats })
+	if !ok {
+		return errorResponse("The evaluations of the flags are not recorded", http.StatusNotFound, c)
+	}
+	return c.JSON(http.StatusOK, impressions.Stats())
+}
+
+// Builds the context the flags are evaluated against from the app version and platform, and from the request
+func evaluationContext(version, platform string, c echo.Context) features.EvaluationContext {
+	header := c.Request().Header
+	evaluation := features.EvaluationContext{UserID: header.Get(CustomerID), SessionID: header.Get(SessionID),
+		RemoteAddress: c.RealIP(), Version: version, Platform: platform, Properties: map[string]string{}}
+	if locale := strings.TrimSpace(strings.Split(header.Get(AcceptLanguage), ",")[0]); locale != "*" {
+		evaluation.Locale = strings.TrimSpace(strings.Split(locale, ";")[0])
+	}
+	for name, values := range header {
+		if strings.HasPrefix(name, FlagPropertyPrefix) && len(name) > len(FlagPropertyPrefix) && len(values) > 0 {
+			evaluation.Properties[strings.ToLower(strings.TrimPrefix(name, FlagPropertyPrefix))] = values[0]
+		}
+	}
+	return evaluation
+}
+
+// HTTPErrorHandler translates the errors returned by the handlers and the middlewares into error responses. The
+// errors of the repository are translated by their kind, e.g. 404 for repository.ErrNotFound, the echo errors by
+// their status, and any other error is an internal server error, logged and answered with InternalErrorMessage.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	status, message := errorStatus(err)
+	if status >= http.StatusInternalServerError {
+		log.Errorf("Failed to serve %s %s: %v", c.Request().Method, c.Request().URL.Path, err)
+	}
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = errorResponse(message, status, c)
+	}
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// Returns the HTTP status and the message translating the given error
+func errorStatus(err error) (int, string) {
+	var httpError *echo.HTTPError
+	var unavailable *repository.UnavailableError
+	switch {
+	case errors.As(err, &httpError):
+		return httpError.Code, fmt.Sprint(httpError.Message)
+	case errors.Is(err, repository.ErrNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, repository.ErrConflict):
+		return http.StatusConflict, err.Error()
+	case errors.As(err, &unavailable):
+		if unavailable.Timeout() {
+			return http.StatusGatewayTimeout, err.Error()
+		}
+		return http.StatusServiceUnavailable, err.Error()
+	case errors.Is(err, repository.ErrInvalid):
+		return http.StatusBadRequest, err.Error()
+	}
+	return http.StatusInternalServerError, InternalErrorMessage
+}
+
 // Helper method
 func errorResponse(msg string, status int, c echo.Context) error {
-	return c.JSON(status, model.ErrorResponse{Message: msg, Code: status})
+	return c.JSON(status, model.ErrorResponse{Message: msg, Code: status, Error: model.ErrorCode(status)})
+}
+
+// Returns the current status of the given release, or an empty status if it has never been published
+func (handler *AppVersionHandler) currentStatus(ctx context.Context, key model.ReleaseKey) string {
+	history, err := handler.History(ctx, key)
+	if err != nil || len(history) == 0 {
+		return ""
+	}
+	return history[len(history)-1].StatusAt(time.Now())
+}
+
+// Tells whether both schedules are made of the same transitions
+func sameSchedule(a, b []model.Transition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Status != b[i].Status || !a[i].Effective.Equal(b[i].Effective) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the keys of every release of the given platform
+func (handler *AppVersionHandler) platformReleases(ctx context.Context, platform string) ([]model.ReleaseKey, error) {
+	var keys []model.ReleaseKey
+	filter := model.ReleaseFilter{Platform: platform, Page: 1, Limit: model.MaxPageLimit, Ascending: true}
+	for {
+		releases, total, err := handler.List(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, release := range releases {
+			keys = append(keys, release.Key())
+		}
+		if len(releases) == 0 || int64(len(keys)) >= total {
+			return keys, nil
+		}
+		filter.Page++
+	}
+}
+
+// Drops the flag definitions cached by the provider, if it evaluates them, for the change to be seen straight away
+func (handler *AppVersionHandler) invalidateFlags() {
+	if provider, ok := handler.Provider.(interface{ Invalidate() }); ok {
+		provider.Invalidate()
+	}
+}
+
+// Records a change made through the API in the audit trail. The change has already been persisted at this stage,
+// hence a failure is logged along with the full record rather than failing the request.
+func (handler *AppVersionHandler) audit(c echo.Context, record model.AuditDAO) {
+	record.Actor = actor(c)
+	record.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+	record.Timestamp = time.Now()
+	if err := handler.InsertAudit(c.Request().Context(), record); err != nil {
+		log.Errorf("Failed to record the change in the audit trail %+v: %v", record, err)
+	}
+}
+
+// Identifies the author of the request from the claims of its JWT token
+func actor(c echo.Context) string {
+	claims := tokenClaims(c)
+	for _, key := range []string{IdentityKey, SubjectKey} {
+		if id, ok := claims[key]; ok && id != nil && id != "" {
+			return fmt.Sprint(id)
+		}
+	}
+	return AnonymousActor
+}
+
+// Reads the apps the JWT token of the request is granted access to from its apps claim, given either as a list or as
+// a comma separated string. The token is restricted only if it carries the claim.
+func grantedApps(c echo.Context) ([]string, bool) {
+	claim, ok := tokenClaims(c)[AppsKey]
+	if !ok {
+		return nil, false
+	}
+	var apps []string
+	switch value := claim.(type) {
+	case []interface{}:
+		for _, app := range value {
+			apps = append(apps, fmt.Sprint(app))
+		}
+	case string:
+		for _, app := range strings.Split(value, ",") {
+			apps = append(apps, strings.TrimSpace(app))
+		}
+	}
+	return apps, true
+}
+
+// Returns the claims of the JWT token of the request, if any
+func tokenClaims(c echo.Context) jwt.MapClaims {
+	token, ok := c.Get(middleware.DefaultJWTConfig.ContextKey).(*jwt.Token)
+	if !ok {
+		return jwt.MapClaims{}
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return jwt.MapClaims{}
+	}
+	return claims
+}
+
+// Reads the pagination query parameters, falling back to the first page of default size
+func pagination(c echo.Context) (int, int, error) {
+	page, limit := 1, model.DefaultPageLimit
+	if value := c.QueryParam("page"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return 0, 0, errors.New("the page must be a positive number")
+		}
+		page = n
+	}
+	if value := c.QueryParam("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > model.MaxPageLimit {
+			return 0, 0, fmt.Errorf("the limit must be between 1 and %d", model.MaxPageLimit)
+		}
+		limit = n
+	}
+	return page, limit, nil
+}
+
+// Builds the key of the release addressed by the request, either by version or by version range
+func releaseKey(c echo.Context) (model.ReleaseKey, error) {
+	platform, err := model.Platform(c.Param(model.AppPlatform)).Value()
+	if err != nil {
+		return model.ReleaseKey{}, err
+	}
+	if version := c.Param(model.AppVersion); version != "" {
+		return model.ReleaseKey{Platform: platform, Version: version}, nil
+	}
+	versionRange := c.QueryParam(model.AppRange)
+	if versionRange == "" {
+		return model.ReleaseKey{}, errors.New("the range query parameter is required")
+	}
+	return model.ReleaseKey{Platform: platform, Range: versionRange}, nil
+}
+
+// Translates the error of a release query into the error response, naming the release when it is not found
+func releaseError(err error, key model.ReleaseKey, c echo.Context) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return errorResponse(fmt.Sprintf("Release not found: %s", key), http.StatusNotFound, c)
+	}
+	return err
+}
+
+// Reads the name of the flag addressed by the request
+func flagName(c echo.Context) (string, error) {
+	name := c.Param(model.FlagName)
+	if !model.ValidFlagName(name) {
+		return "", fmt.Errorf("invalid flag name %q: letters, digits, underscores and dashes only", name)
+	}
+	return name, nil
+}
+
+// Translates the error of a flag query into the error response, naming the flag when it is not found
+func flagError(err error, name string, c echo.Context) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return errorResponse(fmt.Sprintf("Flag not found: %s", name), http.StatusNotFound, c)
+	}
+	return err
 }
 
 // Fetches environment variable, returns default if not set
@@ -1,14 +1,20 @@
 package api
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"github.com/akhettar/app-features-manager/features"
 	"github.com/akhettar/app-features-manager/mocks"
 	"github.com/akhettar/app-features-manager/model"
+	"github.com/akhettar/app-features-manager/repository"
 	"github.com/akhettar/app-features-manager/test"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/golang/mock/gomock"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"testing"
 	"time"
@@ -22,7 +28,7 @@ func TestPublishAppStatus_WithInvalidAppPlatformReturnBadRequestResponse(t *test
 		platform := "dummy"
 		t.Logf("\tWhen Sending Publish App status request to endpoint with unsupported platform value:  \"%s\"", platform)
 		{
-			mockUnleash := test.GetMockUnleashClient(t)
+			mockUnleash := test.GetMockProvider(t)
 			handler := NewAppStatusHandler(Repository, mockUnleash)
 			router := handler.CreateRouter()
 			version := "1.0"
@@ -51,7 +57,7 @@ func TestPublishAppStatus_WithGivenStatusStatusSuccess(t *testing.T) {
 	{
 		t.Logf("\tWhen Sending Publish App status request to endpoint with valid token:  \"%s\"", "\\status")
 		{
-			mockUnleash := test.GetMockUnleashClient(t)
+			mockUnleash := test.GetMockProvider(t)
 			handler := NewAppStatusHandler(Repository, mockUnleash)
 			router := handler.CreateRouter()
 			version := "1.0"
@@ -88,7 +94,7 @@ func TestPublishAppStatus_WithInvalidAppStatus(t *testing.T) {
 		status := "dummystatus"
 		t.Logf("\tWhen Sending Publish App status request to endpoint with unsupported status value:  \"%s\"", status)
 		{
-			mockUnleash := test.GetMockUnleashClient(t)
+			mockUnleash := test.GetMockProvider(t)
 			handler := NewAppStatusHandler(Repository, mockUnleash)
 			router := handler.CreateRouter()
 			version := "1.0"
@@ -117,7 +123,7 @@ func TestPublishAppStatus_UnautorisedForInvalidJWTToken(t *testing.T) {
 	{
 		t.Logf("\tWhen Sending Publish App status request to endpoint with an invalid JWT token:  \"%s\"", "\\status")
 		{
-			mockUnleash := test.GetMockUnleashClient(t)
+			mockUnleash := test.GetMockProvider(t)
 			handler := NewAppStatusHandler(Repository, mockUnleash)
 			router := handler.CreateRouter()
 
@@ -145,7 +151,7 @@ func TestPublishAppStatus_WithInvalidRequest(t *testing.T) {
 	{
 		t.Logf("\tWhen Sending Publish App status request to endpoint with invalid payalod:  \"%s\"", "\\status")
 		{
-			mockUnleash := test.GetMockUnleashClient(t)
+			mockUnleash := test.GetMockProvider(t)
 			handler := NewAppStatusHandler(Repository, mockUnleash)
 			router := handler.CreateRouter()
 
@@ -172,7 +178,7 @@ func TestQueryAppStatus_Success(t *testing.T) {
 	{
 		t.Logf("\tWhen Sending Query app status request with valid token:  \"%s\"", "\\version\\1.0\\ios")
 		{
-			mockUnleash := test.GetMockUnleashClient(t)
+			mockUnleash := test.GetMockProvider(t)
 			// publish app status and assert
 			version := "1.0"
 			platform := "ios"
@@ -215,7 +221,7 @@ func TestQueryAppStatus_WithPlatformShouldReturnBadRequestError(t *testing.T) {
 		t.Logf("\tWhen Sending Query app status request with valid token:  \"%s\"", "\\version\\1.0\\ios")
 		{
 			// publish app status and assert
-			mockUnleash := test.GetMockUnleashClient(t)
+			mockUnleash := test.GetMockProvider(t)
 			version := "1.0"
 			platform := "dummyPlatform"
 			publishAppStatus(version, platform, t, mockUnleash)
@@ -250,7 +256,7 @@ func TestQueryAppStatus_ShouldReturnTheLatestStatus(t *testing.T) {
 			platform := "ios"
 
 			// First entry with default status `Supported`
-			mockUnleash := test.GetMockUnleashClient(t)
+			mockUnleash := test.GetMockProvider(t)
 			publishAppStatus(version, platform, t, mockUnleash)
 
 			// Second entry with deprecated status
@@ -287,6 +293,142 @@ func TestQueryAppStatus_ShouldReturnTheLatestStatus(t *testing.T) {
 	}
 }
 
+// Query status for a version covered by published range policies
+func TestQueryAppStatus_ShouldResolveTheMostSpecificVersionRange(t *testing.T) {
+	t.Logf("Given the app status api is up and running")
+	{
+		t.Logf("\tWhen Sending Query app status request for a version only covered by range policies: \"%s\"", "\\version\\3.2.4\\blackberry")
+		{
+			platform := "blackberry"
+			mockUnleash := test.GetMockProvider(t)
+			publishAppStatusWithBody("", platform, model.ReleaseRequest{Range: "<3.2.0", Platform: platform, Status: model.Unsupported}, t, mockUnleash)
+			publishAppStatusWithBody("", platform, model.ReleaseRequest{Range: "3.2.x", Platform: platform, Status: model.Deprecated}, t, mockUnleash)
+
+			handler := NewAppStatusHandler(Repository, mockUnleash)
+			router := handler.CreateRouter()
+
+			req, err := test.HttpRequest(nil, "/status/version/3.2.4/"+platform, http.MethodGet, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// check call success
+			test.Ok(err, t)
+
+			if w.Code == http.StatusOK {
+				t.Logf("\t\tShould receive a \"%d\" status. %v", http.StatusOK, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive a \"%d\" status. %v %v", http.StatusOK, test.BallotX, w.Code)
+			}
+
+			var response model.ReleaseResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			if response.Status == model.Deprecated {
+				t.Logf("\t\tShould receive app status: \"%s\" . %v", model.Deprecated, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive app status: \"%s\" . %v %v", model.Deprecated, test.BallotX, response.Status)
+			}
+		}
+	}
+}
+
+// Query status for versions covered by the platform version thresholds
+func TestQueryAppStatus_ShouldDeriveStatusFromThresholds(t *testing.T) {
+	t.Logf("Given the app status api is up and running")
+	{
+		t.Logf("\tWhen Sending Publish version thresholds request to endpoint:  \"%s\"", "\\status\\thresholds")
+		{
+			platform := "blackberry"
+			mockUnleash := test.GetMockProvider(t)
+			handler := NewAppStatusHandler(Repository, mockUnleash)
+			router := handler.CreateRouter()
+
+			body := model.ThresholdsRequest{Platform: platform, Thresholds: model.Thresholds{MinSupported: "3.0.0", MinRecommended: "4.0.0", Latest: "5.0.0"}}
+			req, err := test.HttpRequest(body, "/status/thresholds", http.MethodPost, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// check call success
+			test.Ok(err, t)
+
+			if w.Code == http.StatusNoContent {
+				t.Logf("\t\tShould receive a \"%d\" status. %v", http.StatusNoContent, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive a \"%d\" status. %v %v", http.StatusNoContent, test.BallotX, w.Code)
+			}
+
+			// explicit releases take precedence over the thresholds
+			publishAppStatusWithBody("4.5.1", platform, model.ReleaseRequest{Version: "4.5.1", Platform: platform, Status: model.Deprecated}, t, mockUnleash)
+
+			expectations := map[string]string{"4.5.0": model.Supported, "5.1.0": string(model.Latest), "4.5.1": model.Deprecated}
+			for version, expected := range expectations {
+				appStatus := queryAppStatus(version, platform, t, mockUnleash)
+				if appStatus.Status == expected {
+					t.Logf("\t\tApp version %s should have \"%s\" status. %v", version, expected, test.CheckMark)
+				} else {
+					t.Errorf("\t\tApp version %s should have \"%s\" status. %v %v", version, expected, test.BallotX, appStatus.Status)
+				}
+			}
+		}
+	}
+}
+
+// Publish version thresholds in the wrong order
+func TestPublishThresholds_WithThresholdsOutOfOrder(t *testing.T) {
+
+	t.Logf("Given the app status api is up and running")
+	{
+		t.Logf("\tWhen Sending Publish version thresholds request with a minimum supported version above the latest one:  \"%s\"", "\\status\\thresholds")
+		{
+			mockUnleash := test.GetMockProvider(t)
+			handler := NewAppStatusHandler(Repository, mockUnleash)
+			router := handler.CreateRouter()
+
+			body := model.ThresholdsRequest{Platform: "ios", Thresholds: model.Thresholds{MinSupported: "3.0.0", Latest: "2.0.0"}}
+			req, err := test.HttpRequest(body, "/status/thresholds", http.MethodPost, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// check call success
+			test.Ok(err, t)
+
+			if w.Code == http.StatusBadRequest {
+				t.Logf("\t\tShould receive a \"%d\" status. %v", http.StatusBadRequest, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive a \"%d\" status. %v %v", http.StatusBadRequest, test.BallotX, w.Code)
+			}
+		}
+	}
+}
+
+// Publish App status with an invalid version range
+func TestPublishAppStatus_WithInvalidVersionRange(t *testing.T) {
+
+	t.Logf("Given the app status api is up and running")
+	{
+		versionRange := ">4.0.0 <3.0.0"
+		t.Logf("\tWhen Sending Publish App status request to endpoint with an unsatisfiable range:  \"%s\"", versionRange)
+		{
+			mockUnleash := test.GetMockProvider(t)
+			handler := NewAppStatusHandler(Repository, mockUnleash)
+			router := handler.CreateRouter()
+
+			body := model.ReleaseRequest{Range: versionRange, Platform: "ios", Status: model.Deprecated}
+			req, err := test.HttpRequest(body, "/status", http.MethodPost, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// check call success
+			test.Ok(err, t)
+
+			if w.Code == http.StatusBadRequest {
+				t.Logf("\t\tShould receive a \"%d\" status. %v", http.StatusBadRequest, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive a \"%d\" status. %v %v", http.StatusBadRequest, test.BallotX, w.Code)
+			}
+		}
+	}
+}
+
 // Query status for a given version
 func TestQueryAppStatus_NotFound(t *testing.T) {
 	t.Logf("Given the app status api is up and running")
@@ -296,7 +438,7 @@ func TestQueryAppStatus_NotFound(t *testing.T) {
 			// publish app status and assert
 			version := "1.0"
 			platform := "ios"
-			mockUnleash := test.GetMockUnleashClient(t)
+			mockUnleash := test.GetMockProvider(t)
 			publishAppStatus(version, platform, t, mockUnleash)
 
 			// send query request
@@ -331,13 +473,14 @@ func TestAppHandler_PublishAppStatusWithFailureToPersistInDataStore(t *testing.T
 			defer mockCtrl.Finish()
 			mockRepo := mocks.NewMockRepository(mockCtrl)
 
-			expectedErrorMessage := "Insert failed"
+			expectedErrorMessage := InternalErrorMessage
 			body := model.ReleaseRequest{Version: "1.0", Platform: "ios", Status: "deprecated"}
-			err := errors.New(expectedErrorMessage)
+			err := errors.New("Insert failed")
 
-			mockRepo.EXPECT().Insert(gomock.Any()).Return(err).Times(1)
+			mockRepo.EXPECT().History(gomock.Any(), gomock.Any()).Return(nil, repository.ErrNotFound).AnyTimes()
+			mockRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(err).Times(1)
 
-			mockUnleash := test.GetMockUnleashClient(t)
+			mockUnleash := test.GetMockProvider(t)
 			handler := NewAppStatusHandler(mockRepo, mockUnleash)
 			router := handler.CreateRouter()
 
@@ -383,13 +526,13 @@ func TestAppHandler_QueryAppStatusResultsInInternalServerError(t *testing.T) {
 			defer mockCtrl.Finish()
 			mockRepo := mocks.NewMockRepository(mockCtrl)
 
-			expectedErrorMessage := "Failed to query datastore"
-			err := errors.New(expectedErrorMessage)
+			expectedErrorMessage := InternalErrorMessage
+			err := errors.New("Failed to query datastore")
 
-			mockRepo.EXPECT().Find(gomock.Any(), gomock.Any()).Return(model.ReleaseResponse{}, err).Times(1)
+			mockRepo.EXPECT().Find(gomock.Any(), gomock.Any(), gomock.Any()).Return(model.ReleaseResponse{}, err).Times(1)
 
 			// send query request
-			mockUnleash := test.GetMockUnleashClient(t)
+			mockUnleash := test.GetMockProvider(t)
 			handler := NewAppStatusHandler(mockRepo, mockUnleash)
 			router := handler.CreateRouter()
 
@@ -423,14 +566,565 @@ func TestAppHandler_QueryAppStatusResultsInInternalServerError(t *testing.T) {
 
 }
 
+// Map the operations the data store did not complete to 503 and 504 responses
+func TestAppHandler_QueryAppStatusWhenTheDataStoreIsUnavailable(t *testing.T) {
+
+	t.Logf("Given the data store does not complete the queries")
+	{
+		expectations := map[error]int{context.DeadlineExceeded: http.StatusGatewayTimeout,
+			context.Canceled: http.StatusServiceUnavailable}
+		for cause, expected := range expectations {
+			t.Logf("\tWhen Sending Query App Status request failing with \"%v\"", cause)
+			{
+				mockCtrl := gomock.NewController(t)
+				mockRepo := mocks.NewMockRepository(mockCtrl)
+				mockRepo.EXPECT().Find(gomock.Any(), "1.0", "ios").
+					Return(model.ReleaseResponse{}, &repository.UnavailableError{Op: "find", Err: cause}).Times(1)
+
+				router := NewAppStatusHandler(mockRepo, test.GetMockProvider(t)).CreateRouter()
+				req, err := test.HttpRequest(nil, "/status/version/1.0/ios", http.MethodGet, test.ValidToken)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				test.Ok(err, t)
+
+				var response model.ErrorResponse
+				json.NewDecoder(w.Body).Decode(&response)
+				if w.Code == expected && response.Code == expected {
+					t.Logf("\t\tShould receive a \"%d\" status. %v", expected, test.CheckMark)
+				} else {
+					t.Errorf("\t\tShould receive a \"%d\" status. %v %v", expected, test.BallotX, w.Code)
+				}
+				mockCtrl.Finish()
+			}
+		}
+	}
+}
+
+// The errors of the repository and of the middlewares are translated into error responses with a machine-readable code
+func TestAppHandler_ShouldTranslateErrorsIntoErrorResponses(t *testing.T) {
+
+	t.Logf("Given the data store rejects the releases")
+	{
+		expectations := map[error]int{repository.ErrConflict: http.StatusConflict,
+			repository.ErrInvalid:       http.StatusBadRequest,
+			errors.New("Insert failed"): http.StatusInternalServerError}
+		for cause, expected := range expectations {
+			t.Logf("\tWhen Sending Publish App status request failing with \"%v\"", cause)
+			{
+				mockCtrl := gomock.NewController(t)
+				mockRepo := mocks.NewMockRepository(mockCtrl)
+				mockRepo.EXPECT().History(gomock.Any(), gomock.Any()).Return(nil, repository.ErrNotFound).AnyTimes()
+				mockRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(cause).Times(1)
+
+				router := NewAppStatusHandler(mockRepo, test.GetMockProvider(t)).CreateRouter()
+				body := model.ReleaseRequest{Version: "1.0", Platform: "ios", Status: "deprecated"}
+				req, err := test.HttpRequest(body, "/status", http.MethodPost, test.ValidToken)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				test.Ok(err, t)
+
+				var response model.ErrorResponse
+				json.NewDecoder(w.Body).Decode(&response)
+				if w.Code == expected && response.Code == expected && response.Error == model.ErrorCode(expected) {
+					t.Logf("\t\tShould receive a \"%d\" status with the code \"%s\". %v", expected, response.Error, test.CheckMark)
+				} else {
+					t.Errorf("\t\tShould receive a \"%d\" status with the code \"%s\". %v %v %v", expected, model.ErrorCode(expected), test.BallotX, w.Code, response)
+				}
+				mockCtrl.Finish()
+			}
+		}
+	}
+
+	t.Logf("Given the app status api is up and running")
+	{
+		t.Logf("\tWhen Sending Publish App status request with an invalid JWT token")
+		{
+			router := NewAppStatusHandler(Repository, test.GetMockProvider(t)).CreateRouter()
+			body := model.ReleaseRequest{Version: "1.0", Platform: "ios"}
+			req, err := test.HttpRequest(body, "/status", http.MethodPost, test.InvalidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			var response model.ErrorResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			expected := model.ErrorResponse{Message: response.Message, Code: http.StatusUnauthorized, Error: model.ErrorUnauthorized}
+			if w.Code == http.StatusUnauthorized && response == expected && response.Message != "" {
+				t.Logf("\t\tShould receive an error response with the code \"%s\". %v", model.ErrorUnauthorized, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive an error response with the code \"%s\". %v %v", model.ErrorUnauthorized, test.BallotX, response)
+			}
+		}
+	}
+}
+
+// Manage a release through its whole lifecycle
+func TestReleaseAdmin_HistoryUpdateAndDelete(t *testing.T) {
+
+	t.Logf("Given a release was published")
+	{
+		version := "7.7.7"
+		platform := "android"
+		mockUnleash := test.GetMockProvider(t)
+		publishAppStatusWithBody(version, platform, model.ReleaseRequest{Version: version, Platform: platform, Status: model.Supported}, t, mockUnleash)
+		router := NewAppStatusHandler(Repository, mockUnleash).CreateRouter()
+		endpoint := "/status/releases/" + platform + "/" + version
+
+		t.Logf("\tWhen Sending Update release status request to endpoint:  \"%s\"", endpoint)
+		{
+			req, err := test.HttpRequest(model.StatusUpdateRequest{Status: model.Deprecated}, endpoint, http.MethodPut, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			if w.Code == http.StatusNoContent {
+				t.Logf("\t\tShould receive a \"%d\" status. %v", http.StatusNoContent, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive a \"%d\" status. %v %v", http.StatusNoContent, test.BallotX, w.Code)
+			}
+		}
+
+		t.Logf("\tWhen Sending Get release history request to endpoint:  \"%s\"", endpoint)
+		{
+			req, err := test.HttpRequest(nil, endpoint, http.MethodGet, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			var response model.ReleaseHistoryResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			if w.Code == http.StatusOK && response.Status == model.Deprecated && len(response.History) == 2 &&
+				response.History[0].Status == model.Supported {
+				t.Logf("\t\tShould receive the history of the release. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive the history of the release. %v %v %+v", test.BallotX, w.Code, response)
+			}
+		}
+
+		t.Logf("\tWhen Sending Delete release request to endpoint:  \"%s\"", endpoint)
+		{
+			req, err := test.HttpRequest(nil, endpoint, http.MethodDelete, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			if w.Code == http.StatusNoContent {
+				t.Logf("\t\tShould receive a \"%d\" status. %v", http.StatusNoContent, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive a \"%d\" status. %v %v", http.StatusNoContent, test.BallotX, w.Code)
+			}
+
+			req, err = test.HttpRequest(model.StatusUpdateRequest{Status: model.Supported}, endpoint, http.MethodPut, test.ValidToken)
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			if w.Code == http.StatusNotFound {
+				t.Logf("\t\tThe deleted release should not be found anymore. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe deleted release should not be found anymore. %v %v", test.BallotX, w.Code)
+			}
+		}
+	}
+}
+
+// List releases with filters and pagination
+func TestListReleases_ShouldPassFilterToRepository(t *testing.T) {
+
+	t.Logf("Given the app status service is up and running")
+	{
+		endpoint := "/status/releases?platform=ios&status=deprecated&page=2&limit=1&order=asc"
+		t.Logf("\tWhen Sending List releases request to endpoint:  \"%s\"", endpoint)
+		{
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockRepo := mocks.NewMockRepository(mockCtrl)
+
+			released := time.Now()
+			expectedFilter := model.ReleaseFilter{Platform: "ios", Status: model.Deprecated, Page: 2, Limit: 1, Ascending: true}
+			mockRepo.EXPECT().List(gomock.Any(), expectedFilter).Return([]model.ReleaseDAO{
+				{Version: "1.1", Platform: "ios", Status: model.Deprecated, Released: released}}, int64(3), nil).Times(1)
+
+			router := NewAppStatusHandler(mockRepo, test.GetMockProvider(t)).CreateRouter()
+			req, err := test.HttpRequest(nil, endpoint, http.MethodGet, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			var response model.ReleaseListResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			if w.Code == http.StatusOK && response.Total == 3 && response.Page == 2 && len(response.Releases) == 1 &&
+				response.Releases[0].Version == "1.1" {
+				t.Logf("\t\tShould receive the requested page of releases. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive the requested page of releases. %v %v %+v", test.BallotX, w.Code, response)
+			}
+		}
+
+		endpoint = "/status/releases?limit=500"
+		t.Logf("\tWhen Sending List releases request with a limit too high:  \"%s\"", endpoint)
+		{
+			router := NewAppStatusHandler(Repository, test.GetMockProvider(t)).CreateRouter()
+			req, err := test.HttpRequest(nil, endpoint, http.MethodGet, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			if w.Code == http.StatusBadRequest {
+				t.Logf("\t\tShould receive a \"%d\" status. %v", http.StatusBadRequest, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive a \"%d\" status. %v %v", http.StatusBadRequest, test.BallotX, w.Code)
+			}
+		}
+	}
+}
+
+// Every change should be recorded in the audit trail
+func TestAudit_ShouldRecordChangesWithActorAndReason(t *testing.T) {
+
+	t.Logf("Given a release manager identified by their JWT token")
+	{
+		version := "8.8.8"
+		platform := "android"
+		token := signedToken(jwt.MapClaims{IdentityKey: "release-manager", "exp": time.Now().Add(time.Hour).Unix()}, t)
+		mockUnleash := test.GetMockProvider(t)
+		router := NewAppStatusHandler(Repository, mockUnleash).CreateRouter()
+
+		t.Logf("\tWhen publishing then updating the release %s %s", platform, version)
+		{
+			body := model.ReleaseRequest{Version: version, Platform: platform, Status: model.Supported, Reason: "first release"}
+			req, err := test.HttpRequest(body, "/status", http.MethodPost, token)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+			test.Ok(err, t)
+
+			update := model.StatusUpdateRequest{Status: model.Unsupported, Reason: "security issue"}
+			req, err = test.HttpRequest(update, "/status/releases/"+platform+"/"+version, http.MethodPut, token)
+			req.Header.Set("X-Request-ID", "req-42")
+			router.ServeHTTP(httptest.NewRecorder(), req)
+			test.Ok(err, t)
+		}
+
+		t.Logf("\tWhen Sending Get audit trail request for the release")
+		{
+			req, err := test.HttpRequest(nil, "/audit?version="+version+"&platform="+platform+"&actor=release-manager",
+				http.MethodGet, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			var response model.AuditListResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			if w.Code == http.StatusOK && response.Total == 2 {
+				t.Logf("\t\tShould receive both changes. %v", test.CheckMark)
+			} else {
+				t.Fatalf("\t\tShould receive both changes. %v %v %+v", test.BallotX, w.Code, response)
+			}
+
+			latest := response.Records[0]
+			if latest.Action == model.AuditUpdate && latest.PreviousStatus == model.Supported &&
+				latest.NewStatus == model.Unsupported && latest.Reason == "security issue" && latest.RequestID == "req-42" {
+				t.Logf("\t\tThe latest change should record the transition, reason and request id. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe latest change should record the transition, reason and request id. %v %+v", test.BallotX, latest)
+			}
+		}
+
+		t.Logf("\tWhen Sending Get audit trail request with an invalid time window")
+		{
+			req, err := test.HttpRequest(nil, "/audit?from=yesterday", http.MethodGet, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			if w.Code == http.StatusBadRequest {
+				t.Logf("\t\tShould receive a \"%d\" status. %v", http.StatusBadRequest, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive a \"%d\" status. %v %v", http.StatusBadRequest, test.BallotX, w.Code)
+			}
+		}
+	}
+}
+
+// Roll a release back to a past state and query the status it had at a given time
+func TestRollback_ShouldRestoreThePastStatus(t *testing.T) {
+
+	t.Logf("Given a release was published then made unsupported")
+	{
+		version := "9.9.9"
+		platform := "android"
+		mockUnleash := test.GetMockProvider(t)
+		router := NewAppStatusHandler(Repository, mockUnleash).CreateRouter()
+		publishAppStatusWithBody(version, platform, model.ReleaseRequest{Version: version, Platform: platform, Status: model.Deprecated}, t, mockUnleash)
+		time.Sleep(10 * time.Millisecond)
+		before := time.Now()
+		time.Sleep(10 * time.Millisecond)
+		publishAppStatusWithBody(version, platform, model.ReleaseRequest{Version: version, Platform: platform, Status: model.Unsupported}, t, mockUnleash)
+
+		endpoint := "/status/version/" + version + "/" + platform + "?at=" + before.Format(time.RFC3339Nano)
+		t.Logf("\tWhen Sending Query App status request for a past time to endpoint:  \"%s\"", endpoint)
+		{
+			req, err := test.HttpRequest(nil, endpoint, http.MethodGet, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			var response model.ReleaseResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			if w.Code == http.StatusOK && response.Status == model.Deprecated {
+				t.Logf("\t\tShould receive the status the release had at that time. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive the status the release had at that time. %v %v %+v", test.BallotX, w.Code, response)
+			}
+		}
+
+		t.Logf("\tWhen Sending Rollback request to endpoint:  \"%s\"", "/status/rollback")
+		{
+			body := model.RollbackRequest{Platform: platform, Version: version, To: before, Reason: "bad release"}
+			req, err := test.HttpRequest(body, "/status/rollback", http.MethodPost, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			var response model.RollbackResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			if w.Code == http.StatusOK && len(response.Restored) == 1 && response.Restored[0].Status == model.Deprecated {
+				t.Logf("\t\tShould restore the past status of the release. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould restore the past status of the release. %v %v %+v", test.BallotX, w.Code, response)
+			}
+
+			result := queryAppStatus(version, platform, t, mockUnleash)
+			if result.Status == model.Deprecated {
+				t.Logf("\t\tThe release should be queried with its restored status. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe release should be queried with its restored status. %v %v", test.BallotX, result.Status)
+			}
+		}
+
+		t.Logf("\tWhen Sending Rollback request to a time in the future")
+		{
+			body := model.RollbackRequest{Platform: platform, Version: version, To: time.Now().Add(time.Hour)}
+			req, err := test.HttpRequest(body, "/status/rollback", http.MethodPost, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			if w.Code == http.StatusBadRequest {
+				t.Logf("\t\tShould receive a \"%d\" status. %v", http.StatusBadRequest, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive a \"%d\" status. %v %v", http.StatusBadRequest, test.BallotX, w.Code)
+			}
+		}
+	}
+}
+
+// Publish a release with scheduled transitions and list the upcoming ones
+func TestSchedule_ShouldTransitionTheStatusOverTime(t *testing.T) {
+
+	t.Logf("Given a release scheduled to be deprecated in an hour then unsupported in two hours")
+	{
+		version := "11.0.0"
+		platform := "windows"
+		now := time.Now()
+		mockUnleash := test.GetMockProvider(t)
+		router := NewAppStatusHandler(Repository, mockUnleash).CreateRouter()
+		schedule := []model.Transition{{Status: model.Deprecated, Effective: now.Add(time.Hour)},
+			{Status: model.Unsupported, Effective: now.Add(2 * time.Hour)}}
+		body := model.ReleaseRequest{Version: version, Platform: platform, Status: model.Supported, Schedule: schedule}
+		req, err := test.HttpRequest(body, "/status", http.MethodPost, test.ValidToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		test.Ok(err, t)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("\t\tShould publish the release. %v %v", test.BallotX, w.Code)
+		}
+
+		expectations := map[time.Duration]string{time.Minute: model.Supported, 90 * time.Minute: model.Deprecated,
+			3 * time.Hour: model.Unsupported}
+		for offset, expected := range expectations {
+			endpoint := "/status/version/" + version + "/" + platform + "?at=" + now.Add(offset).Format(time.RFC3339Nano)
+			t.Logf("\tWhen Sending Query App status request to endpoint:  \"%s\"", endpoint)
+			{
+				req, err := test.HttpRequest(nil, endpoint, http.MethodGet, test.ValidToken)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				test.Ok(err, t)
+
+				var response model.ReleaseResponse
+				json.NewDecoder(w.Body).Decode(&response)
+				if response.Status == expected {
+					t.Logf("\t\tThe status should be %s. %v", expected, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe status should be %s. %v %v", expected, test.BallotX, response.Status)
+				}
+			}
+		}
+
+		endpoint := "/status/transitions?platform=" + platform + "&until=" + now.Add(90*time.Minute).Format(time.RFC3339)
+		t.Logf("\tWhen Sending List upcoming transitions request to endpoint:  \"%s\"", endpoint)
+		{
+			req, err := test.HttpRequest(nil, endpoint, http.MethodGet, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			var response model.TransitionListResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			if w.Code == http.StatusOK && len(response.Transitions) == 1 && response.Transitions[0].Version == version &&
+				response.Transitions[0].From == model.Supported && response.Transitions[0].To == model.Deprecated {
+				t.Logf("\t\tShould receive the deprecation only. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive the deprecation only. %v %v %+v", test.BallotX, w.Code, response)
+			}
+		}
+
+		t.Logf("\tWhen Sending Publish App status request with a transition in the past")
+		{
+			body := model.ReleaseRequest{Version: version, Platform: platform, Status: model.Supported,
+				Schedule: []model.Transition{{Status: model.Deprecated, Effective: now.Add(-time.Hour)}}}
+			req, err := test.HttpRequest(body, "/status", http.MethodPost, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			if w.Code == http.StatusBadRequest {
+				t.Logf("\t\tShould receive a \"%d\" status. %v", http.StatusBadRequest, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive a \"%d\" status. %v %v", http.StatusBadRequest, test.BallotX, w.Code)
+			}
+		}
+	}
+}
+
+// Publish a release with a store link and localized messages
+func TestQueryAppStatus_ShouldLocalizeTheUpgradeMessage(t *testing.T) {
+
+	t.Logf("Given a deprecated release was published with messages in english and french")
+	{
+		version := "6.6.6"
+		platform := "ios"
+		mockUnleash := test.GetMockProvider(t)
+		router := NewAppStatusHandler(Repository, mockUnleash).CreateRouter()
+		messaging := model.Messaging{StoreURL: "https://apps.apple.com/app/id123", Messages: map[string]model.Message{
+			model.DefaultLocale: {Title: "Update available", Message: "Please update the app", Button: "Update"},
+			"fr":                {Title: "Mise à jour disponible", Message: "Veuillez mettre à jour l'application", Button: "Mettre à jour"},
+		}}
+		body := model.ReleaseRequest{Version: version, Platform: platform, Status: model.Deprecated, Messaging: messaging}
+		publishAppStatusWithBody(version, platform, body, t, mockUnleash)
+
+		for language, expected := range map[string]string{"fr-CA, en;q=0.5": "fr", "es": model.DefaultLocale} {
+			t.Logf("\tWhen Sending Query App status request with Accept-Language \"%s\"", language)
+			{
+				req, err := test.HttpRequest(nil, "/status/version/"+version+"/"+platform, http.MethodGet, test.ValidToken)
+				req.Header.Set(AcceptLanguage, language)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				test.Ok(err, t)
+
+				var response model.ReleaseResponse
+				json.NewDecoder(w.Body).Decode(&response)
+				if w.Code == http.StatusOK && response.StoreURL == messaging.StoreURL && response.Locale == expected &&
+					response.Message != nil && *response.Message == messaging.Messages[expected] {
+					t.Logf("\t\tShould receive the store link and the %s message. %v", expected, test.CheckMark)
+				} else {
+					t.Errorf("\t\tShould receive the store link and the %s message. %v %v %+v", expected, test.BallotX, w.Code, response)
+				}
+			}
+		}
+
+		t.Logf("\tWhen Sending Publish App status request with an invalid store link")
+		{
+			messaging := model.Messaging{StoreURL: "apps.apple.com"}
+			body := model.ReleaseRequest{Version: version, Platform: platform, Status: model.Deprecated, Messaging: messaging}
+			req, err := test.HttpRequest(body, "/status", http.MethodPost, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			if w.Code == http.StatusBadRequest {
+				t.Logf("\t\tShould receive a \"%d\" status. %v", http.StatusBadRequest, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive a \"%d\" status. %v %v", http.StatusBadRequest, test.BallotX, w.Code)
+			}
+		}
+	}
+}
+
+// Manage the releases of several apps in isolation
+func TestApps_ShouldIsolateTheReleasesOfEachApp(t *testing.T) {
+
+	t.Logf("Given a release manager granted access to the app com.acme.one only")
+	{
+		version := "5.5.5"
+		platform := "ios"
+		token := signedToken(jwt.MapClaims{IdentityKey: "release-manager", AppsKey: []string{"com.acme.one"},
+			"exp": time.Now().Add(time.Hour).Unix()}, t)
+		router := NewAppStatusHandler(Repository, test.GetMockProvider(t)).CreateRouter()
+		body := model.ReleaseRequest{Version: version, Platform: platform, Status: model.Unsupported}
+
+		expectations := []struct {
+			app, token string
+			expected   int
+		}{
+			{"com.acme.one", token, http.StatusNoContent},
+			{"com.acme.two", token, http.StatusForbidden},
+			{"com.acme.one", test.ValidToken, http.StatusForbidden},
+			{"com acme", token, http.StatusBadRequest},
+		}
+		for _, tc := range expectations {
+			endpoint := "/apps/" + url.PathEscape(tc.app) + "/status"
+			t.Logf("\tWhen Sending Publish App status request to endpoint:  \"%s\"", endpoint)
+			{
+				req, err := test.HttpRequest(body, endpoint, http.MethodPost, tc.token)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				test.Ok(err, t)
+
+				if w.Code == tc.expected {
+					t.Logf("\t\tShould receive a \"%d\" status. %v", tc.expected, test.CheckMark)
+				} else {
+					t.Errorf("\t\tShould receive a \"%d\" status. %v %v", tc.expected, test.BallotX, w.Code)
+				}
+			}
+		}
+
+		for app, expected := range map[string]int{"com.acme.one": http.StatusOK, "com.acme.two": http.StatusNotFound} {
+			endpoint := "/apps/" + app + "/status/version/" + version + "/" + platform
+			t.Logf("\tWhen Sending Query App status request to endpoint:  \"%s\"", endpoint)
+			{
+				req, err := test.HttpRequest(nil, endpoint, http.MethodGet, test.ValidToken)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				test.Ok(err, t)
+
+				if w.Code == expected {
+					t.Logf("\t\tShould receive a \"%d\" status. %v", expected, test.CheckMark)
+				} else {
+					t.Errorf("\t\tShould receive a \"%d\" status. %v %v", expected, test.BallotX, w.Code)
+				}
+			}
+		}
+
+		t.Logf("\tWhen Sending Query App status request for the default app")
+		{
+			result := queryAppStatus(version, platform, t, test.GetMockProvider(t))
+			if result.Status == model.Supported {
+				t.Logf("\t\tThe release of the app should not be visible. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe release of the app should not be visible. %v %v", test.BallotX, result.Status)
+			}
+		}
+	}
+}
+
 // Helper function
-func publishAppStatus(version, platform string, t *testing.T, mockUnleash *mocks.MockUnleashService) {
+func publishAppStatus(version, platform string, t *testing.T, mockUnleash *mocks.MockProvider) {
 	body := model.ReleaseRequest{Version: version, Platform: platform}
 	publishAppStatusWithBody(version, platform, body, t, mockUnleash)
 }
 
 // Helper function
-func publishAppStatusWithBody(version, platform string, body interface{}, t *testing.T, mockUnleash *mocks.MockUnleashService) {
+func publishAppStatusWithBody(version, platform string, body interface{}, t *testing.T, mockUnleash *mocks.MockProvider) {
 	handler := NewAppStatusHandler(Repository, mockUnleash)
 	router := handler.CreateRouter()
 	req, err := test.HttpRequest(body, "/status", http.MethodPost, test.ValidToken)
@@ -440,7 +1134,7 @@ func publishAppStatusWithBody(version, platform string, body interface{}, t *tes
 	test.Ok(err, t)
 }
 
-func queryAppStatus(version, platform string, t *testing.T, mockUnleash *mocks.MockUnleashService) model.ReleaseResponse {
+func queryAppStatus(version, platform string, t *testing.T, mockUnleash *mocks.MockProvider) model.ReleaseResponse {
 	// send query request
 	handler := NewAppStatusHandler(Repository, mockUnleash)
 	router := handler.CreateRouter()
@@ -456,3 +1150,398 @@ func queryAppStatus(version, platform string, t *testing.T, mockUnleash *mocks.M
 	json.NewDecoder(w.Body).Decode(&response)
 	return response
 }
+
+// Signs a JWT token with the given claims using the default secret
+func signedToken(claims jwt.MapClaims, t *testing.T) string {
+	key, err := base64.StdEncoding.DecodeString(JwtSecret)
+	test.Ok(err, t)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+	test.Ok(err, t)
+	return token
+}
+
+// Query the statistics of the cache of the app statuses
+func TestCacheStats_ShouldCountTheHitsAndMisses(t *testing.T) {
+
+	t.Logf("Given the app statuses are cached")
+	{
+		repo := repository.NewCachingRepository(repository.NewMemoryRepository(),
+			repository.CacheConfig{TTL: time.Minute, NegativeTTL: time.Minute, Size: 100})
+		router := NewAppStatusHandler(repo, test.GetMockProvider(t)).CreateRouter()
+		for i := 0; i < 2; i++ {
+			req, err := test.HttpRequest(nil, "/status/version/1.0.0/ios", http.MethodGet, test.ValidToken)
+			test.Ok(err, t)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		t.Logf("\tWhen Sending Cache statistics request to endpoint:  \"%s\"", "/cache")
+		{
+			req, err := test.HttpRequest(nil, "/cache", http.MethodGet, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			var stats model.CacheStats
+			json.NewDecoder(w.Body).Decode(&stats)
+			if w.Code == http.StatusOK && stats.Hits == 1 && stats.Misses == 1 {
+				t.Logf("\t\tShould count a hit and a miss. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould count a hit and a miss. %v %v %+v", test.BallotX, w.Code, stats)
+			}
+		}
+	}
+
+	t.Logf("Given the app statuses are cached and a token invalid or not granted access to the default app")
+	{
+		repo := repository.NewCachingRepository(repository.NewMemoryRepository(),
+			repository.CacheConfig{TTL: time.Minute, NegativeTTL: time.Minute, Size: 100})
+		router := NewAppStatusHandler(repo, test.GetMockProvider(t)).CreateRouter()
+		restricted := signedToken(jwt.MapClaims{AppsKey: []string{"com.acme.one"}}, t)
+
+		for token, expected := range map[string]int{test.InvalidToken: http.StatusUnauthorized, restricted: http.StatusForbidden} {
+			t.Logf("\tWhen Sending Cache statistics request to endpoint:  \"%s\"", "/cache")
+			{
+				req, err := test.HttpRequest(nil, "/cache", http.MethodGet, token)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				test.Ok(err, t)
+
+				if w.Code == expected {
+					t.Logf("\t\tShould receive a \"%d\" status. %v", expected, test.CheckMark)
+				} else {
+					t.Errorf("\t\tShould receive a \"%d\" status. %v %v", expected, test.BallotX, w.Code)
+				}
+			}
+		}
+	}
+
+	t.Logf("Given the app statuses are not cached")
+	{
+		router := NewAppStatusHandler(Repository, test.GetMockProvider(t)).CreateRouter()
+
+		t.Logf("\tWhen Sending Cache statistics request to endpoint:  \"%s\"", "/cache")
+		{
+			req, err := test.HttpRequest(nil, "/cache", http.MethodGet, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			if w.Code == http.StatusNotFound {
+				t.Logf("\t\tShould receive a \"%d\" status. %v", http.StatusNotFound, test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive a \"%d\" status. %v %v", http.StatusNotFound, test.BallotX, w.Code)
+			}
+		}
+	}
+}
+
+// Count the evaluations of the flags
+func TestImpressionStats_ShouldCountTheEvaluations(t *testing.T) {
+
+	t.Logf("Given the evaluations of the flags are recorded")
+	{
+		provider := features.NewImpressionProvider(test.GetMockProvider(t), nil, features.ImpressionConfig{})
+		defer provider.Close()
+		router := NewAppStatusHandler(Repository, provider).CreateRouter()
+		for _, endpoint := range []string{"/status/version/1.0.0/ios", "/apps/com.acme.banking/status/version/1.0.0/ios"} {
+			req, err := test.HttpRequest(nil, endpoint, http.MethodGet, test.ValidToken)
+			test.Ok(err, t)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		t.Logf("\tWhen Sending Impression statistics request to endpoint:  \"%s\"", "/impressions")
+		{
+			req, err := test.HttpRequest(nil, "/impressions", http.MethodGet, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			var stats model.ImpressionStats
+			json.NewDecoder(w.Body).Decode(&stats)
+			if w.Code == http.StatusOK && len(stats.Flags) == 6 &&
+				reflect.DeepEqual(stats.Flags[0], model.FlagImpressions{Flag: "BANK_AGGREGATION", Disabled: 1}) &&
+				reflect.DeepEqual(stats.Flags[3], model.FlagImpressions{Flag: "com.acme.banking.BANK_AGGREGATION", Disabled: 1}) {
+				t.Logf("\t\tShould count the evaluations of the flags of each app. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould count the evaluations of the flags of each app. %v %v %+v", test.BallotX, w.Code, stats)
+			}
+		}
+	}
+
+	t.Logf("Given the evaluations of the flags are recorded and a token invalid or not granted access to the default app")
+	{
+		provider := features.NewImpressionProvider(test.GetMockProvider(t), nil, features.ImpressionConfig{})
+		defer provider.Close()
+		router := NewAppStatusHandler(Repository, provider).CreateRouter()
+		restricted := signedToken(jwt.MapClaims{AppsKey: []string{"com.acme.one"}}, t)
+
+		for token, expected := range map[string]int{test.InvalidToken: http.StatusUnauthorized, restricted: http.StatusForbidden} {
+			t.Logf("\tWhen Sending Impression statistics request to endpoint:  \"%s\"", "/impressions")
+			{
+				req, err := test.HttpRequest(nil, "/impressions", http.MethodGet, token)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				test.Ok(err, t)
+
+				if w.Code == expected {
+					t.Logf("\t\tShould receive a \"%d\" status. %v", expected, test.CheckMark)
+				} else {
+					t.Errorf("\t\tShould receive a \"%d\" status. %v %v", expected, test.BallotX, w.Code)
+				}
+			}
+		}
+	}
+}
+
+// Evaluate the flags against the app version, the platform and the attributes of the request
+func TestQueryAppStatus_ShouldEvaluateTheFlagsAgainstTheRequest(t *testing.T) {
+
+	t.Logf("Given the app status api is up and running")
+	{
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockUnleash := mocks.NewMockProvider(mockCtrl)
+		var evaluation features.EvaluationContext
+		mockUnleash.EXPECT().FetchFeatureFlags(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context,
+			e features.EvaluationContext) (features.Flags, error) {
+			evaluation = e
+			return features.Flags{}, nil
+		}).Times(1)
+		router := NewAppStatusHandler(Repository, mockUnleash).CreateRouter()
+
+		t.Logf("\tWhen Sending Query App Status request with the customer, session, locale and custom properties")
+		{
+			req, err := test.HttpRequest(nil, "/status/version/5.1.0/android", http.MethodGet, test.ValidToken)
+			test.Ok(err, t)
+			req.Header.Set(CustomerID, "customer-1")
+			req.Header.Set(SessionID, "session-1")
+			req.Header.Set(AcceptLanguage, "fr-CA;q=0.9, en")
+			req.Header.Set("X-Flag-Country", "fr")
+			req.RemoteAddr = "192.0.2.1:54321"
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			expected := features.EvaluationContext{UserID: "customer-1", SessionID: "session-1", RemoteAddress: "192.0.2.1",
+				Version: "5.1.0", Platform: "android", Locale: "fr-CA", Properties: map[string]string{"country": "fr"}}
+			if reflect.DeepEqual(evaluation, expected) {
+				t.Logf("\t\tThe flags should be evaluated against the request. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flags should be evaluated against the request. %v %+v", test.BallotX, evaluation)
+			}
+		}
+	}
+}
+
+// Opt into the variants of the flags
+func TestQueryAppStatus_ShouldReturnTheVariantsWhenRequested(t *testing.T) {
+
+	t.Logf("Given a flag running an experiment")
+	{
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockUnleash := mocks.NewMockProvider(mockCtrl)
+		toggles := features.Toggles{"Checkout": {Enabled: true, Variant: &model.Variant{Name: "blue",
+			Payload: &model.Payload{Type: "json", Value: `{"color": "#00f"}`}}}}
+		mockUnleash.EXPECT().FetchToggles(gomock.Any(), gomock.Any()).Return(toggles, nil).Times(1)
+		mockUnleash.EXPECT().FetchFeatureFlags(gomock.Any(), gomock.Any()).Return(toggles.Flags(), nil).Times(1)
+		router := NewAppStatusHandler(Repository, mockUnleash).CreateRouter()
+
+		expectations := map[string]bool{"/status/version/1.0.0/ios?variants=true": true, "/status/version/1.0.0/ios": false}
+		for endpoint, variants := range expectations {
+			t.Logf("\tWhen Sending Query App Status request to endpoint:  \"%s\"", endpoint)
+			{
+				req, err := test.HttpRequest(nil, endpoint, http.MethodGet, test.ValidToken)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				test.Ok(err, t)
+
+				var response model.ReleaseResponse
+				json.NewDecoder(w.Body).Decode(&response)
+				expected := map[string]model.Toggle(nil)
+				if variants {
+					expected = toggles
+				}
+				if response.Flags["Checkout"] && reflect.DeepEqual(response.Toggles, expected) {
+					t.Logf("\t\tShould receive the flags, with the variants when requested. %v", test.CheckMark)
+				} else {
+					t.Errorf("\t\tShould receive the flags, with the variants when requested. %v %+v", test.BallotX, response)
+				}
+			}
+		}
+	}
+}
+
+// Define the flags evaluated by the service itself through the admin endpoints
+func TestFlagAdmin_ShouldDefineTheFlagsEvaluatedLocally(t *testing.T) {
+
+	t.Logf("Given the flags are evaluated by the local provider")
+	{
+		repo := repository.NewMemoryRepository()
+		router := NewAppStatusHandler(repo, features.NewLocalProvider(repo, time.Hour)).CreateRouter()
+		serve := func(body interface{}, endpoint, method string) *httptest.ResponseRecorder {
+			req, err := test.HttpRequest(body, endpoint, method, test.ValidToken)
+			test.Ok(err, t)
+			req.Header.Set(CustomerID, "tester")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+		queryFlags := func() features.Flags {
+			var response model.ReleaseResponse
+			json.NewDecoder(serve(nil, "/status/version/1.0.0/ios", http.MethodGet).Body).Decode(&response)
+			return response.Flags
+		}
+		queryFlags()
+
+		t.Logf("\tWhen defining a flag enabled for a tester")
+		{
+			w := serve(model.FlagRequest{Enabled: true, Strategies: []model.Strategy{{UserIDs: []string{"tester"}}},
+				Reason: "beta"}, "/flags/Dark", http.MethodPut)
+			if w.Code == http.StatusNoContent && queryFlags()["Dark"] {
+				t.Logf("\t\tThe flag should be enabled for the tester straight away. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flag should be enabled for the tester straight away. %v %v", test.BallotX, w.Code)
+			}
+
+			var list model.FlagListResponse
+			json.NewDecoder(serve(nil, "/flags", http.MethodGet).Body).Decode(&list)
+			records, _, err := repo.FindAudit(context.Background(), model.AuditFilter{Flag: "Dark"})
+			if len(list.Flags) == 1 && list.Flags[0].Name == "Dark" && err == nil && len(records) == 1 &&
+				records[0].Action == model.AuditFlag && records[0].Reason == "beta" {
+				t.Logf("\t\tThe flag should be listed and audited. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flag should be listed and audited. %v %+v %+v", test.BallotX, list, records)
+			}
+		}
+
+		t.Logf("\tWhen defining invalid flags")
+		{
+			rollout := 150
+			for endpoint, body := range map[string]model.FlagRequest{
+				"/flags/Chat":     {Enabled: true, Strategies: []model.Strategy{{Rollout: &rollout}}},
+				"/flags/com.Chat": {Enabled: true},
+			} {
+				if w := serve(body, endpoint, http.MethodPut); w.Code == http.StatusBadRequest {
+					t.Logf("\t\tShould receive a \"%d\" status for %s. %v", http.StatusBadRequest, endpoint, test.CheckMark)
+				} else {
+					t.Errorf("\t\tShould receive a \"%d\" status for %s. %v %v", http.StatusBadRequest, endpoint, test.BallotX, w.Code)
+				}
+			}
+		}
+
+		t.Logf("\tWhen deleting the flag")
+		{
+			w := serve(nil, "/flags/Dark", http.MethodDelete)
+			if w.Code == http.StatusNoContent && serve(nil, "/flags/Dark", http.MethodGet).Code == http.StatusNotFound &&
+				!queryFlags()["Dark"] {
+				t.Logf("\t\tThe flag should be gone. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flag should be gone. %v %v", test.BallotX, w.Code)
+			}
+		}
+	}
+}
+
+// Flag the flags evaluated while the flag server is unreachable as stale
+func TestQueryAppStatus_ShouldFlagTheStaleFlags(t *testing.T) {
+
+	t.Logf("Given the flag server is unreachable")
+	{
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockUnleash := mocks.NewMockProvider(mockCtrl)
+		mockUnleash.EXPECT().FetchFeatureFlags(gomock.Any(), gomock.Any()).Return(features.Flags{"Dark": true}, nil).Times(1)
+		router := NewAppStatusHandler(Repository, staleProvider{mockUnleash}).CreateRouter()
+
+		t.Logf("\tWhen Sending Query App Status request")
+		{
+			req, err := test.HttpRequest(nil, "/status/version/1.0.0/ios", http.MethodGet, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			var response model.ReleaseResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			if response.Flags["Dark"] && response.Stale {
+				t.Logf("\t\tShould receive the last known flags flagged stale. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive the last known flags flagged stale. %v %+v", test.BallotX, response)
+			}
+		}
+	}
+}
+
+// Report the flags which could not be evaluated in time
+func TestQueryAppStatus_ShouldReportTheFlagErrors(t *testing.T) {
+
+	t.Logf("Given a flag whose evaluation stalls")
+	{
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockUnleash := mocks.NewMockProvider(mockCtrl)
+		mockUnleash.EXPECT().FetchFeatureFlags(gomock.Any(), gomock.Any()).Return(features.Flags{"Dark": true},
+			features.EvaluationError{"Slow": context.DeadlineExceeded}).Times(1)
+		router := NewAppStatusHandler(Repository, mockUnleash).CreateRouter()
+
+		t.Logf("\tWhen Sending Query App Status request")
+		{
+			req, err := test.HttpRequest(nil, "/status/version/1.0.0/ios", http.MethodGet, test.ValidToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			test.Ok(err, t)
+
+			var response model.ReleaseResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			expected := map[string]string{"Slow": context.DeadlineExceeded.Error()}
+			if response.Flags["Dark"] && reflect.DeepEqual(response.FlagErrors, expected) {
+				t.Logf("\t\tShould receive the flags evaluated along with the flag errors. %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tShould receive the flags evaluated along with the flag errors. %v %+v", test.BallotX, response)
+			}
+		}
+	}
+}
+
+// Debug the flags forced off by their prerequisites
+func TestQueryAppStatus_ShouldReturnTheReasonsWhenDebugging(t *testing.T) {
+
+	t.Logf("Given MIF_LIMITED_COMPANY requires MIF, disabled")
+	{
+		provider, err := features.NewPrerequisiteProvider(features.NewStaticProvider(map[string]features.Definition{
+			"MIF": {Enabled: false}, "MIF_LIMITED_COMPANY": {Enabled: true}}),
+			features.Prerequisites{"MIF_LIMITED_COMPANY": {"MIF"}})
+		test.Ok(err, t)
+		router := NewAppStatusHandler(Repository, provider).CreateRouter()
+
+		expectations := map[string]map[string]string{
+			"/status/version/1.0.0/ios?debug=true": {"MIF_LIMITED_COMPANY": "the prerequisite MIF is disabled"},
+			"/status/version/1.0.0/ios":            nil,
+		}
+		for endpoint, reasons := range expectations {
+			t.Logf("\tWhen Sending Query App Status request to endpoint:  \"%s\"", endpoint)
+			{
+				req, err := test.HttpRequest(nil, endpoint, http.MethodGet, test.ValidToken)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				test.Ok(err, t)
+
+				var response model.ReleaseResponse
+				json.NewDecoder(w.Body).Decode(&response)
+				if !response.Flags["MIF_LIMITED_COMPANY"] && reflect.DeepEqual(response.Reasons, reasons) {
+					t.Logf("\t\tShould receive the flag disabled, with the reason when debugging. %v", test.CheckMark)
+				} else {
+					t.Errorf("\t\tShould receive the flag disabled, with the reason when debugging. %v %+v", test.BallotX,
+						response)
+				}
+			}
+		}
+	}
+}
+
+// staleProvider a provider evaluating the flags last fetched from an unreachable flag server
+type staleProvider struct {
+	*mocks.MockProvider
+}
+
+func (staleProvider) Stale() bool {
+	return true
+}
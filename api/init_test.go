@@ -17,15 +17,22 @@ import (
 const ProfileEnvVar = "PROFILE"
 
 var (
-	Repository *repository.MongoRepository
+	Repository repository.Repository
 )
 
 // TestMain wraps all tests with the needed initialized mock DB and fixtures
 // This test runs before other integration test. It starts an instance of mongo db in the background (provided you have mongo
-// installed on the server on which this test will be running) and shuts it down.
+// installed on the server on which this test will be running) and shuts it down. When the in-memory backend is
+// selected through the REPOSITORY environment variable, the tests run against the in-memory repository instead.
 func TestMain(m *testing.M) {
 
 	flag.Parse()
+
+	if os.Getenv(repository.Backend) == repository.MemoryBackend {
+		log.Printf("running against the in-memory repository")
+		Repository = repository.NewMemoryRepository()
+		os.Exit(m.Run())
+	}
 	c := dbtest.StartMongoContainer()
 	log.Printf("running mongo with Ip %s", c.Host())
 
@@ -45,7 +52,8 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 
-	Repository = &repository.MongoRepository{client, repository.DBInfo{uri, repository.DefaultDBName, repository.DefaultCollection}}
+	Repository = &repository.MongoRepository{Client: client,
+		DBInfo: repository.DBInfo{uri, repository.DefaultDBName, repository.DefaultCollection}}
 
 	// Run the test suite
 	retCode := m.Run()
@@ -1,6 +1,6 @@
 // GENERATED BY THE COMMAND ABOVE; DO NOT EDIT
 // This file was generated by swaggo/swag at
-// 2020-04-21 22:38:20.486094 +0300 +03 m=+0.030310676
+// 2026-10-17 08:54:34.377901 +0300 +03 m=+0.031204519
 
 package docs
 
@@ -29,99 +29,312 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/health": {
+        "/audit": {
             "get": {
-                "description": "Query the health of the service",
-                "summary": "Health",
-                "operationId": "health",
+                "description": "Query the audit trail of the changes made through the API, the most recent first",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get audit trail",
+                "operationId": "get-audit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "app version",
+                        "name": "version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "version range",
+                        "name": "range",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "App platform IOS, Android",
+                        "name": "platform",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the flag",
+                        "name": "flag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Identity of the author of the changes",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the time window, RFC3339 timestamp",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the time window (exclusive), RFC3339 timestamp",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number starting from 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of records per page, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "ok",
                         "schema": {
-                            "$ref": "#/definitions/model.EmptyBody"
+                            "$ref": "#/definitions/model.AuditListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Server is down",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/model.EmptyBody"
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/status/": {
-            "post": {
-                "description": "Publish a new app status",
-                "consumes": [
+        "/cache": {
+            "get": {
+                "description": "Query the statistics of the cache of the app statuses since start up. Like the admin routes, it takes a\ntoken allowed to manage the default app.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Cache statistics",
+                "operationId": "cache-stats",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.CacheStats"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid token",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The token is not allowed to manage the default app",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "The app statuses are not cached",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/flags": {
+            "get": {
+                "description": "List the definitions of the flags evaluated by the service itself with the local provider, sorted by\nname",
+                "produces": [
                     "application/json"
                 ],
+                "summary": "List flags",
+                "operationId": "list-flags",
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "$ref": "#/definitions/model.FlagListResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/flags/{flag}": {
+            "get": {
+                "description": "Query the definition of a flag evaluated by the service itself with the local provider",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Publish App status",
-                "operationId": "publish-app-status",
+                "summary": "Get flag",
+                "operationId": "get-flag",
                 "parameters": [
                     {
-                        "description": "New App Status",
-                        "name": "status-request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.ReleaseRequest"
-                        }
+                        "type": "string",
+                        "description": "Name of the flag",
+                        "name": "flag",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "204": {},
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "$ref": "#/definitions/model.FlagRecord"
+                        }
+                    },
                     "400": {
                         "description": "Bad request",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
                     }
                 }
-            }
-        },
-        "/status/version/{version}/{platform}": {
-            "get": {
-                "description": "Query app status for a given app release version",
+            },
+            "put": {
+                "description": "Define a flag evaluated by the service itself with the local provider, replacing its previous\ndefinition. The flag is enabled for the requests matching any of its strategies, or for every request\nwhen it has none. A strategy matches the requests meeting every criterion it sets: the percentage of\nthe customers or of the sessions rolled out to, the customers listed, the platforms and the range of\nthe app versions, and the time window.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Get App Status",
-                "operationId": "get-app-status",
+                "summary": "Define flag",
+                "operationId": "save-flag",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "app version",
-                        "name": "version",
+                        "description": "Name of the flag",
+                        "name": "flag",
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "description": "Definition of the flag",
+                        "name": "flag-request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.FlagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete the definition of a flag evaluated by the service itself with the local provider",
+                "summary": "Delete flag",
+                "operationId": "delete-flag",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "App platform IOS, Android",
-                        "name": "platform",
+                        "description": "Name of the flag",
+                        "name": "flag",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reason of the deletion recorded in the audit trail",
+                        "name": "reason",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "ok",
-                        "schema": {
-                            "$ref": "#/definitions/model.ReleaseResponse"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad request",
@@ -132,7 +345,7 @@ var doc = `{
                     "404": {
                         "description": "not found",
                         "schema": {
-                            "$ref": "#/definitions/model.ReleaseResponse"
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "500": {
@@ -140,57 +353,1383 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "model.EmptyBody": {
-            "type": "object"
-        },
-        "model.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "code": {
-                    "type": "integer"
-                },
-                "message": {
-                    "type": "string"
-                }
-            }
         },
-        "model.ReleaseRequest": {
-            "type": "object",
-            "required": [
-                "platform",
-                "version"
-            ],
-            "properties": {
-                "platform": {
-                    "type": "string"
-                },
-                "status": {
-                    "type": "string"
-                },
-                "version": {
-                    "type": "string"
+        "/health": {
+            "get": {
+                "description": "Query the health of the service",
+                "summary": "Health",
+                "operationId": "health",
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "$ref": "#/definitions/model.EmptyBody"
+                        }
+                    },
+                    "500": {
+                        "description": "Server is down",
+                        "schema": {
+                            "$ref": "#/definitions/model.EmptyBody"
+                        }
+                    }
                 }
             }
         },
-        "model.ReleaseResponse": {
-            "type": "object",
-            "properties": {
-                "flags": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "boolean"
+        "/impressions": {
+            "get": {
+                "description": "Query the number of evaluations of each flag since start up, by result and variant, along with the\nimpressions sent to the sink. The flags of the apps are namespaced by their identifier. Like the admin\nroutes, it takes a token allowed to manage the default app.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Flag impressions",
+                "operationId": "impression-stats",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ImpressionStats"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid token",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The token is not allowed to manage the default app",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "The evaluations of the flags are not recorded",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/status/": {
+            "post": {
+                "description": "Publish a new app status for either a single version or a semantic version range",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Publish App status",
+                "operationId": "publish-app-status",
+                "parameters": [
+                    {
+                        "description": "New App Status",
+                        "name": "status-request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ReleaseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/status/releases": {
+            "get": {
+                "description": "List the releases in their current state, the most recently released first",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List releases",
+                "operationId": "list-releases",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "App platform IOS, Android",
+                        "name": "platform",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Current status of the releases",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number starting from 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of releases per page, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order of the released date: asc or desc",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReleaseListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/status/releases/{platform}/{version}": {
+            "get": {
+                "description": "Query the current status of a release along with every state it was published with. Range\npolicies are addressed by /status/ranges/{platform}?range={range}.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get release history",
+                "operationId": "get-release-history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "App platform IOS, Android",
+                        "name": "platform",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "app version",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReleaseHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Publish a new status for an existing release, keeping its history. The schedule of the release is\nreplaced by the one given, if any. Range policies are addressed by /status/ranges/{platform}?range={range}.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update release status",
+                "operationId": "update-release-status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "App platform IOS, Android",
+                        "name": "platform",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "app version",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New status",
+                        "name": "status-request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.StatusUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a release along with its whole history. Range policies are addressed by\n/status/ranges/{platform}?range={range}.",
+                "summary": "Delete release",
+                "operationId": "delete-release",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "App platform IOS, Android",
+                        "name": "platform",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "app version",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reason of the deletion recorded in the audit trail",
+                        "name": "reason",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/status/rollback": {
+            "post": {
+                "description": "Restore a release, or every release and the version thresholds of a platform when neither a version\nnor a range is given, to the state they had at the given time. The restored states are published as\nnew records so that the history is kept, and releases first published afterwards are skipped. The\ntransitions the restored releases were scheduled with are kept when still to come.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Rollback",
+                "operationId": "rollback",
+                "parameters": [
+                    {
+                        "description": "Rollback",
+                        "name": "rollback-request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RollbackRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "$ref": "#/definitions/model.RollbackResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/status/thresholds": {
+            "post": {
+                "description": "Declare the minimum supported, minimum recommended and latest versions of a platform",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Publish version thresholds",
+                "operationId": "publish-thresholds",
+                "parameters": [
+                    {
+                        "description": "New version thresholds",
+                        "name": "thresholds-request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ThresholdsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/status/thresholds/{platform}": {
+            "get": {
+                "description": "Query the version thresholds currently declared for a platform",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get version thresholds",
+                "operationId": "get-thresholds",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "App platform IOS, Android",
+                        "name": "platform",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "$ref": "#/definitions/model.ThresholdsRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/status/transitions": {
+            "get": {
+                "description": "List the status transitions scheduled for the releases in their current state, the soonest first",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List upcoming transitions",
+                "operationId": "list-transitions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "App platform IOS, Android",
+                        "name": "platform",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the time window (inclusive), RFC3339 timestamp",
+                        "name": "until",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "$ref": "#/definitions/model.TransitionListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/status/version/{version}/{platform}": {
+            "get": {
+                "description": "Query app status for a given app release version. When the version was not published explicitly\nits status is resolved from the published version range policies, the most specific range winning,\nand failing that derived from the version thresholds of the platform. The upgrade message published\nwith the status is localized from the Accept-Language header, falling back to less specific languages\n(e.g. fr-CA then fr) and finally to the default message. The flags are evaluated against the version\nand platform of the app, the customer, session, address and locale of the request, and the custom\nproperties given by the X-Flag- headers, e.g. \"X-Flag-Country: fr\". The flags are flagged stale when\nevaluated from the last toggles known or from their defaults, the flag provider being unreachable.\nThe flags which could not be evaluated in time are left out of the flags, their errors being returned\nin flagErrors. The flags whose prerequisites are not all enabled are disabled, the reason being returned\nin reasons when debugging.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get App Status",
+                "operationId": "get-app-status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "app version",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "App platform IOS, Android",
+                        "name": "platform",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Query the status the version had at this RFC3339 timestamp",
+                        "name": "at",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Preferred languages of the upgrade message, e.g. fr-CA, fr;q=0.9, en;q=0.8",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Customer the flags are evaluated for",
+                        "name": "CUSTOMER_ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Session the flags are evaluated for",
+                        "name": "X-Session-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the toggles holding the variant and payload of every flag along with the flags",
+                        "name": "variants",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the reasons the flags were forced off along with the flags",
+                        "name": "debug",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReleaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReleaseResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Data store unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Data store timeout",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "model.AuditListResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AuditRecord"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.AuditRecord": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "flag": {
+                    "type": "string"
+                },
+                "newStatus": {
+                    "type": "string"
+                },
+                "newThresholds": {
+                    "$ref": "#/definitions/model.Thresholds"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "previousStatus": {
+                    "type": "string"
+                },
+                "previousThresholds": {
+                    "$ref": "#/definitions/model.Thresholds"
+                },
+                "range": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "requestId": {
+                    "type": "string"
+                },
+                "schedule": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Transition"
+                    }
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.CacheStats": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "description": "Entries the number of statuses currently cached",
+                    "type": "integer"
+                },
+                "evictions": {
+                    "description": "Evictions the statuses evicted before expiring to bound the size of the cache",
+                    "type": "integer"
+                },
+                "hitRatio": {
+                    "description": "HitRatio the ratio of the queries served from the cache, zero when none was received",
+                    "type": "number"
+                },
+                "hits": {
+                    "description": "Hits the queries served from the cache, including the versions cached as not found",
+                    "type": "integer"
+                },
+                "invalidations": {
+                    "description": "Invalidations the writes invalidating the cached statuses of a platform",
+                    "type": "integer"
+                },
+                "misses": {
+                    "description": "Misses the queries served from the data store",
+                    "type": "integer"
+                },
+                "shared": {
+                    "description": "Shared the queries served by waiting for the same query already sent to the data store",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.EmptyBody": {
+            "type": "object"
+        },
+        "model.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code the HTTP status of the response",
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "Error the machine-readable code of the error, e.g. \"not_found\"",
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.FlagImpressions": {
+            "type": "object",
+            "properties": {
+                "disabled": {
+                    "description": "Disabled the evaluations disabling the flag",
+                    "type": "integer"
+                },
+                "enabled": {
+                    "description": "Enabled the evaluations enabling the flag",
+                    "type": "integer"
+                },
+                "flag": {
+                    "description": "Flag the name of the flag, namespaced by the identifier of its app",
+                    "type": "string"
+                },
+                "variants": {
+                    "description": "Variants the evaluations enabling the flag, by variant",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "model.FlagListResponse": {
+            "type": "object",
+            "properties": {
+                "flags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.FlagRecord"
+                    }
+                }
+            }
+        },
+        "model.FlagRecord": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "strategies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Strategy"
+                    }
+                },
+                "updated": {
+                    "type": "string"
+                },
+                "variant": {
+                    "$ref": "#/definitions/model.Variant"
+                }
+            }
+        },
+        "model.FlagRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "strategies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Strategy"
+                    }
+                },
+                "variant": {
+                    "$ref": "#/definitions/model.Variant"
+                }
+            }
+        },
+        "model.ImpressionStats": {
+            "type": "object",
+            "properties": {
+                "dropped": {
+                    "description": "Dropped the impressions sampled but dropped as the queue of the sink was full",
+                    "type": "integer"
+                },
+                "failed": {
+                    "description": "Failed the impressions the sink failed to receive",
+                    "type": "integer"
+                },
+                "flags": {
+                    "description": "Flags the evaluations of each flag, sorted by name",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.FlagImpressions"
+                    }
+                },
+                "sampled": {
+                    "description": "Sampled the impressions sampled to be sent to the sink",
+                    "type": "integer"
+                },
+                "sent": {
+                    "description": "Sent the impressions sent to the sink",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Message": {
+            "type": "object",
+            "properties": {
+                "button": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Payload": {
+            "type": "object",
+            "properties": {
+                "type": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ReleaseHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ReleaseRecord"
+                    }
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "range": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ReleaseListResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "releases": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ReleaseRecord"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.ReleaseRecord": {
+            "type": "object",
+            "properties": {
+                "messages": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.Message"
+                    }
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "range": {
+                    "type": "string"
+                },
+                "released": {
+                    "type": "string"
+                },
+                "schedule": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Transition"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "storeUrl": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ReleaseRequest": {
+            "type": "object",
+            "required": [
+                "platform"
+            ],
+            "properties": {
+                "messages": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.Message"
+                    }
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "range": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "schedule": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Transition"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "storeUrl": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ReleaseResponse": {
+            "type": "object",
+            "properties": {
+                "flagErrors": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "flags": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "boolean"
+                    }
+                },
+                "locale": {
+                    "type": "string"
+                },
+                "message": {
+                    "$ref": "#/definitions/model.Message"
+                },
+                "reasons": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "stale": {
+                    "type": "boolean"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "storeUrl": {
+                    "type": "string"
+                },
+                "toggles": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.Toggle"
+                    }
+                }
+            }
+        },
+        "model.RollbackRequest": {
+            "type": "object",
+            "required": [
+                "platform",
+                "to"
+            ],
+            "properties": {
+                "platform": {
+                    "type": "string"
+                },
+                "range": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.RollbackResponse": {
+            "type": "object",
+            "properties": {
+                "restored": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ReleaseRecord"
+                    }
+                },
+                "skipped": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ReleaseRecord"
+                    }
+                },
+                "thresholds": {
+                    "$ref": "#/definitions/model.Thresholds"
+                }
+            }
+        },
+        "model.ScheduledTransition": {
+            "type": "object",
+            "properties": {
+                "effective": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "range": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.StatusUpdateRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "messages": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.Message"
+                    }
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "schedule": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Transition"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "storeUrl": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Strategy": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "From the time the flag is enabled from, inclusive",
+                    "type": "string"
+                },
+                "groupId": {
+                    "description": "GroupID the group the customers and sessions are hashed with, the name of the flag when empty. Flags sharing\na group are enabled for the same customers at the same percentage.",
+                    "type": "string"
+                },
+                "platforms": {
+                    "description": "Platforms the platforms the flag is enabled on, e.g. [\"android\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rollout": {
+                    "description": "Rollout the percentage of the customers the flag is enabled for, each customer being assigned a stable bucket\nby hashing its identifier along with the group",
+                    "type": "integer"
+                },
+                "sessionRollout": {
+                    "description": "SessionRollout the percentage of the sessions the flag is enabled for, hashed like the customers",
+                    "type": "integer"
+                },
+                "until": {
+                    "description": "Until the time the flag is enabled until, exclusive",
+                    "type": "string"
+                },
+                "userIds": {
+                    "description": "UserIDs the customers the flag is enabled for",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "versionRange": {
+                    "description": "VersionRange the range of the app versions the flag is enabled for, e.g. \"\u003e=5.0\"",
+                    "type": "string"
+                }
+            }
+        },
+        "model.Thresholds": {
+            "type": "object",
+            "properties": {
+                "latest": {
+                    "type": "string"
+                },
+                "minRecommended": {
+                    "type": "string"
+                },
+                "minSupported": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ThresholdsRequest": {
+            "type": "object",
+            "required": [
+                "platform"
+            ],
+            "properties": {
+                "latest": {
+                    "type": "string"
+                },
+                "messages": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.Message"
                     }
                 },
+                "minRecommended": {
+                    "type": "string"
+                },
+                "minSupported": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "storeUrl": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Toggle": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "variant": {
+                    "$ref": "#/definitions/model.Variant"
+                }
+            }
+        },
+        "model.Transition": {
+            "type": "object",
+            "properties": {
+                "effective": {
+                    "type": "string"
+                },
                 "status": {
                     "type": "string"
                 }
             }
+        },
+        "model.TransitionListResponse": {
+            "type": "object",
+            "properties": {
+                "transitions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ScheduledTransition"
+                    }
+                }
+            }
+        },
+        "model.Variant": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "payload": {
+                    "$ref": "#/definitions/model.Payload"
+                }
+            }
         }
     }
 }`
@@ -211,7 +1750,7 @@ var SwaggerInfo = swaggerInfo{
 	BasePath:    "/",
 	Schemes:     []string{},
 	Title:       "App Status API",
-	Description: "",
+	Description: "Every route but the health check is also served for a given app under /apps/{appId}, e.g.\n/apps/com.acme.banking/status/version/{version}/{platform}, the data and flags of each app being isolated.",
 }
 
 type s struct{}
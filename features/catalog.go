@@ -0,0 +1,171 @@
+package features
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/labstack/gommon/log"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+
+	// UnleashFlagTag environment variable restricting the flags to the ones tagged with the given tag, e.g.
+	// "simple:mobile"
+	UnleashFlagTag = "UNLEASH_FLAG_TAG"
+
+	// UnleashFlagProject environment variable restricting the flags to the ones of the given unleash project
+	UnleashFlagProject = "UNLEASH_FLAG_PROJECT"
+
+	// UnleashFlagPrefix environment variable restricting the flags to the ones whose name starts with the given prefix
+	UnleashFlagPrefix = "UNLEASH_FLAG_PREFIX"
+
+	// featuresPath the path of the client API of unleash listing the toggles
+	featuresPath = "/client/features"
+)
+
+// Filter the criteria of the flags relevant to the service, the empty ones matching every flag. The criteria are sent
+// to the unleash server along with the requests listing the toggles.
+type Filter struct {
+
+	// Tag the tag of the flags, e.g. "simple:mobile"
+	Tag string
+
+	// Project the unleash project of the flags
+	Project string
+
+	// NamePrefix the prefix of the name of the flags
+	NamePrefix string
+}
+
+// Catalog the names of the flags defined in unleash, refreshed every time the unleash client fetches the toggles. The
+// catalog is live while the toggles are fetched from unleash, and stale when they are read from a bootstrap file or
+// unleash is unreachable.
+type Catalog struct {
+	sync.RWMutex
+	Filter
+	names  []string
+	loaded bool
+	live   bool
+}
+
+// toggles the response of the client API of unleash listing the toggles
+type toggles struct {
+	Features []struct {
+		Name    string `json:"name"`
+		Project string `json:"project"`
+	} `json:"features"`
+}
+
+// NewCatalog creates an empty catalog of the flags matching the given filter
+func NewCatalog(filter Filter) *Catalog {
+	return &Catalog{Filter: filter}
+}
+
+// Names returns the names of the flags sorted by name, and whether the toggles were fetched from unleash yet
+func (c *Catalog) Names() ([]string, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	return c.names, c.loaded
+}
+
+// Live tells whether the last attempt to fetch the toggles from unleash succeeded
+func (c *Catalog) Live() bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.live
+}
+
+// Bootstrap reads the flags from the given file holding the toggles in the format of the client API of unleash, e.g.
+// the backup of the unleash client, until the toggles are fetched from unleash
+func (c *Catalog) Bootstrap(path string) error {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return c.update(body)
+}
+
+// Transport wraps the given transport of the unleash client to refresh the catalog from the toggles it fetches
+func (c *Catalog) Transport(transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &catalogTransport{RoundTripper: transport, catalog: c}
+}
+
+// setLive records whether the last attempt to fetch the toggles from unleash succeeded
+func (c *Catalog) setLive(live bool) {
+	c.Lock()
+	defer c.Unlock()
+	c.live = live
+}
+
+// update replaces the flags of the catalog by the toggles of the given response of the client API of unleash
+func (c *Catalog) update(body []byte) error {
+	var response toggles
+	if err := json.Unmarshal(body, &response); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(response.Features))
+	for _, feature := range response.Features {
+		if !strings.HasPrefix(feature.Name, c.NamePrefix) {
+			continue
+		}
+		if c.Project != "" && feature.Project != "" && feature.Project != c.Project {
+			continue
+		}
+		names = append(names, feature.Name)
+	}
+	sort.Strings(names)
+
+	c.Lock()
+	defer c.Unlock()
+	c.names, c.loaded = names, true
+	return nil
+}
+
+// catalogTransport the transport of the unleash client filtering the toggles it fetches and refreshing the catalog
+// from them
+type catalogTransport struct {
+	http.RoundTripper
+	catalog *Catalog
+}
+
+// RoundTrip sends the request, reading the toggles from the response when listing them and recording whether they
+// could be fetched
+func (t *catalogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || !strings.HasSuffix(req.URL.Path, featuresPath) {
+		return t.RoundTripper.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	query := req.URL.Query()
+	for param, value := range map[string]string{"tag": t.catalog.Tag, "project": t.catalog.Project,
+		"namePrefix": t.catalog.NamePrefix} {
+		if value != "" {
+			query.Set(param, value)
+		}
+	}
+	req.URL.RawQuery = query.Encode()
+
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.catalog.setLive(err == nil && resp.StatusCode == http.StatusNotModified)
+		return resp, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	err = t.catalog.update(body)
+	if err != nil {
+		log.Warnf("Failed to read the toggles fetched from unleash: %v", err)
+	}
+	t.catalog.setLive(err == nil)
+	return resp, nil
+}
@@ -0,0 +1,167 @@
+package features_test
+
+import (
+	"context"
+	. "github.com/akhettar/app-features-manager/features"
+	"github.com/akhettar/app-features-manager/test"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// TestCatalog_ShouldDiscoverTheFlagsOfEachApp checks the flags are discovered from the toggles fetched from unleash
+func TestCatalog_ShouldDiscoverTheFlagsOfEachApp(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`{"version": 1, "features": [{"name": "Dark", "project": "mobile"},
+			{"name": "Chat", "project": "mobile"}, {"name": "Web", "project": "web"},
+			{"name": "com.acme.banking.Pay", "project": "mobile"}, {"name": "checkout.v2", "project": "mobile"}]}`))
+	}))
+	defer server.Close()
+
+	t.Logf("Given a catalog of the flags of the mobile project, the app com.acme.banking being registered")
+	{
+		RegisterApps("com.acme.banking")
+		catalog := NewCatalog(Filter{Project: "mobile", Tag: "simple:mobile"})
+		client := UnleashClient{Catalog: catalog}
+		ListOfFlags = "ITFeature"
+
+		t.Logf("\tWhen fetching the flags before unleash listed the toggles")
+		{
+			expectFlags(fetchFlags(client, EvaluationContext{UserID: "customer"}, t), []string{"ITFeature"}, t)
+		}
+
+		t.Logf("\tWhen the unleash client lists the toggles")
+		{
+			resp, err := (&http.Client{Transport: catalog.Transport(nil)}).Get(server.URL + "/api/client/features")
+			test.Ok(err, t)
+			body, err := ioutil.ReadAll(resp.Body)
+			test.Ok(err, t)
+			if len(body) > 0 && query.Get("project") == "mobile" && query.Get("tag") == "simple:mobile" {
+				t.Logf("\t\tThe filter should have been sent to unleash %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe filter should have been sent to unleash %v %v", query, test.BallotX)
+			}
+			expectFlags(fetchFlags(client, EvaluationContext{UserID: "customer"}, t), []string{"Chat", "Dark",
+				"checkout.v2"}, t)
+			expectFlags(fetchFlags(client.ForApp("com.acme.banking"), EvaluationContext{UserID: "customer"}, t),
+				[]string{"Pay"}, t)
+		}
+
+		t.Logf("\tWhen the flags of an unknown app namespacing a flag of the default app are requested")
+		{
+			expectFlags(fetchFlags(client.ForApp("checkout"), EvaluationContext{UserID: "customer"}, t),
+				[]string{"v2"}, t)
+			expectFlags(fetchFlags(client, EvaluationContext{UserID: "customer"}, t), []string{"Chat", "Dark",
+				"checkout.v2"}, t)
+		}
+	}
+}
+
+// fetchFlags fetches the flags of the provider, failing the test when any flag could not be evaluated
+func fetchFlags(provider Provider, evaluation EvaluationContext, t *testing.T) Flags {
+	flags, err := provider.FetchFeatureFlags(context.Background(), evaluation)
+	test.Ok(err, t)
+	return flags
+}
+
+// fetchToggles fetches the toggles of the provider, failing the test when any flag could not be evaluated
+func fetchToggles(provider Provider, evaluation EvaluationContext, t *testing.T) Toggles {
+	toggles, err := provider.FetchToggles(context.Background(), evaluation)
+	test.Ok(err, t)
+	return toggles
+}
+
+// expectFlags checks the names of the given flags
+func expectFlags(flags Flags, expected []string, t *testing.T) {
+	names := make(map[string]bool)
+	for name := range flags {
+		names[name] = true
+	}
+	want := make(map[string]bool)
+	for _, name := range expected {
+		want[name] = true
+	}
+	if reflect.DeepEqual(names, want) {
+		t.Logf("\t\tThe flags should be %v %v", expected, test.CheckMark)
+	} else {
+		t.Errorf("\t\tThe flags should be %v %v %v", expected, flags, test.BallotX)
+	}
+}
+
+// TestCatalog_ShouldBeStaleUntilUnleashIsReached checks the flags are read from the bootstrap file and flagged stale
+// until the toggles are fetched from unleash, and again once unleash is unreachable
+func TestCatalog_ShouldBeStaleUntilUnleashIsReached(t *testing.T) {
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"version": 1, "features": [{"name": "Dark"}, {"name": "Chat"}]}`))
+	}))
+	bootstrap, err := ioutil.TempFile("", "toggles")
+	test.Ok(err, t)
+	defer os.Remove(bootstrap.Name())
+	bootstrap.Write([]byte(`{"version": 1, "features": [{"name": "Dark"}]}`))
+	bootstrap.Close()
+
+	t.Logf("Given the toggles of a bootstrap file and a default value for a flag unknown to unleash")
+	{
+		catalog := NewCatalog(Filter{})
+		test.Ok(catalog.Bootstrap(bootstrap.Name()), t)
+		client := UnleashClient{Catalog: catalog}
+		httpClient := &http.Client{Transport: catalog.Transport(nil)}
+		fetch := func() {
+			if resp, err := httpClient.Get(server.URL + "/api/client/features"); err == nil {
+				resp.Body.Close()
+			}
+		}
+		expectStale := func(expected bool) {
+			if client.Stale() == expected {
+				t.Logf("\t\tThe flags should be stale: %v %v", expected, test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flags should be stale: %v %v", expected, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen unleash fails to list the toggles")
+		{
+			fetch()
+			expectFlags(fetchFlags(client, EvaluationContext{}, t), []string{"Dark"}, t)
+			expectStale(true)
+		}
+
+		t.Logf("\tWhen unleash lists the toggles")
+		{
+			status = http.StatusOK
+			fetch()
+			expectFlags(fetchFlags(client, EvaluationContext{}, t), []string{"Chat", "Dark"}, t)
+			expectStale(false)
+		}
+
+		t.Logf("\tWhen unleash is unreachable")
+		{
+			server.Close()
+			fetch()
+			expectFlags(fetchFlags(client, EvaluationContext{}, t), []string{"Chat", "Dark"}, t)
+			expectStale(true)
+		}
+	}
+
+	t.Logf("Given no toggles were ever fetched from unleash")
+	{
+		ListOfFlags = "ITFeature"
+		client := UnleashClient{Catalog: NewCatalog(Filter{}), Defaults: map[string]bool{"Beta": true,
+			"com.acme.banking.Pay": true}}
+
+		t.Logf("\tWhen fetching the flags")
+		{
+			expectFlags(fetchFlags(client, EvaluationContext{}, t), []string{"Beta", "ITFeature"}, t)
+			expectFlags(fetchFlags(client.ForApp("com.acme.banking"), EvaluationContext{}, t),
+				[]string{"ITFeature", "Pay"}, t)
+		}
+	}
+}
@@ -1,18 +1,22 @@
 package features
 
 import (
-	"encoding/base64"
-	"errors"
+	"context"
 	"fmt"
-	"github.com/Unleash/unleash-client-go"
-	"github.com/Unleash/unleash-client-go/context"
+	"github.com/Unleash/unleash-client-go/v3"
+	"github.com/akhettar/app-features-manager/model"
 	"github.com/labstack/gommon/log"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
-// ListOfFlags list of feature flag comma separated to be populated from the vault server
+// ListOfFlags list of feature flag comma separated to be populated from the vault server, evaluated until the flags
+// are discovered from unleash
 var ListOfFlags string
 
 const (
@@ -22,7 +26,7 @@ const (
 	// UnleashPassword is the password to access the unleash server
 	UnleashPassword = "UNLEASH_PASSWORD"
 
-	// UnleashBaseURL is the base URL of the UnleashService server
+	// UnleashBaseURL is the base URL of the unleash server
 	UnleashBaseURL = "UNLEASH_BASE_URL"
 
 	// UnleashAppName the name of the app to use within unleash
@@ -45,80 +49,231 @@ const (
 
 	// UnleashDevURL the unleash dev url.
 	UnleashDevURL = "http://localhost"
+
+	// AppProperty the unleash context property holding the identifier of the app the flags are evaluated for
+	AppProperty = "appId"
+
+	// AppFlagSeparator the separator between the app identifier and the name of the flags of an app
+	AppFlagSeparator = "."
+
+	// UnleashBackupFile the name of the file the unleash client persists the toggles of the given app name to
+	UnleashBackupFile = "unleash-repo-schema-v1-%s.json"
 )
 
 // Flags holding list of feature flags
 type Flags map[string]bool
 
-// UnleashService interface
-type UnleashService interface {
-	FetchFeatureFlags(customerID string) Flags
+// Toggles the evaluation of the feature flags, with the variants of the customer, by name
+type Toggles map[string]model.Toggle
+
+// Flags returns whether each flag is enabled
+func (t Toggles) Flags() Flags {
+	flags := make(Flags, len(t))
+	for name, toggle := range t {
+		flags[name] = toggle.Enabled
+	}
+	return flags
+}
+
+// Reasons returns the reasons the flags were forced off, by name
+func (t Toggles) Reasons() map[string]string {
+	reasons := make(map[string]string)
+	for name, toggle := range t {
+		if toggle.Reason != "" {
+			reasons[name] = toggle.Reason
+		}
+	}
+	return reasons
 }
 
-// UnleashClient the unleash client
-type UnleashClient struct{}
+// UnleashClient the provider evaluating the flags with the unleash client
+type UnleashClient struct {
+
+	// Catalog the flags discovered from unleash, ListOfFlags being evaluated when nil or not loaded yet
+	*Catalog
+
+	// Defaults the values of the flags unknown to unleash, e.g. while unreachable, by name
+	Defaults map[string]bool
 
-// NewUnleashClient initialises an instance of the Unleash client
-func NewUnleashClient() UnleashService {
+	// Workers the number of flags of a request evaluated concurrently, DefaultWorkers when not positive
+	Workers int
 
-	// Getting default values that will be used in the integration test
-	ListOfFlags = "ITFeature,ITFeatureDisabled"
-	password := fetchEnv(UnleashPassword)
-	username := fetchEnv(UnleashUsername)
-	url := UnleashDevURL
+	// Timeout the deadline of the evaluation of the flags of a request, only bound by the deadline of the request
+	// when zero
+	Timeout time.Duration
 
-	headers := http.Header{
-		AuthorizationHeader: []string{AuthorizationBasicPrefix +
-			base64.StdEncoding.EncodeToString([]byte(username+":"+password))},
+	// App the identifier of the app the flags are evaluated for, the default app when empty
+	App string
+}
+
+func init() {
+	Register(UnleashProviderName, NewUnleashClient)
+}
+
+// NewUnleashClient initialises an instance of the Unleash client with the given configuration. The toggles persisted
+// by the unleash client to the backup path, or failing that the ones of the bootstrap file, are evaluated until the
+// toggles are fetched from unleash.
+func NewUnleashClient(config Config) (Provider, error) {
+	if err := config.validateUnleash(); err != nil {
+		return nil, fmt.Errorf("invalid unleash configuration: %w", err)
 	}
+	ListOfFlags = os.Getenv(FeatureFlagList)
+	catalog := NewCatalog(config.Filter)
 
-	unleash.Initialize(
+	backupPath := config.BackupPath
+	if backupPath == "" {
+		backupPath = os.TempDir()
+	}
+	backup := filepath.Join(backupPath, fmt.Sprintf(UnleashBackupFile, config.AppName))
+	if err := bootstrap(backup, config.BootstrapFile); err != nil {
+		return nil, fmt.Errorf("failed to bootstrap the toggles: %w", err)
+	}
+	if err := catalog.Bootstrap(backup); err == nil {
+		log.Infof("Evaluating the toggles of %s until they are fetched from unleash", backup)
+	} else if !os.IsNotExist(err) {
+		log.Warnf("Failed to read the toggles of %s: %v", backup, err)
+	}
+
+	options := []unleash.ConfigOption{
 		unleash.WithListener(&unleash.DebugListener{}),
-		unleash.WithAppName(UnleashAppName),
-		unleash.WithUrl(url),
-		unleash.WithCustomHeaders(headers),
-	)
-	return UnleashClient{}
+		unleash.WithAppName(config.AppName),
+		unleash.WithUrl(config.APIURL()),
+		unleash.WithCustomHeaders(config.headers()),
+		unleash.WithHttpClient(&http.Client{Transport: catalog.Transport(nil)}),
+		unleash.WithStrategies(AppVersionStrategy{}),
+		unleash.WithBackupPath(backupPath),
+	}
+	if config.InstanceID != "" {
+		options = append(options, unleash.WithInstanceId(config.InstanceID))
+	}
+	if config.Environment != "" {
+		options = append(options, unleash.WithEnvironment(config.Environment))
+	}
+	if config.RefreshInterval > 0 {
+		options = append(options, unleash.WithRefreshInterval(config.RefreshInterval))
+	}
+	if config.MetricsInterval > 0 {
+		options = append(options, unleash.WithMetricsInterval(config.MetricsInterval))
+	}
+	if err := unleash.Initialize(options...); err != nil {
+		return nil, fmt.Errorf("failed to initialise the unleash client: %w", err)
+	}
+	log.Infof("Initialised the unleash client of %s against %s", config.AppName, config.APIURL())
+	return UnleashClient{Catalog: catalog, Defaults: config.Defaults, Workers: config.Workers,
+		Timeout: config.Timeout}, nil
 }
 
-// FetchFeatureFlags fetches feature flags for a given customerID
-func (cl UnleashClient) FetchFeatureFlags(customerID string) Flags {
-
-	ctx := context.Context{
-		UserId: customerID,
+// bootstrap copies the given bootstrap file to the backup of the unleash client, unless it already holds toggles
+func bootstrap(backup, file string) error {
+	if file == "" {
+		return nil
+	}
+	if _, err := os.Stat(backup); err == nil {
+		return nil
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
 	}
-	results := make(map[string]bool)
+	if err := ioutil.WriteFile(backup, content, 0644); err != nil {
+		return err
+	}
+	log.Infof("Bootstrapped the toggles of %s from %s", backup, file)
+	return nil
+}
 
-	flagNames := strings.Split(ListOfFlags, CommaSeparator)
-	ch := make(chan func() (string, bool), len(flagNames))
-	defer close(ch)
+// ForApp returns a client evaluating the flags of the given app. The flags of an app are namespaced by its identifier
+// in unleash, e.g. "com.acme.banking.ITFeature", and evaluated with the app identifier in the context.
+func (cl UnleashClient) ForApp(app string) Provider {
+	return UnleashClient{Catalog: cl.Catalog, Defaults: cl.Defaults, Workers: cl.Workers, Timeout: cl.Timeout, App: app}
+}
+
+// Stale tells whether the flags are evaluated from the toggles fetched last or from their defaults, unleash being
+// unreachable or not reached yet
+func (cl UnleashClient) Stale() bool {
+	return cl.Catalog != nil && !cl.Live()
+}
+
+// FetchFeatureFlags fetches feature flags evaluated against the given context: every flag of the app discovered from
+// unleash
+func (cl UnleashClient) FetchFeatureFlags(ctx context.Context, evaluation EvaluationContext) (Flags, error) {
+	unleashCtx := evaluation.unleashContext(cl.App)
+	prefix := appPrefix(cl.App)
+	toggles, err := cl.evaluate(ctx, func(ctx context.Context, flag string) (model.Toggle, error) {
+		toggle := model.Toggle{Enabled: unleash.IsEnabled(prefix+flag, unleash.WithContext(unleashCtx),
+			unleash.WithFallback(cl.Defaults[prefix+flag]))}
+		log.Debugf("Found feature flag value for %s%s:%v", prefix, flag, toggle.Enabled)
+		return toggle, nil
+	})
+	return toggles.Flags(), err
+}
 
-	// Fire all the queries for given flags in the background
-	for _, flag := range flagNames {
-		go fetchFlagStatus(ctx, ch, flag)
+// FetchToggles fetches the toggles evaluated against the given context: whether every flag of the app discovered from
+// unleash is enabled, along with the variant of the customer
+func (cl UnleashClient) FetchToggles(ctx context.Context, evaluation EvaluationContext) (Toggles, error) {
+	unleashCtx := evaluation.unleashContext(cl.App)
+	prefix := appPrefix(cl.App)
+	return cl.evaluate(ctx, func(ctx context.Context, flag string) (model.Toggle, error) {
+		toggle := model.Toggle{Enabled: unleash.IsEnabled(prefix+flag, unleash.WithContext(unleashCtx),
+			unleash.WithFallback(cl.Defaults[prefix+flag]))}
+		variant := unleash.GetVariant(prefix+flag, unleash.WithVariantContext(unleashCtx))
+		if toggle.Enabled && variant != nil && variant.Enabled {
+			toggle.Variant = &model.Variant{Name: variant.Name}
+			if variant.Payload.Type != "" {
+				toggle.Variant.Payload = &model.Payload{Type: variant.Payload.Type, Value: variant.Payload.Value}
+			}
+		}
+		log.Debugf("Found feature flag toggle for %s%s:%+v", prefix, flag, toggle)
+		return toggle, nil
+	})
+}
+
+// Evaluates every flag of the app with the given function on the workers of the client, within the timeout of the
+// client
+func (cl UnleashClient) evaluate(ctx context.Context,
+	fn func(ctx context.Context, flag string) (model.Toggle, error)) (Toggles, error) {
+	if cl.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cl.Timeout)
+		defer cancel()
 	}
+	return evaluate(ctx, cl.Workers, cl.flagNames(), fn)
+}
 
-	// Read all the feature flag status
-	for range flagNames {
-		flag, status := (<-ch)()
-		results[flag] = status
+// Returns the names of the flags of the app discovered from unleash, without the app prefix, or the ones listed along
+// with the ones defaulted until discovered
+func (cl UnleashClient) flagNames() []string {
+	if cl.Catalog != nil {
+		if names, loaded := cl.Names(); loaded {
+			return appFlags(names, cl.App)
+		}
+	}
+	names := listedFlags()
+	for _, name := range appFlags(defaultedFlags(cl.Defaults), cl.App) {
+		if !contains(names, name) {
+			names = append(names, name)
+		}
 	}
-	return results
+	return names
 }
 
-// Fetches the status for a given flag from the unleash server
-func fetchFlagStatus(ctx context.Context, c chan func() (string, bool), flag string) {
-	c <- func() (string, bool) {
-		log.Infof("Fetching feature flag value for %s", flag)
-		status := unleash.IsEnabled(flag, unleash.WithContext(ctx))
-		log.Infof("Found feature flag value for %s:%v", flag, status)
-		return flag, status
+// Returns the names of the flags holding a default value, sorted by name
+func defaultedFlags(defaults map[string]bool) []string {
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
 }
 
-func fetchEnv(key string) string {
-	if val, ok := os.LookupEnv(key); ok {
-		return val
+// Returns the names of the flags of ListOfFlags
+func listedFlags() []string {
+	var names []string
+	for _, name := range strings.Split(ListOfFlags, CommaSeparator) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
 	}
-	panic(errors.New(fmt.Sprintf("Required environment variable nout found %s", key)))
+	return names
 }
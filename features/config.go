@@ -0,0 +1,375 @@
+package features
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/akhettar/app-features-manager/repository"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+
+	// UnleashAppNameKey environment variable setting the name of the app to use within unleash, UnleashAppName by
+	// default
+	UnleashAppNameKey = "UNLEASH_APP_NAME"
+
+	// UnleashInstanceID environment variable identifying the instance of the service within unleash
+	UnleashInstanceID = "UNLEASH_INSTANCE_ID"
+
+	// UnleashEnvironment environment variable setting the unleash environment the flags are evaluated in
+	UnleashEnvironment = "UNLEASH_ENVIRONMENT"
+
+	// UnleashRefreshInterval environment variable setting the interval the toggles are fetched at, e.g. "15s"
+	UnleashRefreshInterval = "UNLEASH_REFRESH_INTERVAL"
+
+	// UnleashMetricsInterval environment variable setting the interval the metrics are sent at, e.g. "60s"
+	UnleashMetricsInterval = "UNLEASH_METRICS_INTERVAL"
+
+	// UnleashAPIToken environment variable setting the API token to access the unleash server, instead of a username
+	// and a password
+	UnleashAPIToken = "UNLEASH_API_TOKEN"
+
+	// UnleashHeaders environment variable setting the custom headers sent to the unleash server, separated by
+	// semicolons, e.g. "X-Team: mobile; X-Region: eu"
+	UnleashHeaders = "UNLEASH_HEADERS"
+
+	// HeaderSeparator separator of the custom headers sent to the unleash server
+	HeaderSeparator = ";"
+
+	// UnleashBackupPath environment variable setting the directory the unleash client persists the last toggles
+	// fetched to, and reads them from on start up, the temporary directory by default
+	UnleashBackupPath = "UNLEASH_BACKUP_PATH"
+
+	// UnleashBootstrapFile environment variable setting the file holding the toggles evaluated until they are fetched
+	// from unleash when no toggles were persisted yet, in the format of the client API of unleash
+	UnleashBootstrapFile = "UNLEASH_BOOTSTRAP_FILE"
+
+	// FeatureFlagDefaults environment variable setting the values of the flags unknown to the provider, e.g. when
+	// unleash is unreachable, comma separated, e.g. "Dark=true,com.acme.banking.Pay=false"
+	FeatureFlagDefaults = "FEATURE_FLAG_DEFAULTS"
+
+	// FeatureFlagApps environment variable registering the identifiers of the apps namespacing their flags, comma
+	// separated, e.g. "com.acme.banking,com.acme.wallet", the flags namespaced by no app registered being the flags
+	// of the default app
+	FeatureFlagApps = "FEATURE_FLAG_APPS"
+
+	// FeatureFlagWorkers environment variable setting the number of flags of a request evaluated concurrently,
+	// DefaultWorkers by default
+	FeatureFlagWorkers = "FEATURE_FLAG_WORKERS"
+
+	// FeatureFlagTimeout environment variable setting the deadline of the evaluation of the flags of a request, e.g.
+	// "500ms", DefaultEvaluationTimeout by default
+	FeatureFlagTimeout = "FEATURE_FLAG_TIMEOUT"
+
+	// OpenFeatureBackendKey environment variable choosing the registered OpenFeature provider the flags are resolved
+	// with by the openfeature provider, e.g. "file"
+	OpenFeatureBackendKey = "OPENFEATURE_BACKEND"
+)
+
+// Config the configuration of the provider of the feature flags
+type Config struct {
+
+	// Provider the name of the registered provider of the flags, UnleashProviderName when empty
+	Provider string
+
+	// File the path of the YAML or JSON file defining the flags, read by the file provider
+	File string
+
+	// OpenFeatureBackend the name of the registered OpenFeature provider the flags are resolved with by the
+	// openfeature provider
+	OpenFeatureBackend string
+
+	// Repository the repository of the flag definitions evaluated by the local provider
+	Repository repository.Repository
+
+	// URL the base URL of the unleash server, the API being served under UnleashAPISuffix
+	URL string
+
+	// AppName the name of the app to use within unleash
+	AppName string
+
+	// InstanceID the identifier of the instance of the service within unleash, generated by the client when empty
+	InstanceID string
+
+	// Environment the unleash environment the flags are evaluated in, the default one when empty
+	Environment string
+
+	// RefreshInterval the interval the toggles are fetched at by the unleash client, or the flag definitions are
+	// reloaded at by the local provider, the default of the provider when zero
+	RefreshInterval time.Duration
+
+	// MetricsInterval the interval the metrics are sent at, the default of the client when zero
+	MetricsInterval time.Duration
+
+	// Username the username to access the unleash server with basic authentication
+	Username string
+
+	// Password the password to access the unleash server with basic authentication
+	Password string
+
+	// APIToken the API token to access the unleash server, exclusive of the basic authentication
+	APIToken string
+
+	// Headers the custom headers sent to the unleash server
+	Headers http.Header
+
+	// Filter the criteria of the flags discovered from unleash
+	Filter Filter
+
+	// BackupPath the directory the unleash client persists the last toggles fetched to, the temporary directory when
+	// empty
+	BackupPath string
+
+	// BootstrapFile the file holding the toggles evaluated until they are fetched from unleash, when none were
+	// persisted to the backup path yet
+	BootstrapFile string
+
+	// Defaults the values of the flags unknown to the provider, by name, the flags of an app being namespaced by its
+	// identifier
+	Defaults map[string]bool
+
+	// Apps the identifiers of the apps namespacing their flags, registered when the provider is created, the flags
+	// of the default app being the ones namespaced by none of them
+	Apps []string
+
+	// Workers the number of flags of a request evaluated concurrently by the unleash and the OpenFeature providers,
+	// DefaultWorkers when not positive
+	Workers int
+
+	// Timeout the deadline of the evaluation of the flags of a request by the unleash and the OpenFeature providers,
+	// the flags being only bound by the deadline of the request when zero
+	Timeout time.Duration
+
+	// Impressions the configuration of the recording of the evaluations of the flags
+	Impressions ImpressionConfig
+
+	// Prerequisites the flags each flag requires to be enabled, by name, enforced on the flags of every provider
+	Prerequisites Prerequisites
+}
+
+// ConfigFromEnv reads the configuration of the provider from the environment variables, the unleash server
+// defaulting to UnleashDevURL. The configuration is validated, every problem being reported in the error.
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		Provider:           os.Getenv(FeatureProvider),
+		File:               os.Getenv(FeatureFile),
+		OpenFeatureBackend: os.Getenv(OpenFeatureBackendKey),
+		URL:                envOrDefault(UnleashBaseURL, UnleashDevURL),
+		AppName:            envOrDefault(UnleashAppNameKey, UnleashAppName),
+		InstanceID:         os.Getenv(UnleashInstanceID),
+		Environment:        os.Getenv(UnleashEnvironment),
+		Username:           os.Getenv(UnleashUsername),
+		Password:           os.Getenv(UnleashPassword),
+		APIToken:           os.Getenv(UnleashAPIToken),
+		Headers:            http.Header{},
+		Filter: Filter{Tag: os.Getenv(UnleashFlagTag), Project: os.Getenv(UnleashFlagProject),
+			NamePrefix: os.Getenv(UnleashFlagPrefix)},
+		BackupPath:    os.Getenv(UnleashBackupPath),
+		BootstrapFile: os.Getenv(UnleashBootstrapFile),
+		Defaults:      map[string]bool{},
+		Timeout:       DefaultEvaluationTimeout,
+		Impressions: ImpressionConfig{Sink: os.Getenv(ImpressionSink), File: os.Getenv(ImpressionFile),
+			WebhookURL: os.Getenv(ImpressionWebhookURL)},
+	}
+
+	var problems []string
+	for key, interval := range map[string]*time.Duration{UnleashRefreshInterval: &config.RefreshInterval,
+		UnleashMetricsInterval: &config.MetricsInterval, FeatureFlagTimeout: &config.Timeout,
+		ImpressionFlushInterval: &config.Impressions.FlushInterval} {
+		if value, ok := os.LookupEnv(key); ok {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s is not a duration: %q", key, value))
+			}
+			*interval = d
+		}
+	}
+	for key, number := range map[string]*int{FeatureFlagWorkers: &config.Workers,
+		ImpressionBatchSize: &config.Impressions.BatchSize} {
+		if value, ok := os.LookupEnv(key); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s is not a number: %q", key, value))
+			}
+			*number = n
+		}
+	}
+	if value, ok := os.LookupEnv(ImpressionSampleRate); ok {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s is not a number: %q", ImpressionSampleRate, value))
+		}
+		config.Impressions.SampleRate = &rate
+	}
+	for _, header := range strings.Split(os.Getenv(UnleashHeaders), HeaderSeparator) {
+		if strings.TrimSpace(header) == "" {
+			continue
+		}
+		i := strings.Index(header, ":")
+		if i <= 0 {
+			problems = append(problems, fmt.Sprintf("%s holds a header without a name: %q", UnleashHeaders, header))
+			continue
+		}
+		config.Headers.Add(strings.TrimSpace(header[:i]), strings.TrimSpace(header[i+1:]))
+	}
+	for _, flag := range strings.Split(os.Getenv(FeatureFlagDefaults), CommaSeparator) {
+		if strings.TrimSpace(flag) == "" {
+			continue
+		}
+		i := strings.Index(flag, "=")
+		value, err := strconv.ParseBool(strings.TrimSpace(flag[i+1:]))
+		if i <= 0 || err != nil {
+			problems = append(problems, fmt.Sprintf("%s holds an invalid default: %q", FeatureFlagDefaults, flag))
+			continue
+		}
+		config.Defaults[strings.TrimSpace(flag[:i])] = value
+	}
+	for _, app := range strings.Split(os.Getenv(FeatureFlagApps), CommaSeparator) {
+		if app = strings.TrimSpace(app); app != "" {
+			config.Apps = append(config.Apps, app)
+		}
+	}
+	prerequisites, err := ParsePrerequisites(os.Getenv(FeatureFlagPrerequisites))
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	config.Prerequisites = prerequisites
+	if err := config.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := config.Impressions.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		return config, fmt.Errorf("invalid feature flag configuration: %s", strings.Join(problems, "; "))
+	}
+	return config, nil
+}
+
+// Validate checks the configuration of the chosen provider is complete and consistent, and that the prerequisites of
+// the flags form no cycle, every problem being reported in the error
+func (c Config) Validate() error {
+	var problems []string
+	for _, err := range []error{c.validateProvider(), c.Prerequisites.Validate()} {
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateProvider checks the configuration of the chosen provider is complete and consistent
+func (c Config) validateProvider() error {
+	switch c.provider() {
+	case UnleashProviderName:
+		return c.validateUnleash()
+	case FileProviderName:
+		if c.File == "" {
+			return fmt.Errorf("the file provider takes the path of the file defining the flags in %s", FeatureFile)
+		}
+	case OpenFeatureProviderName:
+		if err := c.validateOpenFeature(); err != nil {
+			return err
+		}
+	}
+	for _, name := range Providers() {
+		if name == c.provider() {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown feature flag provider %q, expecting one of %s", c.provider(),
+		strings.Join(Providers(), ", "))
+}
+
+// validateOpenFeature checks the OpenFeature provider the flags are resolved with is registered, the SDK resolving
+// every flag to its default value otherwise
+func (c Config) validateOpenFeature() error {
+	for _, name := range OpenFeatureBackends() {
+		if name == c.OpenFeatureBackend {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown OpenFeature provider %q set in %s, expecting one of %s", c.OpenFeatureBackend,
+		OpenFeatureBackendKey, strings.Join(OpenFeatureBackends(), ", "))
+}
+
+// validateUnleash checks the configuration of the unleash client is complete and consistent, every problem being
+// reported in the error
+func (c Config) validateUnleash() error {
+	var problems []string
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("the URL of the unleash server is not an http(s) URL: %q", c.URL))
+	}
+	if c.AppName == "" {
+		problems = append(problems, "the app name is missing")
+	}
+	if c.RefreshInterval < 0 {
+		problems = append(problems, fmt.Sprintf("the refresh interval is negative: %v", c.RefreshInterval))
+	}
+	if c.MetricsInterval < 0 {
+		problems = append(problems, fmt.Sprintf("the metrics interval is negative: %v", c.MetricsInterval))
+	}
+	if c.Timeout < 0 {
+		problems = append(problems, fmt.Sprintf("the evaluation timeout is negative: %v", c.Timeout))
+	}
+	if c.APIToken != "" && (c.Username != "" || c.Password != "") {
+		problems = append(problems, "both an API token and a username and password are given")
+	}
+	if (c.Username == "") != (c.Password == "") {
+		problems = append(problems, "the basic authentication takes both a username and a password")
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// provider the name of the chosen provider
+func (c Config) provider() string {
+	if c.Provider == "" {
+		return UnleashProviderName
+	}
+	return c.Provider
+}
+
+// APIURL the URL of the API of the unleash server
+func (c Config) APIURL() string {
+	base := strings.TrimSuffix(c.URL, "/")
+	if !strings.HasSuffix(base, UnleashAPISuffix) {
+		base += UnleashAPISuffix
+	}
+	return base + "/"
+}
+
+// headers the headers sent to the unleash server: the custom headers along with the authorization
+func (c Config) headers() http.Header {
+	headers := http.Header{}
+	for name, values := range c.Headers {
+		headers[name] = append([]string(nil), values...)
+	}
+	switch {
+	case c.APIToken != "":
+		headers.Set(AuthorizationHeader, c.APIToken)
+	case c.Username != "":
+		headers.Set(AuthorizationHeader, AuthorizationBasicPrefix+
+			base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password)))
+	}
+	return headers
+}
+
+// envOrDefault env variable or fall back to default when not set or empty
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
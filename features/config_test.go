@@ -0,0 +1,84 @@
+package features_test
+
+import (
+	. "github.com/akhettar/app-features-manager/features"
+	"github.com/akhettar/app-features-manager/test"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestConfigFromEnv_ShouldReadTheConfiguration checks the configuration is read from the environment variables
+func TestConfigFromEnv_ShouldReadTheConfiguration(t *testing.T) {
+	env := map[string]string{UnleashBaseURL: "https://unleash.acme.com", UnleashAppNameKey: "status-api",
+		UnleashEnvironment: "production", UnleashRefreshInterval: "30s", UnleashAPIToken: "*:production.secret",
+		UnleashHeaders: "X-Team: mobile; X-Region: eu", FeatureFlagDefaults: "Dark=true, com.acme.banking.Pay=false",
+		FeatureFlagWorkers: "32", FeatureFlagTimeout: "500ms", ImpressionSink: WebhookSinkName,
+		ImpressionWebhookURL: "https://events.acme.com/impressions", ImpressionSampleRate: "0.25",
+		FeatureFlagPrerequisites: "MIF_LIMITED_COMPANY=MIF", FeatureFlagApps: "com.acme.banking, com.acme.wallet"}
+	setEnv(env)
+	defer unsetEnv(env)
+
+	t.Logf("Given the unleash client is configured through the environment")
+	{
+		t.Logf("\tWhen reading the configuration")
+		{
+			config, err := ConfigFromEnv()
+			test.Ok(err, t)
+			if config.APIURL() == "https://unleash.acme.com/api/" && config.AppName == "status-api" &&
+				config.Environment == "production" && config.RefreshInterval == 30*time.Second &&
+				config.APIToken == "*:production.secret" && config.Headers.Get("X-Region") == "eu" &&
+				reflect.DeepEqual(config.Defaults, map[string]bool{"Dark": true, "com.acme.banking.Pay": false}) &&
+				config.Workers == 32 && config.Timeout == 500*time.Millisecond &&
+				config.Impressions.WebhookURL == "https://events.acme.com/impressions" &&
+				config.Impressions.SampleRate != nil && *config.Impressions.SampleRate == 0.25 &&
+				reflect.DeepEqual(config.Prerequisites, Prerequisites{"MIF_LIMITED_COMPANY": {"MIF"}}) &&
+				reflect.DeepEqual(config.Apps, []string{"com.acme.banking", "com.acme.wallet"}) {
+				t.Logf("\t\tThe configuration should have been read %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe configuration should have been read %+v %v", config, test.BallotX)
+			}
+		}
+	}
+}
+
+// TestConfigFromEnv_ShouldReportEveryProblem checks an invalid configuration is reported with every problem it has
+func TestConfigFromEnv_ShouldReportEveryProblem(t *testing.T) {
+	env := map[string]string{UnleashBaseURL: "unleash.acme.com", UnleashMetricsInterval: "often",
+		UnleashAPIToken: "*:production.secret", UnleashUsername: "admin", UnleashHeaders: "X-Team",
+		FeatureFlagDefaults: "Dark=maybe", FeatureFlagWorkers: "many", ImpressionSink: "kafka",
+		FeatureFlagPrerequisites: "MIF=MIF_LIMITED_COMPANY; MIF_LIMITED_COMPANY=MIF"}
+	setEnv(env)
+	defer unsetEnv(env)
+
+	t.Logf("Given the unleash client is misconfigured through the environment")
+	{
+		t.Logf("\tWhen reading the configuration")
+		{
+			_, err := ConfigFromEnv()
+			expected := []string{UnleashMetricsInterval, "X-Team", "Dark=maybe", FeatureFlagWorkers, "not an http(s) URL",
+				"both an API token", "both a username and a password", "kafka", "MIF -> MIF_LIMITED_COMPANY -> MIF"}
+			for _, problem := range expected {
+				if err != nil && strings.Contains(err.Error(), problem) {
+					t.Logf("\t\tThe error should report %q %v", problem, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe error should report %q %v %v", problem, err, test.BallotX)
+				}
+			}
+		}
+	}
+}
+
+func setEnv(env map[string]string) {
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+}
+
+func unsetEnv(env map[string]string) {
+	for key := range env {
+		os.Unsetenv(key)
+	}
+}
@@ -0,0 +1,42 @@
+package features
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvFlagPrefix the prefix of the environment variables defining the flags for the env provider, e.g. "FLAG_Dark=true"
+// or "FLAG_com.acme.banking.Pay=false"
+const EnvFlagPrefix = "FLAG_"
+
+// NewEnvProvider creates a provider evaluating the flags defined by the given environment, in the "key=value" form
+// of os.Environ. Every variable starting with EnvFlagPrefix defines the flag named after the rest of its name, enabled
+// on every platform and version when its value is true.
+func NewEnvProvider(environ []string) (*StaticProvider, error) {
+	definitions := make(map[string]Definition)
+	var problems []string
+	for _, variable := range environ {
+		i := strings.Index(variable, "=")
+		if i < 0 || !strings.HasPrefix(variable[:i], EnvFlagPrefix) || i == len(EnvFlagPrefix) {
+			continue
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(variable[i+1:]))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s is not a boolean: %q", variable[:i], variable[i+1:]))
+			continue
+		}
+		definitions[variable[len(EnvFlagPrefix):i]] = Definition{Enabled: enabled}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid flags: %s", strings.Join(problems, "; "))
+	}
+	return NewStaticProvider(definitions), nil
+}
+
+func init() {
+	Register(EnvProviderName, func(config Config) (Provider, error) {
+		return NewEnvProvider(os.Environ())
+	})
+}
@@ -0,0 +1,109 @@
+package features
+
+import (
+	"github.com/Unleash/unleash-client-go/v3/context"
+	"github.com/akhettar/app-features-manager/model"
+	"strings"
+)
+
+const (
+
+	// VersionProperty the unleash context property holding the version of the app the flags are evaluated for
+	VersionProperty = "appVersion"
+
+	// PlatformProperty the unleash context property holding the platform of the app the flags are evaluated for
+	PlatformProperty = "platform"
+
+	// LocaleProperty the unleash context property holding the preferred locale of the user, e.g. "fr-CA"
+	LocaleProperty = "locale"
+
+	// AppVersionStrategyName the name of the strategy enabling the flags for some platforms and app versions
+	AppVersionStrategyName = "appVersion"
+
+	// PlatformsParameter the parameter of the app version strategy listing the platforms, comma separated, every
+	// platform being enabled when empty
+	PlatformsParameter = "platforms"
+
+	// VersionRangeParameter the parameter of the app version strategy holding the range of the versions enabled, e.g.
+	// ">=5.0", every version being enabled when empty
+	VersionRangeParameter = "versionRange"
+)
+
+// EvaluationContext the attributes of the request the flags are evaluated against
+type EvaluationContext struct {
+
+	// UserID the identifier of the customer
+	UserID string
+
+	// SessionID the identifier of the session of the customer
+	SessionID string
+
+	// RemoteAddress the address of the device
+	RemoteAddress string
+
+	// Version the version of the app
+	Version string
+
+	// Platform the platform of the app, e.g. "android"
+	Platform string
+
+	// Locale the preferred locale of the user, e.g. "fr-CA"
+	Locale string
+
+	// Properties custom properties, overridden by the properties of the attributes above
+	Properties map[string]string
+}
+
+// unleashContext the unleash context evaluating the flags of the given app, the default app when empty
+func (e EvaluationContext) unleashContext(app string) context.Context {
+	properties := make(map[string]string, len(e.Properties)+4)
+	for name, value := range e.Properties {
+		properties[name] = value
+	}
+	for name, value := range map[string]string{AppProperty: app, VersionProperty: e.Version,
+		PlatformProperty: e.Platform, LocaleProperty: e.Locale} {
+		if value != "" {
+			properties[name] = value
+		}
+	}
+	return context.Context{UserId: e.UserID, SessionId: e.SessionID, RemoteAddress: e.RemoteAddress,
+		Properties: properties}
+}
+
+// AppVersionStrategy the unleash strategy enabling a flag for the platforms and the app versions given by its
+// parameters, e.g. on android >= 5.0 only with the platforms "android" and the version range ">=5.0"
+type AppVersionStrategy struct{}
+
+// Name the name of the strategy in unleash
+func (s AppVersionStrategy) Name() string {
+	return AppVersionStrategyName
+}
+
+// IsEnabled tells whether the platform and the version of the app in the given context match the parameters
+func (s AppVersionStrategy) IsEnabled(params map[string]interface{}, ctx *context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	if platforms, _ := params[PlatformsParameter].(string); strings.TrimSpace(platforms) != "" {
+		matched := false
+		for _, platform := range strings.Split(platforms, CommaSeparator) {
+			if strings.EqualFold(strings.TrimSpace(platform), ctx.Properties[PlatformProperty]) {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if versionRange, _ := params[VersionRangeParameter].(string); strings.TrimSpace(versionRange) != "" {
+		constraint, err := model.ParseConstraint(versionRange)
+		if err != nil {
+			return false
+		}
+		version, err := model.ParseVersion(ctx.Properties[VersionProperty])
+		if err != nil || !constraint.Check(version) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,34 @@
+package features_test
+
+import (
+	"github.com/Unleash/unleash-client-go/v3/context"
+	. "github.com/akhettar/app-features-manager/features"
+	"github.com/akhettar/app-features-manager/test"
+	"testing"
+)
+
+// TestAppVersionStrategy_ShouldEnableTheFlagsOfThePlatformVersions checks the strategy enables a flag on android >= 5.0 only
+func TestAppVersionStrategy_ShouldEnableTheFlagsOfThePlatformVersions(t *testing.T) {
+	strategy := AppVersionStrategy{}
+	params := map[string]interface{}{PlatformsParameter: "android", VersionRangeParameter: ">=5.0"}
+
+	t.Logf("Given a flag enabled on android >= 5.0 only")
+	{
+		expectations := []struct {
+			platform, version string
+			enabled           bool
+		}{{"android", "5.0.0", true}, {"Android", "6.1", true}, {"android", "4.9.9", false},
+			{"ios", "5.0.0", false}, {"android", "", false}}
+		for _, e := range expectations {
+			t.Logf("\tWhen evaluating the flag on %s %s", e.platform, e.version)
+			{
+				ctx := &context.Context{Properties: map[string]string{PlatformProperty: e.platform, VersionProperty: e.version}}
+				if strategy.IsEnabled(params, ctx) == e.enabled {
+					t.Logf("\t\tThe flag should be enabled: %v %v", e.enabled, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe flag should be enabled: %v %v", e.enabled, test.BallotX)
+				}
+			}
+		}
+	}
+}
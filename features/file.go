@@ -0,0 +1,56 @@
+package features
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// FeatureFile environment variable setting the path of the YAML or JSON file defining the flags, read by the file
+// provider
+const FeatureFile = "FEATURE_FILE"
+
+// NewFileProvider creates a provider evaluating the flags defined in the given file, read once. The file maps the
+// name of each flag to its definition, in YAML when its extension is ".yaml" or ".yml" and in JSON otherwise, e.g.
+//
+//	Dark:
+//	  enabled: true
+//	com.acme.banking.Pay:
+//	  enabled: true
+//	  platforms: [android]
+//	  versionRange: ">=5.0"
+func NewFileProvider(path string) (*StaticProvider, error) {
+	definitions, err := readDefinitions(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewStaticProvider(definitions), nil
+}
+
+// readDefinitions reads the definitions of the flags of the given file, by name
+func readDefinitions(path string) (map[string]Definition, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the flags: %w", err)
+	}
+	definitions := make(map[string]Definition)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(content, &definitions)
+	default:
+		err = json.Unmarshal(content, &definitions)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the flags of %s: %w", path, err)
+	}
+	return definitions, nil
+}
+
+func init() {
+	Register(FileProviderName, func(config Config) (Provider, error) {
+		return NewFileProvider(config.File)
+	})
+}
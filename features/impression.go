@@ -0,0 +1,287 @@
+package features
+
+import (
+	"context"
+	"github.com/akhettar/app-features-manager/model"
+	"github.com/akhettar/app-features-manager/repository"
+	"github.com/labstack/gommon/log"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+
+	// DefaultImpressionBatchSize the default number of impressions sent to the sink at once
+	DefaultImpressionBatchSize = 100
+
+	// DefaultImpressionSampleRate the default share of the evaluations sent to the sink: every evaluation
+	DefaultImpressionSampleRate = 1.0
+
+	// DefaultImpressionFlushInterval the default interval the impressions queued are sent to the sink at
+	DefaultImpressionFlushInterval = 10 * time.Second
+
+	// impressionQueueBatches the number of batches the queue of the sink holds, the impressions being dropped once
+	// the queue is full
+	impressionQueueBatches = 10
+)
+
+// Impression the evaluation of a flag for a customer
+type Impression struct {
+
+	// Flag the name of the flag, without the app prefix
+	Flag string `json:"flag"`
+
+	// App the identifier of the app of the flag, empty for the default app
+	App string `json:"app,omitempty"`
+
+	// Enabled whether the flag was enabled
+	Enabled bool `json:"enabled"`
+
+	// Variant the name of the variant of the flag, only known when the toggles were evaluated
+	Variant string `json:"variant,omitempty"`
+
+	// UserID the identifier of the customer
+	UserID string `json:"userId,omitempty"`
+
+	// Platform the platform of the app
+	Platform string `json:"platform,omitempty"`
+
+	// Version the version of the app
+	Version string `json:"version,omitempty"`
+
+	// Time the time of the evaluation
+	Time time.Time `json:"time"`
+}
+
+// Sink the destination of the impressions, receiving them in batches from a single goroutine
+type Sink interface {
+	Send(impressions []Impression) error
+}
+
+// ImpressionProvider the provider recording every evaluation of the flags of the given provider: the evaluations are
+// counted by flag and result, and a sample of them is sent in batches to the sink as impressions. The recording is
+// shared by the providers of every app.
+type ImpressionProvider struct {
+	Provider
+	*impressions
+
+	// App the identifier of the app the flags are evaluated for, the default app when empty
+	App string
+}
+
+// impressions the counters of the evaluations and the queue of the impressions sent to the sink
+type impressions struct {
+	sync.Mutex
+	config   ImpressionConfig
+	sink     Sink
+	counters map[string]*model.FlagImpressions
+	stats    model.ImpressionStats
+	queue    chan Impression
+	done     chan struct{}
+	closed   bool
+}
+
+// NewImpressionProvider creates a provider recording the evaluations of the flags of the given provider, sending the
+// impressions to the given sink, if any, until closed
+func NewImpressionProvider(provider Provider, sink Sink, config ImpressionConfig) *ImpressionProvider {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultImpressionBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultImpressionFlushInterval
+	}
+	if config.SampleRate == nil {
+		rate := DefaultImpressionSampleRate
+		config.SampleRate = &rate
+	}
+	recorder := &impressions{config: config, sink: sink, counters: make(map[string]*model.FlagImpressions),
+		done: make(chan struct{})}
+	if sink != nil {
+		recorder.queue = make(chan Impression, config.BatchSize*impressionQueueBatches)
+		go recorder.run()
+	} else {
+		close(recorder.done)
+	}
+	return &ImpressionProvider{Provider: provider, impressions: recorder}
+}
+
+// ForApp returns a provider recording the evaluations of the flags of the given app
+func (p *ImpressionProvider) ForApp(app string) Provider {
+	return &ImpressionProvider{Provider: p.Provider.ForApp(app), impressions: p.impressions, App: app}
+}
+
+// FetchFeatureFlags returns whether each flag of the app is enabled for the given context, recording the evaluations
+func (p *ImpressionProvider) FetchFeatureFlags(ctx context.Context, evaluation EvaluationContext) (Flags, error) {
+	flags, err := p.Provider.FetchFeatureFlags(ctx, evaluation)
+	now := time.Now()
+	evaluated := make([]Impression, 0, len(flags))
+	for flag, enabled := range flags {
+		evaluated = append(evaluated, p.impression(flag, model.Toggle{Enabled: enabled}, evaluation, now))
+	}
+	p.record(evaluated)
+	return flags, err
+}
+
+// FetchToggles returns the toggles of the app for the given context, recording the evaluations along with the variants
+func (p *ImpressionProvider) FetchToggles(ctx context.Context, evaluation EvaluationContext) (Toggles, error) {
+	toggles, err := p.Provider.FetchToggles(ctx, evaluation)
+	now := time.Now()
+	evaluated := make([]Impression, 0, len(toggles))
+	for flag, toggle := range toggles {
+		evaluated = append(evaluated, p.impression(flag, toggle, evaluation, now))
+	}
+	p.record(evaluated)
+	return toggles, err
+}
+
+// Stale tells whether the flags of the given provider are stale, when it tells
+func (p *ImpressionProvider) Stale() bool {
+	provider, ok := p.Provider.(interface{ Stale() bool })
+	return ok && provider.Stale()
+}
+
+// Invalidate drops the flag definitions loaded by the given provider, when it loads any
+func (p *ImpressionProvider) Invalidate() {
+	if provider, ok := p.Provider.(interface{ Invalidate() }); ok {
+		provider.Invalidate()
+	}
+}
+
+// Refresh expires the flag definitions of the given provider the given change may have changed, when it loads any
+func (p *ImpressionProvider) Refresh(event repository.ChangeEvent) {
+	if provider, ok := p.Provider.(interface{ Refresh(repository.ChangeEvent) }); ok {
+		provider.Refresh(event)
+	}
+}
+
+// impression the impression of the given evaluation of a flag of the app
+func (p *ImpressionProvider) impression(flag string, toggle model.Toggle, evaluation EvaluationContext,
+	at time.Time) Impression {
+	impression := Impression{Flag: flag, App: p.App, Enabled: toggle.Enabled, UserID: evaluation.UserID,
+		Platform: evaluation.Platform, Version: evaluation.Version, Time: at}
+	if toggle.Variant != nil {
+		impression.Variant = toggle.Variant.Name
+	}
+	return impression
+}
+
+// Stats returns the evaluations of the flags since start up, along with the impressions sent to the sink
+func (i *impressions) Stats() model.ImpressionStats {
+	i.Lock()
+	defer i.Unlock()
+	stats := i.stats
+	stats.Flags = make([]model.FlagImpressions, 0, len(i.counters))
+	for _, counter := range i.counters {
+		flag := *counter
+		if counter.Variants != nil {
+			flag.Variants = make(map[string]uint64, len(counter.Variants))
+			for variant, count := range counter.Variants {
+				flag.Variants[variant] = count
+			}
+		}
+		stats.Flags = append(stats.Flags, flag)
+	}
+	sort.Slice(stats.Flags, func(a, b int) bool { return stats.Flags[a].Flag < stats.Flags[b].Flag })
+	return stats
+}
+
+// Close sends the impressions queued to the sink, closing it when it can be, and stops recording them
+func (i *impressions) Close() error {
+	i.Lock()
+	closed := i.closed
+	if !closed && i.queue != nil {
+		close(i.queue)
+	}
+	i.closed = true
+	i.Unlock()
+	<-i.done
+	if closer, ok := i.sink.(io.Closer); ok && !closed {
+		return closer.Close()
+	}
+	return nil
+}
+
+// record counts the given evaluations and queues the ones sampled for the sink, dropping them when the queue is full
+func (i *impressions) record(evaluated []Impression) {
+	i.Lock()
+	defer i.Unlock()
+	for _, impression := range evaluated {
+		name := appPrefix(impression.App) + impression.Flag
+		counter, ok := i.counters[name]
+		if !ok {
+			counter = &model.FlagImpressions{Flag: name}
+			i.counters[name] = counter
+		}
+		if !impression.Enabled {
+			counter.Disabled++
+		} else {
+			counter.Enabled++
+			if impression.Variant != "" {
+				if counter.Variants == nil {
+					counter.Variants = make(map[string]uint64)
+				}
+				counter.Variants[impression.Variant]++
+			}
+		}
+
+		if i.queue == nil || i.closed || !i.sampled() {
+			continue
+		}
+		i.stats.Sampled++
+		select {
+		case i.queue <- impression:
+		default:
+			i.stats.Dropped++
+		}
+	}
+}
+
+// sampled tells whether an impression is sampled to be sent to the sink, none being sampled at a rate of zero or below
+func (i *impressions) sampled() bool {
+	rate := *i.config.SampleRate
+	return rate >= 1 || rand.Float64() < rate
+}
+
+// run sends the impressions queued to the sink in batches, once a batch is full or the flush interval elapsed, until
+// the queue is closed
+func (i *impressions) run() {
+	defer close(i.done)
+	ticker := time.NewTicker(i.config.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]Impression, 0, i.config.BatchSize)
+	for {
+		select {
+		case impression, ok := <-i.queue:
+			if !ok {
+				i.send(batch)
+				return
+			}
+			if batch = append(batch, impression); len(batch) >= i.config.BatchSize {
+				i.send(batch)
+				batch = make([]Impression, 0, i.config.BatchSize)
+			}
+		case <-ticker.C:
+			i.send(batch)
+			batch = make([]Impression, 0, i.config.BatchSize)
+		}
+	}
+}
+
+// send sends the given batch to the sink, counting the impressions sent or failed
+func (i *impressions) send(batch []Impression) {
+	if len(batch) == 0 {
+		return
+	}
+	err := i.sink.Send(batch)
+	i.Lock()
+	defer i.Unlock()
+	if err != nil {
+		log.Warnf("Failed to send %d impressions: %v", len(batch), err)
+		i.stats.Failed += uint64(len(batch))
+		return
+	}
+	i.stats.Sent += uint64(len(batch))
+}
@@ -0,0 +1,189 @@
+package features_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	. "github.com/akhettar/app-features-manager/features"
+	"github.com/akhettar/app-features-manager/model"
+	"github.com/akhettar/app-features-manager/test"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// TestImpressionProvider_ShouldRecordTheEvaluations checks every evaluation is counted by flag and result, and sent to
+// the sink in batches
+func TestImpressionProvider_ShouldRecordTheEvaluations(t *testing.T) {
+	sink := &collectingSink{}
+	provider := NewImpressionProvider(NewStaticProvider(map[string]Definition{
+		"Dark":                 {Enabled: true, Variant: &model.Variant{Name: "blue"}},
+		"Chat":                 {Enabled: false},
+		"com.acme.banking.Pay": {Enabled: true},
+	}), sink, ImpressionConfig{BatchSize: 2})
+
+	t.Logf("Given the evaluations of the flags are recorded")
+	{
+		t.Logf("\tWhen evaluating the flags and the toggles of a customer and the flags of an app")
+		{
+			evaluation := EvaluationContext{UserID: "customer", Platform: "android", Version: "5.1"}
+			fetchFlags(provider, evaluation, t)
+			fetchToggles(provider, evaluation, t)
+			fetchFlags(provider.ForApp("com.acme.banking"), evaluation, t)
+			test.Ok(provider.Close(), t)
+
+			expected := []model.FlagImpressions{{Flag: "Chat", Disabled: 2},
+				{Flag: "Dark", Enabled: 2, Variants: map[string]uint64{"blue": 1}},
+				{Flag: "com.acme.banking.Pay", Enabled: 1}}
+			stats := provider.Stats()
+			if reflect.DeepEqual(stats.Flags, expected) {
+				t.Logf("\t\tThe evaluations should be counted by flag and result %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe evaluations should be counted by flag and result %+v %v", stats.Flags, test.BallotX)
+			}
+			if stats.Sampled == 5 && stats.Sent == 5 && len(sink.impressions) == 5 && sink.batches == 3 {
+				t.Logf("\t\tThe impressions should be sent in batches of 2 %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe impressions should be sent in batches of 2 %+v %v %v", stats, sink.batches,
+					test.BallotX)
+			}
+			pay := sink.impressions[len(sink.impressions)-1]
+			if pay == (Impression{Flag: "Pay", App: "com.acme.banking", Enabled: true, UserID: "customer",
+				Platform: "android", Version: "5.1", Time: pay.Time}) {
+				t.Logf("\t\tThe impression should describe the evaluation %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe impression should describe the evaluation %+v %v", pay, test.BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given a tenth of the evaluations are sampled")
+	{
+		rate := 0.1
+		sampled := NewImpressionProvider(NewStaticProvider(map[string]Definition{"Dark": {Enabled: true}}),
+			&collectingSink{}, ImpressionConfig{SampleRate: &rate})
+
+		t.Logf("\tWhen evaluating the flags 2000 times")
+		{
+			for i := 0; i < 2000; i++ {
+				sampled.FetchFeatureFlags(context.Background(), EvaluationContext{})
+			}
+			test.Ok(sampled.Close(), t)
+			stats := sampled.Stats()
+			if stats.Flags[0].Enabled == 2000 && stats.Sampled > 100 && stats.Sampled < 300 {
+				t.Logf("\t\tAbout a tenth of the evaluations should be sent %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tAbout a tenth of the evaluations should be sent %+v %v", stats, test.BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given none of the evaluations are sampled")
+	{
+		rate := 0.0
+		sink := &collectingSink{}
+		unsampled := NewImpressionProvider(NewStaticProvider(map[string]Definition{"Dark": {Enabled: true}}),
+			sink, ImpressionConfig{SampleRate: &rate})
+
+		t.Logf("\tWhen evaluating the flags 100 times")
+		{
+			for i := 0; i < 100; i++ {
+				unsampled.FetchFeatureFlags(context.Background(), EvaluationContext{})
+			}
+			test.Ok(unsampled.Close(), t)
+			stats := unsampled.Stats()
+			if stats.Flags[0].Enabled == 100 && stats.Sampled == 0 && len(sink.impressions) == 0 {
+				t.Logf("\t\tThe evaluations should be counted but none sent %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe evaluations should be counted but none sent %+v %v", stats, test.BallotX)
+			}
+		}
+	}
+}
+
+// TestSinks_ShouldDeliverTheImpressions checks the impressions are appended to the file and posted to the webhook
+func TestSinks_ShouldDeliverTheImpressions(t *testing.T) {
+	impressions := []Impression{{Flag: "Dark", Enabled: true, UserID: "customer"}, {Flag: "Chat"}}
+	dir, err := ioutil.TempDir("", "impressions")
+	test.Ok(err, t)
+	defer os.RemoveAll(dir)
+
+	t.Logf("Given the impressions are appended to a file")
+	{
+		sink, err := ImpressionConfig{Sink: FileSinkName, File: filepath.Join(dir, "impressions.json")}.NewSink()
+		test.Ok(err, t)
+
+		t.Logf("\tWhen sending the impressions")
+		{
+			test.Ok(sink.Send(impressions), t)
+			test.Ok(sink.(*FileSink).Close(), t)
+			file, err := os.Open(filepath.Join(dir, "impressions.json"))
+			test.Ok(err, t)
+			defer file.Close()
+			var received []Impression
+			for scanner := bufio.NewScanner(file); scanner.Scan(); {
+				var impression Impression
+				test.Ok(json.Unmarshal(scanner.Bytes(), &impression), t)
+				received = append(received, impression)
+			}
+			expectImpressions(received, impressions, t)
+		}
+	}
+
+	t.Logf("Given the impressions are posted to a webhook")
+	{
+		status := http.StatusAccepted
+		var received []Impression
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&received)
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+		sink, err := ImpressionConfig{Sink: WebhookSinkName, WebhookURL: server.URL}.NewSink()
+		test.Ok(err, t)
+
+		t.Logf("\tWhen sending the impressions")
+		{
+			test.Ok(sink.Send(impressions), t)
+			expectImpressions(received, impressions, t)
+		}
+
+		t.Logf("\tWhen the webhook fails")
+		{
+			status = http.StatusServiceUnavailable
+			if sink.Send(impressions) != nil {
+				t.Logf("\t\tThe impressions should have failed to be sent %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe impressions should have failed to be sent %v", test.BallotX)
+			}
+		}
+	}
+}
+
+func expectImpressions(received, expected []Impression, t *testing.T) {
+	if reflect.DeepEqual(received, expected) {
+		t.Logf("\t\tThe impressions should have been received %v", test.CheckMark)
+	} else {
+		t.Errorf("\t\tThe impressions should have been received %+v %v", received, test.BallotX)
+	}
+}
+
+// collectingSink a sink collecting the impressions it receives
+type collectingSink struct {
+	sync.Mutex
+	impressions []Impression
+	batches     int
+}
+
+func (s *collectingSink) Send(impressions []Impression) error {
+	s.Lock()
+	defer s.Unlock()
+	s.impressions = append(s.impressions, impressions...)
+	s.batches++
+	return nil
+}
@@ -0,0 +1,254 @@
+package features
+
+import (
+	"context"
+	"errors"
+	"github.com/akhettar/app-features-manager/model"
+	"github.com/akhettar/app-features-manager/repository"
+	"github.com/labstack/gommon/log"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+
+	// LocalProviderName the name of the provider evaluating the flag definitions stored in the repository
+	LocalProviderName = "local"
+
+	// DefaultLocalRefreshInterval the default interval the local provider reloads the flag definitions at
+	DefaultLocalRefreshInterval = 10 * time.Second
+
+	// localLoadTimeout the deadline of the loading of the flag definitions of an app
+	localLoadTimeout = 2 * time.Second
+
+	// rolloutBuckets the number of buckets the customers and the sessions are hashed into, one per percent
+	rolloutBuckets = 100
+)
+
+// LocalProvider the provider evaluating the flag definitions stored in the repository itself, without any flag
+// server. The definitions of each app are loaded on their first evaluation and reloaded once older than the refresh
+// interval, the definitions previously loaded being evaluated while the repository is unavailable. The concurrent
+// evaluations of definitions to load wait for a single load of the repository.
+type LocalProvider struct {
+	*snapshots
+	repo repository.Repository
+
+	// App the identifier of the app the flags are evaluated for, the default app when empty
+	App string
+}
+
+// snapshots the flag definitions loaded for each app, shared by the providers of every app
+type snapshots struct {
+	sync.Mutex
+	interval time.Duration
+	apps     map[string]*snapshot
+	loads    map[string]*load
+
+	// epoch the number of invalidations, the definitions loaded before an invalidation not being kept
+	epoch uint64
+}
+
+// load a load of the flag definitions of an app the concurrent evaluations wait for, until an invalidation
+type load struct {
+	done  chan struct{}
+	epoch uint64
+	flags []model.FlagDAO
+}
+
+// snapshot the flag definitions of an app along with the time they were loaded at, stale when the repository failed
+// to reload them
+type snapshot struct {
+	flags  []model.FlagDAO
+	loaded time.Time
+	stale  bool
+}
+
+// NewLocalProvider creates a provider evaluating the flag definitions stored in the given repository, reloaded at
+// the given interval
+func NewLocalProvider(repo repository.Repository, interval time.Duration) *LocalProvider {
+	if interval <= 0 {
+		interval = DefaultLocalRefreshInterval
+	}
+	return &LocalProvider{snapshots: &snapshots{interval: interval, apps: make(map[string]*snapshot),
+		loads: make(map[string]*load)}, repo: repo}
+}
+
+func init() {
+	Register(LocalProviderName, func(config Config) (Provider, error) {
+		if config.Repository == nil {
+			return nil, errors.New("the local provider takes the repository of the flag definitions")
+		}
+		return NewLocalProvider(config.Repository, config.RefreshInterval), nil
+	})
+}
+
+// ForApp returns a provider evaluating the flags of the given app
+func (p *LocalProvider) ForApp(app string) Provider {
+	return &LocalProvider{snapshots: p.snapshots, repo: p.repo, App: app}
+}
+
+// FetchFeatureFlags returns whether each flag of the app is enabled for the given context
+func (p *LocalProvider) FetchFeatureFlags(ctx context.Context, evaluation EvaluationContext) (Flags, error) {
+	toggles, err := p.FetchToggles(ctx, evaluation)
+	return toggles.Flags(), err
+}
+
+// FetchToggles returns the toggles of the app for the given context, with the variant of the flags enabled
+func (p *LocalProvider) FetchToggles(ctx context.Context, evaluation EvaluationContext) (Toggles, error) {
+	now := time.Now()
+	results := make(Toggles)
+	for _, flag := range p.definitions(now) {
+		toggle := model.Toggle{Enabled: Evaluate(flag, evaluation, now)}
+		if toggle.Enabled {
+			toggle.Variant = flag.Variant
+		}
+		results[flag.Name] = toggle
+	}
+	return results, nil
+}
+
+// Invalidate drops the flag definitions loaded for the app, for the changes made through this replica to be
+// evaluated straight away
+func (p *LocalProvider) Invalidate() {
+	p.Lock()
+	defer p.Unlock()
+	delete(p.apps, p.App)
+	p.epoch++
+}
+
+// Refresh expires the flag definitions the given change may have changed, e.g. as notified by a repository.Watcher of
+// the writes of the other replicas, for them to be reloaded on their next evaluation. The definitions expired are
+// still evaluated when the repository fails to reload them.
+func (p *LocalProvider) Refresh(event repository.ChangeEvent) {
+	p.Lock()
+	defer p.Unlock()
+	for app, current := range p.apps {
+		if event.All || (event.Flags && app == event.App) {
+			p.apps[app] = &snapshot{flags: current.flags, stale: current.stale}
+		}
+	}
+	p.epoch++
+}
+
+// definitions returns the flag definitions of the app, reloading them when older than the refresh interval. The
+// concurrent evaluations of the definitions to reload wait for the first one to load them.
+func (p *LocalProvider) definitions(now time.Time) []model.FlagDAO {
+	p.Lock()
+	current, ok := p.apps[p.App]
+	if ok && now.Sub(current.loaded) < p.interval {
+		p.Unlock()
+		return current.flags
+	}
+	if l, loading := p.loads[p.App]; loading && l.epoch == p.epoch {
+		p.Unlock()
+		<-l.done
+		return l.flags
+	}
+	l := &load{done: make(chan struct{}), epoch: p.epoch}
+	p.loads[p.App] = l
+	p.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), localLoadTimeout)
+	defer cancel()
+	flags, err := p.repo.ForApp(p.App).FindFlags(ctx)
+	if err != nil {
+		log.Warnf("Failed to load the flag definitions of the app %q, evaluating the ones loaded before: %v", p.App, err)
+		if !ok {
+			current = &snapshot{}
+		}
+		flags = current.flags
+	}
+
+	p.Lock()
+	if p.loads[p.App] == l {
+		delete(p.loads, p.App)
+	}
+	if l.epoch == p.epoch {
+		p.apps[p.App] = &snapshot{flags: flags, loaded: now, stale: err != nil}
+	}
+	p.Unlock()
+	l.flags = flags
+	close(l.done)
+	return flags
+}
+
+// Stale tells whether the flags are evaluated from the definitions loaded before the repository failed to reload them
+func (p *LocalProvider) Stale() bool {
+	p.Lock()
+	defer p.Unlock()
+	current, ok := p.apps[p.App]
+	return ok && current.stale
+}
+
+// Evaluate tells whether the given flag is enabled for the given context at the given time: whether it is enabled
+// and has either no strategy or a strategy matching every criterion it sets
+func Evaluate(flag model.FlagDAO, evaluation EvaluationContext, at time.Time) bool {
+	if !flag.Enabled {
+		return false
+	}
+	if len(flag.Strategies) == 0 {
+		return true
+	}
+	for _, strategy := range flag.Strategies {
+		if matches(strategy, flag.Name, evaluation, at) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches tells whether the given context matches every criterion the strategy of the given flag sets
+func matches(strategy model.Strategy, flag string, evaluation EvaluationContext, at time.Time) bool {
+	group := strategy.GroupID
+	if group == "" {
+		group = flag
+	}
+	if strategy.Rollout != nil && !inRollout(group, evaluation.UserID, *strategy.Rollout) {
+		return false
+	}
+	if strategy.SessionRollout != nil && !inRollout(group, evaluation.SessionID, *strategy.SessionRollout) {
+		return false
+	}
+	if len(strategy.UserIDs) > 0 && !contains(strategy.UserIDs, evaluation.UserID) {
+		return false
+	}
+	if strategy.From != nil && at.Before(*strategy.From) {
+		return false
+	}
+	if strategy.Until != nil && !at.Before(*strategy.Until) {
+		return false
+	}
+	ctx := evaluation.unleashContext("")
+	params := map[string]interface{}{PlatformsParameter: strings.Join(strategy.Platforms, CommaSeparator),
+		VersionRangeParameter: strategy.VersionRange}
+	return AppVersionStrategy{}.IsEnabled(params, &ctx)
+}
+
+// inRollout tells whether the given identifier falls into the given percentage of the group. The identifiers are
+// hashed into stable buckets so that a customer keeps its flags as the percentage grows. Empty identifiers are never
+// rolled out to.
+func inRollout(group, id string, percentage int) bool {
+	if id == "" {
+		return false
+	}
+	return Bucket(group, id) < percentage
+}
+
+// Bucket returns the bucket from 0 to 99 the given identifier is hashed into within the given group
+func Bucket(group, id string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(group + ":" + id))
+	return int(hash.Sum32() % rolloutBuckets)
+}
+
+// contains tells whether the given values hold the given value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
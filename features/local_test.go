@@ -0,0 +1,239 @@
+package features_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "github.com/akhettar/app-features-manager/features"
+	"github.com/akhettar/app-features-manager/model"
+	"github.com/akhettar/app-features-manager/repository"
+	"github.com/akhettar/app-features-manager/test"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestEvaluate_ShouldMatchEveryCriterionOfAStrategy checks a flag is enabled when every criterion of one of its
+// strategies matches
+func TestEvaluate_ShouldMatchEveryCriterionOfAStrategy(t *testing.T) {
+	now := time.Now()
+	from, until := now.Add(-time.Hour), now.Add(time.Hour)
+	flag := model.FlagDAO{Name: "Chat", Enabled: true, Strategies: []model.Strategy{
+		{UserIDs: []string{"tester"}},
+		{Platforms: []string{"android"}, VersionRange: ">=5.0", From: &from, Until: &until},
+	}}
+
+	t.Logf("Given a flag enabled for a tester and on android >= 5.0 for the next hour")
+	{
+		expectations := []struct {
+			description string
+			evaluation  EvaluationContext
+			at          time.Time
+			enabled     bool
+		}{
+			{"the tester on ios", EvaluationContext{UserID: "tester", Platform: "ios"}, now, true},
+			{"a customer on android 5.1", EvaluationContext{UserID: "customer", Platform: "android", Version: "5.1"}, now, true},
+			{"a customer on android 4.0", EvaluationContext{UserID: "customer", Platform: "android", Version: "4.0"}, now, false},
+			{"a customer on android 5.1 tomorrow", EvaluationContext{Platform: "android", Version: "5.1"}, now.Add(24 * time.Hour), false},
+		}
+		for _, e := range expectations {
+			t.Logf("\tWhen evaluating the flag for %s", e.description)
+			{
+				if Evaluate(flag, e.evaluation, e.at) == e.enabled {
+					t.Logf("\t\tThe flag should be enabled: %v %v", e.enabled, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe flag should be enabled: %v %v", e.enabled, test.BallotX)
+				}
+			}
+		}
+
+		t.Logf("\tWhen the flag is disabled")
+		{
+			flag.Enabled = false
+			if !Evaluate(flag, EvaluationContext{UserID: "tester"}, now) {
+				t.Logf("\t\tThe flag should be disabled for everyone %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flag should be disabled for everyone %v", test.BallotX)
+			}
+		}
+	}
+}
+
+// TestEvaluate_ShouldRollOutToAStableShareOfTheCustomers checks the gradual rollout enables the flag for the share of
+// the customers it is rolled out to, the customers keeping the flag as the rollout grows
+func TestEvaluate_ShouldRollOutToAStableShareOfTheCustomers(t *testing.T) {
+	rollout := func(percentage int) model.FlagDAO {
+		return model.FlagDAO{Name: "Dark", Enabled: true, Strategies: []model.Strategy{{Rollout: &percentage}}}
+	}
+
+	t.Logf("Given a flag rolled out to 20%% then to 50%% of 10000 customers")
+	{
+		t.Logf("\tWhen evaluating the flag for every customer")
+		{
+			enabled, kept := 0, true
+			for i := 0; i < 10000; i++ {
+				customer := EvaluationContext{UserID: fmt.Sprintf("customer-%d", i)}
+				before, after := Evaluate(rollout(20), customer, time.Now()), Evaluate(rollout(50), customer, time.Now())
+				if before {
+					enabled++
+					kept = kept && after
+				}
+			}
+			if enabled > 1800 && enabled < 2200 {
+				t.Logf("\t\tAbout 20%% of the customers should have the flag %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tAbout 20%% of the customers should have the flag: %d %v", enabled, test.BallotX)
+			}
+			if kept {
+				t.Logf("\t\tThe customers should keep the flag as the rollout grows %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe customers should keep the flag as the rollout grows %v", test.BallotX)
+			}
+			if !Evaluate(rollout(100), EvaluationContext{}, time.Now()) {
+				t.Logf("\t\tThe anonymous customers should not have the flag %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe anonymous customers should not have the flag %v", test.BallotX)
+			}
+		}
+	}
+}
+
+// TestLocalProvider_ShouldEvaluateTheStoredFlags checks the flags of each app are evaluated from the repository
+func TestLocalProvider_ShouldEvaluateTheStoredFlags(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	ctx := context.Background()
+	test.Ok(repo.SaveFlag(ctx, model.FlagDAO{Name: "Dark", Enabled: true,
+		Variant: &model.Variant{Name: "blue"}}), t)
+	test.Ok(repo.ForApp("com.acme.banking").SaveFlag(ctx, model.FlagDAO{Name: "Pay", Enabled: true}), t)
+
+	t.Logf("Given the flags of the default app and of an app stored in the repository")
+	{
+		provider, err := NewProvider(Config{Provider: LocalProviderName, Repository: repo, RefreshInterval: time.Hour})
+		test.Ok(err, t)
+
+		t.Logf("\tWhen fetching the toggles of each app")
+		{
+			toggles := fetchToggles(provider, EvaluationContext{}, t)
+			expectFlags(toggles.Flags(), []string{"Dark"}, t)
+			if toggles["Dark"].Variant != nil && toggles["Dark"].Variant.Name == "blue" {
+				t.Logf("\t\tThe variant of the flag should have been returned %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe variant of the flag should have been returned %+v %v", toggles, test.BallotX)
+			}
+			expectFlags(fetchFlags(provider.ForApp("com.acme.banking"), EvaluationContext{}, t), []string{"Pay"}, t)
+		}
+
+		t.Logf("\tWhen a flag is defined before the refresh interval elapses")
+		{
+			test.Ok(repo.SaveFlag(ctx, model.FlagDAO{Name: "Chat", Enabled: true}), t)
+			expectFlags(fetchFlags(provider, EvaluationContext{}, t), []string{"Dark"}, t)
+			provider.(*LocalProvider).Invalidate()
+			expectFlags(fetchFlags(provider, EvaluationContext{}, t), []string{"Chat", "Dark"}, t)
+		}
+
+		t.Logf("\tWhen another replica defines a flag of an app and the change is watched")
+		{
+			test.Ok(repo.ForApp("com.acme.banking").SaveFlag(ctx, model.FlagDAO{Name: "Loans", Enabled: true}), t)
+			test.Ok(repo.SaveFlag(ctx, model.FlagDAO{Name: "Web", Enabled: true}), t)
+			provider.(*LocalProvider).Refresh(repository.ChangeEvent{App: "com.acme.banking", Flags: true})
+			expectFlags(fetchFlags(provider.ForApp("com.acme.banking"), EvaluationContext{}, t), []string{"Loans", "Pay"}, t)
+			expectFlags(fetchFlags(provider, EvaluationContext{}, t), []string{"Chat", "Dark"}, t)
+			provider.(*LocalProvider).Refresh(repository.ChangeEvent{All: true})
+			expectFlags(fetchFlags(provider, EvaluationContext{}, t), []string{"Chat", "Dark", "Web"}, t)
+		}
+	}
+}
+
+// TestLocalProvider_ShouldEvaluateTheLastFlagsLoaded checks the flags loaded before the repository failed are still
+// evaluated, flagged stale until the repository recovers
+func TestLocalProvider_ShouldEvaluateTheLastFlagsLoaded(t *testing.T) {
+	repo := &unavailableRepository{Repository: repository.NewMemoryRepository()}
+	test.Ok(repo.SaveFlag(context.Background(), model.FlagDAO{Name: "Dark", Enabled: true}), t)
+
+	t.Logf("Given the flags are reloaded from the repository on every evaluation")
+	{
+		provider := NewLocalProvider(repo, time.Nanosecond)
+		expectFlags(fetchFlags(provider, EvaluationContext{}, t), []string{"Dark"}, t)
+
+		for _, unavailable := range []bool{true, false} {
+			t.Logf("\tWhen the repository is unavailable: %v", unavailable)
+			{
+				repo.unavailable = unavailable
+				expectFlags(fetchFlags(provider, EvaluationContext{}, t), []string{"Dark"}, t)
+				if provider.Stale() == unavailable {
+					t.Logf("\t\tThe flags should be stale: %v %v", unavailable, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe flags should be stale: %v %v", unavailable, test.BallotX)
+				}
+			}
+		}
+	}
+}
+
+// TestLocalProvider_ShouldLoadTheFlagsOnceForTheConcurrentEvaluations checks the concurrent evaluations of the flags
+// to load wait for a single load of the repository
+func TestLocalProvider_ShouldLoadTheFlagsOnceForTheConcurrentEvaluations(t *testing.T) {
+	repo := &slowRepository{Repository: repository.NewMemoryRepository()}
+	test.Ok(repo.SaveFlag(context.Background(), model.FlagDAO{Name: "Dark", Enabled: true}), t)
+
+	t.Logf("Given the flags take a while to load from the repository")
+	{
+		provider := NewLocalProvider(repo, time.Hour)
+
+		t.Logf("\tWhen evaluating the flags 50 times concurrently")
+		{
+			var wg sync.WaitGroup
+			results := make([]Flags, 50)
+			for i := range results {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					results[i], _ = provider.FetchFeatureFlags(context.Background(), EvaluationContext{})
+				}(i)
+			}
+			wg.Wait()
+			for _, flags := range results {
+				expectFlags(flags, []string{"Dark"}, t)
+			}
+			if loads := atomic.LoadInt32(&repo.loads); loads == 1 {
+				t.Logf("\t\tThe flags should have been loaded once %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flags should have been loaded once: %d %v", loads, test.BallotX)
+			}
+		}
+	}
+}
+
+// slowRepository a repository counting the loads of the flags, each taking a while
+type slowRepository struct {
+	repository.Repository
+	loads int32
+}
+
+func (r *slowRepository) ForApp(app string) repository.Repository {
+	return r
+}
+
+func (r *slowRepository) FindFlags(ctx context.Context) ([]model.FlagDAO, error) {
+	atomic.AddInt32(&r.loads, 1)
+	time.Sleep(50 * time.Millisecond)
+	return r.Repository.FindFlags(ctx)
+}
+
+// unavailableRepository a repository failing to find the flags while unavailable
+type unavailableRepository struct {
+	repository.Repository
+	unavailable bool
+}
+
+func (r *unavailableRepository) ForApp(app string) repository.Repository {
+	return r
+}
+
+func (r *unavailableRepository) FindFlags(ctx context.Context) ([]model.FlagDAO, error) {
+	if r.unavailable {
+		return nil, errors.New("connection refused")
+	}
+	return r.Repository.FindFlags(ctx)
+}
@@ -0,0 +1,225 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	unleash "github.com/Unleash/unleash-client-go/v3/context"
+	"github.com/akhettar/app-features-manager/model"
+	"github.com/labstack/gommon/log"
+	"github.com/open-feature/go-sdk/openfeature"
+	"github.com/open-feature/go-sdk/openfeature/memprovider"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+
+	// OpenFeatureProviderName the name of the provider evaluating the flags through the OpenFeature Go SDK
+	OpenFeatureProviderName = "openfeature"
+
+	// SessionProperty the attribute of the OpenFeature context holding the identifier of the session of the customer
+	SessionProperty = "sessionId"
+
+	// RemoteAddressProperty the attribute of the OpenFeature context holding the address of the device
+	RemoteAddressProperty = "remoteAddress"
+)
+
+// OpenFeatureClient the boolean evaluation of the client of the OpenFeature Go SDK, implemented by
+// *openfeature.Client
+type OpenFeatureClient interface {
+	BooleanValueDetails(ctx context.Context, flag string, defaultValue bool, evalCtx openfeature.EvaluationContext,
+		options ...openfeature.Option) (openfeature.BooleanEvaluationDetails, error)
+}
+
+// OpenFeatureProvider the provider evaluating the flags through a client of the OpenFeature Go SDK. OpenFeature does
+// not list the flags, so the provider evaluates the given names, namespaced by the app identifier for the apps as in
+// unleash.
+type OpenFeatureProvider struct {
+	client OpenFeatureClient
+	names  []string
+
+	// Workers the number of flags of a request evaluated concurrently, DefaultWorkers when not positive
+	Workers int
+
+	// Timeout the deadline of the evaluation of the flags of a request, only bound by the deadline of the request
+	// when zero
+	Timeout time.Duration
+
+	// App the identifier of the app the flags are evaluated for, the default app when empty
+	App string
+}
+
+// NewOpenFeatureProvider creates a provider evaluating the given flags through the given OpenFeature client, the
+// flags of ListOfFlags when none are given
+func NewOpenFeatureProvider(client OpenFeatureClient, names []string) *OpenFeatureProvider {
+	return &OpenFeatureProvider{client: client, names: names}
+}
+
+// OpenFeatureFactory creates the OpenFeature provider the client of the SDK resolves the flags with, e.g. a flagd or
+// LaunchDarkly provider, from the configuration
+type OpenFeatureFactory func(config Config) (openfeature.FeatureProvider, error)
+
+var (
+	openFeatureMu        sync.RWMutex
+	openFeatureFactories = make(map[string]OpenFeatureFactory)
+)
+
+// RegisterOpenFeature makes an OpenFeature provider available under the given name, to be chosen through
+// OpenFeatureBackendKey. RegisterOpenFeature panics when called twice with the same name or with a nil factory, like
+// Register.
+func RegisterOpenFeature(name string, factory OpenFeatureFactory) {
+	openFeatureMu.Lock()
+	defer openFeatureMu.Unlock()
+	if factory == nil {
+		panic("features: RegisterOpenFeature factory is nil")
+	}
+	if _, dup := openFeatureFactories[name]; dup {
+		panic("features: RegisterOpenFeature called twice for provider " + name)
+	}
+	openFeatureFactories[name] = factory
+}
+
+// OpenFeatureBackends returns the names of the registered OpenFeature providers, sorted by name
+func OpenFeatureBackends() []string {
+	openFeatureMu.RLock()
+	defer openFeatureMu.RUnlock()
+	names := make([]string, 0, len(openFeatureFactories))
+	for name := range openFeatureFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register(OpenFeatureProviderName, NewOpenFeatureClient)
+	RegisterOpenFeature(FileProviderName, func(config Config) (openfeature.FeatureProvider, error) {
+		return NewOpenFeatureFileProvider(config.File)
+	})
+}
+
+// NewOpenFeatureClient creates a provider evaluating the flags of ListOfFlags through the client of the OpenFeature Go
+// SDK named after the app name, bound to the registered OpenFeature provider chosen by the configuration
+func NewOpenFeatureClient(config Config) (Provider, error) {
+	if err := config.validateOpenFeature(); err != nil {
+		return nil, err
+	}
+	openFeatureMu.RLock()
+	factory := openFeatureFactories[config.OpenFeatureBackend]
+	openFeatureMu.RUnlock()
+	backend, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OpenFeature provider %s: %w", config.OpenFeatureBackend, err)
+	}
+	if err := openfeature.SetNamedProvider(config.AppName, backend); err != nil {
+		return nil, fmt.Errorf("failed to set the OpenFeature provider %s: %w", config.OpenFeatureBackend, err)
+	}
+	ListOfFlags = os.Getenv(FeatureFlagList)
+	provider := NewOpenFeatureProvider(openfeature.NewClient(config.AppName), nil)
+	provider.Workers, provider.Timeout = config.Workers, config.Timeout
+	log.Infof("Initialised the OpenFeature client of %s against %s", config.AppName, backend.Metadata().Name)
+	return provider, nil
+}
+
+// NewOpenFeatureFileProvider creates an OpenFeature provider resolving the flags defined in the given file, read once
+// as by the file provider, through the in-memory provider of the SDK. The flags are enabled for the platforms and the
+// app versions of their definition, resolving to the name of their variant when they have one.
+func NewOpenFeatureFileProvider(path string) (memprovider.InMemoryProvider, error) {
+	definitions, err := readDefinitions(path)
+	if err != nil {
+		return memprovider.InMemoryProvider{}, err
+	}
+	flags := make(map[string]memprovider.InMemoryFlag, len(definitions))
+	for name, definition := range definitions {
+		flags[name] = openFeatureFlag(name, definition)
+	}
+	return memprovider.NewInMemoryProvider(flags), nil
+}
+
+// openFeatureFlag the in-memory OpenFeature flag resolving the given definition against the platform and the version
+// of the app in the context
+func openFeatureFlag(name string, definition Definition) memprovider.InMemoryFlag {
+	variant := ""
+	if definition.Variant != nil {
+		variant = definition.Variant.Name
+	}
+	params := map[string]interface{}{PlatformsParameter: strings.Join(definition.Platforms, CommaSeparator),
+		VersionRangeParameter: definition.VersionRange}
+	evaluator := func(flag memprovider.InMemoryFlag,
+		evalCtx openfeature.FlattenedContext) (interface{}, openfeature.ProviderResolutionDetail) {
+		ctx := unleash.Context{Properties: make(map[string]string)}
+		for _, property := range []string{PlatformProperty, VersionProperty} {
+			if value, ok := evalCtx[property].(string); ok {
+				ctx.Properties[property] = value
+			}
+		}
+		if definition.Enabled && (AppVersionStrategy{}).IsEnabled(params, &ctx) {
+			return true, openfeature.ProviderResolutionDetail{Reason: openfeature.TargetingMatchReason, Variant: variant}
+		}
+		return false, openfeature.ProviderResolutionDetail{Reason: openfeature.DefaultReason}
+	}
+	return memprovider.InMemoryFlag{Key: name, State: memprovider.Enabled, ContextEvaluator: &evaluator}
+}
+
+// ForApp returns a provider evaluating the flags of the given app
+func (p *OpenFeatureProvider) ForApp(app string) Provider {
+	return &OpenFeatureProvider{client: p.client, names: p.names, Workers: p.Workers, Timeout: p.Timeout, App: app}
+}
+
+// FetchFeatureFlags returns whether each flag of the app is enabled for the given context
+func (p *OpenFeatureProvider) FetchFeatureFlags(ctx context.Context, evaluation EvaluationContext) (Flags, error) {
+	toggles, err := p.FetchToggles(ctx, evaluation)
+	return toggles.Flags(), err
+}
+
+// FetchToggles returns the toggles of the app for the given context, with the variant the flags enabled resolved to.
+// The flags are resolved concurrently by the workers of the provider within its timeout, the flags failing to
+// resolve in time being reported in an EvaluationError.
+func (p *OpenFeatureProvider) FetchToggles(ctx context.Context, evaluation EvaluationContext) (Toggles, error) {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	evalCtx := evaluation.openFeatureContext(p.App)
+	names := p.names
+	if names == nil {
+		names = listedFlags()
+	}
+	return evaluate(ctx, p.Workers, names, func(ctx context.Context, flag string) (model.Toggle, error) {
+		details, err := p.client.BooleanValueDetails(ctx, appPrefix(p.App)+flag, false, evalCtx)
+		if err != nil {
+			log.Warnf("Failed to resolve the feature flag %s%s: %v", appPrefix(p.App), flag, err)
+			return model.Toggle{}, err
+		}
+		toggle := model.Toggle{Enabled: details.Value}
+		if toggle.Enabled && details.Variant != "" {
+			toggle.Variant = &model.Variant{Name: details.Variant}
+		}
+		return toggle, nil
+	})
+}
+
+// openFeatureContext the OpenFeature context evaluating the flags of the given app: the customer, or the session
+// of anonymous customers, targeted with the properties of the unleash context
+func (e EvaluationContext) openFeatureContext(app string) openfeature.EvaluationContext {
+	ctx := e.unleashContext(app)
+	attributes := make(map[string]interface{}, len(ctx.Properties)+2)
+	for name, value := range ctx.Properties {
+		attributes[name] = value
+	}
+	if e.SessionID != "" {
+		attributes[SessionProperty] = e.SessionID
+	}
+	if e.RemoteAddress != "" {
+		attributes[RemoteAddressProperty] = e.RemoteAddress
+	}
+	key := e.UserID
+	if key == "" {
+		key = e.SessionID
+	}
+	return openfeature.NewEvaluationContext(key, attributes)
+}
@@ -0,0 +1,102 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"github.com/akhettar/app-features-manager/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+
+	// DefaultWorkers the default number of flags of a request evaluated concurrently
+	DefaultWorkers = 16
+
+	// DefaultEvaluationTimeout the default deadline of the evaluation of the flags of a request
+	DefaultEvaluationTimeout = time.Second
+)
+
+// EvaluationError the errors of the flags which could not be evaluated, by name, the other flags being evaluated
+type EvaluationError map[string]error
+
+// Error lists the flags which could not be evaluated, sorted by name
+func (e EvaluationError) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	problems := make([]string, len(names))
+	for i, name := range names {
+		problems[i] = fmt.Sprintf("%s: %v", name, e[name])
+	}
+	return fmt.Sprintf("failed to evaluate %d flags: %s", len(e), strings.Join(problems, "; "))
+}
+
+// evaluation the result of the evaluation of a flag by a worker
+type evaluation struct {
+	flag   string
+	toggle model.Toggle
+	err    error
+}
+
+// evaluate evaluates the given flags with the given function, on at most the given number of workers, DefaultWorkers
+// when not positive, until the context is done. The flags which failed to evaluate, or were still being evaluated when
+// the context was done, are missing from the toggles and reported in an EvaluationError. The evaluations in progress
+// are not interrupted but their results are dropped.
+func evaluate(ctx context.Context, workers int, names []string,
+	fn func(ctx context.Context, flag string) (model.Toggle, error)) (Toggles, error) {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if workers > len(names) {
+		workers = len(names)
+	}
+
+	// Both channels are buffered to hold every flag, for the workers to never block on a request already served
+	jobs := make(chan string, len(names))
+	for _, flag := range names {
+		jobs <- flag
+	}
+	close(jobs)
+	results := make(chan evaluation, len(names))
+	for i := 0; i < workers; i++ {
+		go func() {
+			for flag := range jobs {
+				if err := ctx.Err(); err != nil {
+					results <- evaluation{flag: flag, err: err}
+					continue
+				}
+				toggle, err := fn(ctx, flag)
+				results <- evaluation{flag: flag, toggle: toggle, err: err}
+			}
+		}()
+	}
+
+	toggles := make(Toggles, len(names))
+	failures := make(EvaluationError)
+collect:
+	for received := 0; received < len(names); received++ {
+		select {
+		case result := <-results:
+			if result.err != nil {
+				failures[result.flag] = result.err
+			} else {
+				toggles[result.flag] = result.toggle
+			}
+		case <-ctx.Done():
+			for _, flag := range names {
+				if _, ok := toggles[flag]; !ok && failures[flag] == nil {
+					failures[flag] = ctx.Err()
+				}
+			}
+			break collect
+		}
+	}
+	if len(failures) > 0 {
+		return toggles, failures
+	}
+	return toggles, nil
+}
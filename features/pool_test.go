@@ -0,0 +1,168 @@
+package features_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "github.com/akhettar/app-features-manager/features"
+	"github.com/akhettar/app-features-manager/test"
+	"github.com/open-feature/go-sdk/openfeature"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestEvaluation_ShouldReturnTheFlagsEvaluatedBeforeTheDeadline checks the flags stalling past the deadline of the
+// request are reported while the other flags are returned
+func TestEvaluation_ShouldReturnTheFlagsEvaluatedBeforeTheDeadline(t *testing.T) {
+	stalled := make(chan struct{})
+	defer close(stalled)
+	client := openFeatureClient(func(flag string, evalCtx openfeature.EvaluationContext) (openfeature.BooleanEvaluationDetails, error) {
+		if flag == "Slow" {
+			<-stalled
+		}
+		return booleanDetails(true, ""), nil
+	})
+
+	t.Logf("Given a flag whose evaluation stalls")
+	{
+		provider := NewOpenFeatureProvider(client, []string{"Dark", "Slow", "Chat"})
+
+		t.Logf("\tWhen fetching the flags within 50ms")
+		{
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			flags, err := provider.FetchFeatureFlags(ctx, EvaluationContext{})
+			if time.Since(start) < time.Second {
+				t.Logf("\t\tThe flags should be returned by the deadline %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flags should be returned by the deadline: %v %v", time.Since(start), test.BallotX)
+			}
+			expectFlags(flags, []string{"Chat", "Dark"}, t)
+			var failures EvaluationError
+			if errors.As(err, &failures) && len(failures) == 1 && errors.Is(failures["Slow"], context.DeadlineExceeded) {
+				t.Logf("\t\tThe stalled flag should be reported %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe stalled flag should be reported %v %v", err, test.BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given a flag whose evaluation stalls past the timeout of the provider")
+	{
+		provider := NewOpenFeatureProvider(client, []string{"Dark", "Slow"})
+		provider.Timeout = 50 * time.Millisecond
+
+		t.Logf("\tWhen fetching the flags")
+		{
+			flags, err := provider.ForApp("").FetchFeatureFlags(context.Background(), EvaluationContext{})
+			expectFlags(flags, []string{"Dark"}, t)
+			var failures EvaluationError
+			if errors.As(err, &failures) && len(failures) == 1 && errors.Is(failures["Slow"], context.DeadlineExceeded) {
+				t.Logf("\t\tThe stalled flag should be reported %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe stalled flag should be reported %v %v", err, test.BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given the request is cancelled")
+	{
+		ListOfFlags = "Dark, Chat"
+		client := UnleashClient{Timeout: time.Second}
+
+		t.Logf("\tWhen fetching the flags")
+		{
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			flags, err := client.FetchFeatureFlags(ctx, EvaluationContext{})
+			if len(flags) == 0 && err != nil && strings.Contains(err.Error(), "failed to evaluate 2 flags") {
+				t.Logf("\t\tNo flag should be evaluated %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tNo flag should be evaluated %v %v %v", flags, err, test.BallotX)
+			}
+		}
+	}
+}
+
+// TestEvaluation_ShouldBoundTheConcurrentEvaluations checks no more than DefaultWorkers flags of a request are
+// evaluated at once
+func TestEvaluation_ShouldBoundTheConcurrentEvaluations(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	client := openFeatureClient(func(flag string, evalCtx openfeature.EvaluationContext) (openfeature.BooleanEvaluationDetails, error) {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return booleanDetails(true, ""), nil
+	})
+
+	t.Logf("Given an app holding 200 flags")
+	{
+		provider := NewOpenFeatureProvider(client, flagNames(200))
+
+		t.Logf("\tWhen fetching the flags")
+		{
+			flags, err := provider.FetchFeatureFlags(context.Background(), EvaluationContext{})
+			test.Ok(err, t)
+			if len(flags) == 200 && peak <= DefaultWorkers {
+				t.Logf("\t\tAt most %d flags should be evaluated at once %v", DefaultWorkers, test.CheckMark)
+			} else {
+				t.Errorf("\t\tAt most %d flags should be evaluated at once: %d %v", DefaultWorkers, peak, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen fetching the flags with 4 workers")
+		{
+			peak = 0
+			provider.Workers = 4
+			flags, err := provider.FetchFeatureFlags(context.Background(), EvaluationContext{})
+			test.Ok(err, t)
+			if len(flags) == 200 && peak <= 4 {
+				t.Logf("\t\tAt most 4 flags should be evaluated at once %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tAt most 4 flags should be evaluated at once: %d %v", peak, test.BallotX)
+			}
+		}
+	}
+}
+
+// BenchmarkUnleashClient_FetchFeatureFlags measures the evaluation of hundreds of flags by concurrent requests
+func BenchmarkUnleashClient_FetchFeatureFlags(b *testing.B) {
+	for _, count := range []int{100, 500} {
+		for _, workers := range []int{1, DefaultWorkers, 64} {
+			b.Run(fmt.Sprintf("flags=%d/workers=%d", count, workers), func(b *testing.B) {
+				ListOfFlags = strings.Join(flagNames(count), CommaSeparator)
+				client := UnleashClient{Workers: workers, Timeout: DefaultEvaluationTimeout}
+				evaluation := EvaluationContext{UserID: "customer", Platform: "android", Version: "5.1"}
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						if _, err := client.FetchFeatureFlags(context.Background(), evaluation); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+// flagNames returns the given number of flag names
+func flagNames(count int) []string {
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("Flag%d", i)
+	}
+	return names
+}
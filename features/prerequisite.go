@@ -0,0 +1,217 @@
+package features
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/akhettar/app-features-manager/model"
+	"github.com/akhettar/app-features-manager/repository"
+	"sort"
+	"strings"
+)
+
+const (
+
+	// FeatureFlagPrerequisites environment variable declaring the flags other flags require, separated by
+	// semicolons, each flag listing its prerequisites comma separated, e.g.
+	// "MIF_LIMITED_COMPANY=MIF; com.acme.banking.Pay=Wallet,KYC". The prerequisites of the flags of an app are flags
+	// of the same app and are named without the app prefix.
+	FeatureFlagPrerequisites = "FEATURE_FLAG_PREREQUISITES"
+
+	// PrerequisiteSeparator separator of the flags declaring prerequisites
+	PrerequisiteSeparator = ";"
+)
+
+// Prerequisites the flags each flag requires to be enabled, by name, the flags of an app being namespaced by its
+// identifier both as the flag and as its prerequisites, e.g. "com.acme.banking.Pay" requiring
+// "com.acme.banking.Wallet"
+type Prerequisites map[string][]string
+
+// ParsePrerequisites parses the prerequisites declared in the format of FeatureFlagPrerequisites
+func ParsePrerequisites(value string) (Prerequisites, error) {
+	prerequisites := make(Prerequisites)
+	var problems []string
+	for _, declaration := range strings.Split(value, PrerequisiteSeparator) {
+		if strings.TrimSpace(declaration) == "" {
+			continue
+		}
+		i := strings.Index(declaration, "=")
+		if i <= 0 || strings.TrimSpace(declaration[i+1:]) == "" {
+			problems = append(problems, fmt.Sprintf("%s holds an invalid declaration: %q", FeatureFlagPrerequisites,
+				declaration))
+			continue
+		}
+		flag := strings.TrimSpace(declaration[:i])
+		for _, parent := range strings.Split(declaration[i+1:], CommaSeparator) {
+			if parent = strings.TrimSpace(parent); parent != "" {
+				prerequisites[flag] = append(prerequisites[flag], namespace(flag)+parent)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return prerequisites, errors.New(strings.Join(problems, "; "))
+	}
+	return prerequisites, nil
+}
+
+// Validate checks the prerequisites of each flag are flags of the same app and that no flag requires itself, even
+// through other flags, every problem being reported in the error
+func (p Prerequisites) Validate() error {
+	var problems []string
+	for _, flag := range p.flags() {
+		for _, parent := range p[flag] {
+			if namespace(parent) != namespace(flag) {
+				problems = append(problems, fmt.Sprintf("the flag %s requires %s of another app", flag, parent))
+			}
+		}
+	}
+
+	// Walk the flags depth first, a flag met again while its prerequisites are being walked closing a cycle
+	const (
+		walking = iota + 1
+		walked
+	)
+	state := make(map[string]int)
+	var path []string
+	var walk func(flag string)
+	walk = func(flag string) {
+		switch state[flag] {
+		case walking:
+			for i := range path {
+				if path[i] == flag {
+					cycle := append(append([]string(nil), path[i:]...), flag)
+					problems = append(problems, fmt.Sprintf("the prerequisites form a cycle: %s",
+						strings.Join(cycle, " -> ")))
+				}
+			}
+			return
+		case walked:
+			return
+		}
+		state[flag] = walking
+		path = append(path, flag)
+		for _, parent := range p[flag] {
+			walk(parent)
+		}
+		path = path[:len(path)-1]
+		state[flag] = walked
+	}
+	for _, flag := range p.flags() {
+		walk(flag)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid prerequisites: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// flags the flags declaring prerequisites, sorted by name
+func (p Prerequisites) flags() []string {
+	flags := make([]string, 0, len(p))
+	for flag := range p {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// namespace the prefix namespacing the given flag by the identifier of its app, empty for the default app, flag names
+// holding no AppFlagSeparator
+func namespace(flag string) string {
+	return flag[:strings.LastIndex(flag, AppFlagSeparator)+1]
+}
+
+// PrerequisiteProvider the provider forcing off the flags of the given provider whose prerequisites are not all
+// enabled, the prerequisites being themselves forced off when theirs are not
+type PrerequisiteProvider struct {
+	Provider
+	prerequisites Prerequisites
+
+	// App the identifier of the app the flags are evaluated for, the default app when empty
+	App string
+}
+
+// NewPrerequisiteProvider creates a provider enforcing the given prerequisites on the flags of the given provider
+func NewPrerequisiteProvider(provider Provider, prerequisites Prerequisites) (*PrerequisiteProvider, error) {
+	if err := prerequisites.Validate(); err != nil {
+		return nil, err
+	}
+	return &PrerequisiteProvider{Provider: provider, prerequisites: prerequisites}, nil
+}
+
+// ForApp returns a provider enforcing the prerequisites on the flags of the given app
+func (p *PrerequisiteProvider) ForApp(app string) Provider {
+	return &PrerequisiteProvider{Provider: p.Provider.ForApp(app), prerequisites: p.prerequisites, App: app}
+}
+
+// FetchFeatureFlags returns whether each flag of the app is enabled for the given context, the flags whose
+// prerequisites are not all enabled being disabled
+func (p *PrerequisiteProvider) FetchFeatureFlags(ctx context.Context, evaluation EvaluationContext) (Flags, error) {
+	flags, err := p.Provider.FetchFeatureFlags(ctx, evaluation)
+	for flag := range p.resolve(flags) {
+		flags[flag] = false
+	}
+	return flags, err
+}
+
+// FetchToggles returns the toggles of the app for the given context, the flags whose prerequisites are not all enabled
+// being disabled along with the reason
+func (p *PrerequisiteProvider) FetchToggles(ctx context.Context, evaluation EvaluationContext) (Toggles, error) {
+	toggles, err := p.Provider.FetchToggles(ctx, evaluation)
+	for flag, reason := range p.resolve(toggles.Flags()) {
+		toggles[flag] = model.Toggle{Reason: reason}
+	}
+	return toggles, err
+}
+
+// Stale tells whether the flags of the given provider are stale, when it tells
+func (p *PrerequisiteProvider) Stale() bool {
+	provider, ok := p.Provider.(interface{ Stale() bool })
+	return ok && provider.Stale()
+}
+
+// Invalidate drops the flag definitions loaded by the given provider, when it loads any
+func (p *PrerequisiteProvider) Invalidate() {
+	if provider, ok := p.Provider.(interface{ Invalidate() }); ok {
+		provider.Invalidate()
+	}
+}
+
+// Refresh expires the flag definitions of the given provider the given change may have changed, when it loads any
+func (p *PrerequisiteProvider) Refresh(event repository.ChangeEvent) {
+	if provider, ok := p.Provider.(interface{ Refresh(repository.ChangeEvent) }); ok {
+		provider.Refresh(event)
+	}
+}
+
+// resolve returns the reason each enabled flag of the app is forced off, by name: a prerequisite missing from the
+// given flags, i.e. unknown or not evaluated, or disabled, itself possibly forced off
+func (p *PrerequisiteProvider) resolve(flags Flags) map[string]string {
+	prefix := appPrefix(p.App)
+	reasons := make(map[string]string)
+	resolved := make(map[string]bool, len(flags))
+	var enabled func(flag string) bool
+	enabled = func(flag string) bool {
+		if on, ok := resolved[flag]; ok {
+			return on
+		}
+		on := flags[flag]
+		for _, parent := range p.prerequisites[prefix+flag] {
+			if !on {
+				break
+			}
+			parent = strings.TrimPrefix(parent, prefix)
+			if _, ok := flags[parent]; !ok {
+				on, reasons[flag] = false, fmt.Sprintf("the prerequisite %s is unknown or was not evaluated", parent)
+			} else if !enabled(parent) {
+				on, reasons[flag] = false, fmt.Sprintf("the prerequisite %s is disabled", parent)
+			}
+		}
+		resolved[flag] = on
+		return on
+	}
+	for flag := range flags {
+		enabled(flag)
+	}
+	return reasons
+}
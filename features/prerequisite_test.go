@@ -0,0 +1,103 @@
+package features_test
+
+import (
+	"context"
+	. "github.com/akhettar/app-features-manager/features"
+	"github.com/akhettar/app-features-manager/test"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestPrerequisites_ShouldRejectTheCycles checks the prerequisites are parsed relative to the app of each flag, and
+// rejected when a flag requires itself or a flag of another app
+func TestPrerequisites_ShouldRejectTheCycles(t *testing.T) {
+	t.Logf("Given the prerequisites declared in the environment")
+	{
+		t.Logf("\tWhen parsing the prerequisites")
+		{
+			prerequisites, err := ParsePrerequisites("MIF_LIMITED_COMPANY=MIF; com.acme.banking.Pay = Wallet, KYC")
+			test.Ok(err, t)
+			expected := Prerequisites{"MIF_LIMITED_COMPANY": {"MIF"},
+				"com.acme.banking.Pay": {"com.acme.banking.Wallet", "com.acme.banking.KYC"}}
+			if reflect.DeepEqual(prerequisites, expected) {
+				t.Logf("\t\tThe prerequisites should be namespaced by the app of the flag %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe prerequisites should be namespaced by the app of the flag %v %v", prerequisites,
+					test.BallotX)
+			}
+			test.Ok(prerequisites.Validate(), t)
+		}
+
+		t.Logf("\tWhen the prerequisites form a cycle or cross the apps")
+		{
+			err := Prerequisites{"A": {"B"}, "B": {"C"}, "C": {"A"}, "D": {"D"},
+				"com.acme.banking.Pay": {"Wallet"}}.Validate()
+			for _, problem := range []string{"A -> B -> C -> A", "D -> D", "com.acme.banking.Pay requires Wallet"} {
+				if err != nil && strings.Contains(err.Error(), problem) {
+					t.Logf("\t\tThe error should report %q %v", problem, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe error should report %q %v %v", problem, err, test.BallotX)
+				}
+			}
+			if _, err := NewProvider(Config{Provider: EnvProviderName, Prerequisites: Prerequisites{"A": {"A"}}}); err != nil {
+				t.Logf("\t\tThe provider should not be created %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe provider should not be created %v", test.BallotX)
+			}
+		}
+	}
+}
+
+// TestPrerequisiteProvider_ShouldForceOffTheFlagsRequiringDisabledFlags checks a flag is disabled when one of its
+// prerequisites is, directly or through other flags, along with the reason
+func TestPrerequisiteProvider_ShouldForceOffTheFlagsRequiringDisabledFlags(t *testing.T) {
+	static := NewStaticProvider(map[string]Definition{"MIF": {Enabled: false}, "MIF_LIMITED_COMPANY": {Enabled: true},
+		"MIF_INVOICES": {Enabled: true}, "BANK_AGGREGATION": {Enabled: true}, "com.acme.banking.Wallet": {Enabled: true},
+		"com.acme.banking.Pay": {Enabled: true}, "com.acme.banking.Loans": {Enabled: true}})
+	provider, err := NewPrerequisiteProvider(static, Prerequisites{"MIF_LIMITED_COMPANY": {"MIF"},
+		"MIF_INVOICES": {"MIF_LIMITED_COMPANY"}, "com.acme.banking.Pay": {"com.acme.banking.Wallet"},
+		"com.acme.banking.Loans": {"com.acme.banking.KYC"}})
+	test.Ok(err, t)
+
+	t.Logf("Given MIF_LIMITED_COMPANY requires MIF, disabled, and MIF_INVOICES requires MIF_LIMITED_COMPANY")
+	{
+		t.Logf("\tWhen fetching the flags")
+		{
+			flags := fetchFlags(provider, EvaluationContext{}, t)
+			expected := Flags{"MIF": false, "MIF_LIMITED_COMPANY": false, "MIF_INVOICES": false, "BANK_AGGREGATION": true}
+			if reflect.DeepEqual(flags, expected) {
+				t.Logf("\t\tThe flags requiring MIF should be disabled %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flags requiring MIF should be disabled %v %v", flags, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen fetching the toggles")
+		{
+			reasons := fetchToggles(provider, EvaluationContext{}, t).Reasons()
+			expected := map[string]string{"MIF_LIMITED_COMPANY": "the prerequisite MIF is disabled",
+				"MIF_INVOICES": "the prerequisite MIF_LIMITED_COMPANY is disabled"}
+			if reflect.DeepEqual(reasons, expected) {
+				t.Logf("\t\tThe reasons the flags were disabled should be given %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe reasons the flags were disabled should be given %v %v", reasons, test.BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given the flags of an app requiring a flag enabled and an unknown flag")
+	{
+		t.Logf("\tWhen fetching the toggles of the app")
+		{
+			toggles, err := provider.ForApp("com.acme.banking").FetchToggles(context.Background(), EvaluationContext{})
+			test.Ok(err, t)
+			if toggles["Pay"].Enabled && !toggles["Loans"].Enabled &&
+				toggles["Loans"].Reason == "the prerequisite KYC is unknown or was not evaluated" {
+				t.Logf("\t\tOnly the flag requiring the unknown flag should be disabled %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tOnly the flag requiring the unknown flag should be disabled %+v %v", toggles, test.BallotX)
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+
+	// FeatureProvider environment variable choosing the provider of the feature flags, UnleashProviderName by default
+	FeatureProvider = "FEATURE_PROVIDER"
+
+	// UnleashProviderName the name of the provider evaluating the flags with an unleash server
+	UnleashProviderName = "unleash"
+
+	// FileProviderName the name of the provider reading the flags from a static YAML or JSON file
+	FileProviderName = "file"
+
+	// EnvProviderName the name of the provider reading the flags from the environment variables
+	EnvProviderName = "env"
+)
+
+// Provider the provider of the feature flags of the apps
+type Provider interface {
+
+	// FetchFeatureFlags returns whether each flag of the app is enabled for the given evaluation context, within the
+	// deadline of the given context. The flags which could not be evaluated are missing from the flags and reported
+	// in an EvaluationError.
+	FetchFeatureFlags(ctx context.Context, evaluation EvaluationContext) (Flags, error)
+
+	// FetchToggles returns the toggles of the app for the given evaluation context, with the variants of the
+	// customer, within the deadline of the given context. The flags which could not be evaluated are missing from the
+	// toggles and reported in an EvaluationError.
+	FetchToggles(ctx context.Context, evaluation EvaluationContext) (Toggles, error)
+
+	// ForApp returns the provider evaluating the flags of the given app, the default app when empty
+	ForApp(app string) Provider
+}
+
+// Factory creates a provider from the configuration
+type Factory func(config Config) (Provider, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+
+	appsMu sync.RWMutex
+	apps   = make(map[string]bool)
+)
+
+// Register makes a provider available under the given name, to be chosen through FeatureProvider. Register panics
+// when called twice with the same name or with a nil factory, like the drivers of database/sql.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if factory == nil {
+		panic("features: Register factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic("features: Register called twice for provider " + name)
+	}
+	factories[name] = factory
+}
+
+// Providers returns the names of the registered providers, sorted by name
+func Providers() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProvider creates the provider chosen by the configuration, once validated, enforcing the prerequisites of the
+// flags when any are declared
+func NewProvider(config Config) (Provider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid feature flag configuration: %w", err)
+	}
+	factoriesMu.RLock()
+	factory := factories[config.provider()]
+	factoriesMu.RUnlock()
+	RegisterApps(config.Apps...)
+	provider, err := factory(config)
+	if err != nil || len(config.Prerequisites) == 0 {
+		return provider, err
+	}
+	return NewPrerequisiteProvider(provider, config.Prerequisites)
+}
+
+// RegisterApps registers the identifiers of the apps namespacing their flags, e.g. from FeatureFlagApps at start up.
+// The flags of the default app are the ones namespaced by none of the apps registered.
+func RegisterApps(ids ...string) {
+	appsMu.Lock()
+	defer appsMu.Unlock()
+	for _, id := range ids {
+		if id != "" {
+			apps[id] = true
+		}
+	}
+}
+
+// appOf returns the identifier of the app namespacing the given flag among the registered apps and the given app, the
+// longest one when several do, and empty for the flags of the default app, e.g. "checkout.v2" when no app "checkout"
+// is registered
+func appOf(name, app string) string {
+	appsMu.RLock()
+	defer appsMu.RUnlock()
+	for i := strings.LastIndex(name, AppFlagSeparator); i > 0; i = strings.LastIndex(name[:i], AppFlagSeparator) {
+		if apps[name[:i]] || name[:i] == app {
+			return name[:i]
+		}
+	}
+	return ""
+}
+
+// appFlags returns the names of the flags of the given app among the given names, without the app prefix. The flags
+// of an app are namespaced by its identifier, e.g. "com.acme.banking.ITFeature", and the flags of the default app are
+// the ones not namespaced by any registered app, so their names may hold an AppFlagSeparator, e.g. "checkout.v2". The
+// app requested is not registered, for the requests of any app to leave the flags of the default app unchanged.
+func appFlags(names []string, app string) []string {
+	var flags []string
+	for _, name := range names {
+		if appOf(name, app) == app {
+			flags = append(flags, strings.TrimPrefix(name, appPrefix(app)))
+		}
+	}
+	return flags
+}
+
+// appPrefix the prefix namespacing the flags of the given app, empty for the default app
+func appPrefix(app string) string {
+	if app == "" {
+		return ""
+	}
+	return app + AppFlagSeparator
+}
@@ -0,0 +1,274 @@
+package features_test
+
+import (
+	"context"
+	"errors"
+	. "github.com/akhettar/app-features-manager/features"
+	"github.com/akhettar/app-features-manager/test"
+	"github.com/open-feature/go-sdk/openfeature"
+	"github.com/open-feature/go-sdk/openfeature/memprovider"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const flagsYAML = `
+Dark:
+  enabled: true
+Chat:
+  enabled: true
+  platforms: [android]
+  versionRange: ">=5.0"
+  variant:
+    name: blue
+    payload: {type: string, value: "#00f"}
+com.acme.banking.Pay:
+  enabled: true
+`
+
+// TestFileProvider_ShouldEvaluateTheFlagsOfTheFile checks the flags defined in a YAML file are evaluated against the
+// platform and the version of the app
+func TestFileProvider_ShouldEvaluateTheFlagsOfTheFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flags")
+	test.Ok(err, t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "flags.yaml")
+	test.Ok(ioutil.WriteFile(path, []byte(flagsYAML), 0600), t)
+
+	t.Logf("Given the file provider reads the flags from a YAML file")
+	{
+		provider, err := NewProvider(Config{Provider: FileProviderName, File: path})
+		test.Ok(err, t)
+
+		t.Logf("\tWhen fetching the toggles on android 5.1")
+		{
+			toggles := fetchToggles(provider, EvaluationContext{Platform: "android", Version: "5.1"}, t)
+			expectFlags(toggles.Flags(), []string{"Chat", "Dark"}, t)
+			if toggles["Chat"].Enabled && toggles["Chat"].Variant != nil && toggles["Chat"].Variant.Name == "blue" &&
+				toggles["Chat"].Variant.Payload.Value == "#00f" {
+				t.Logf("\t\tThe variant of the flag should have been returned %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe variant of the flag should have been returned %+v %v", toggles["Chat"], test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen fetching the flags on ios")
+		{
+			flags := fetchFlags(provider, EvaluationContext{Platform: "ios", Version: "5.1"}, t)
+			if flags["Dark"] && !flags["Chat"] {
+				t.Logf("\t\tThe flag restricted to android should be disabled %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flag restricted to android should be disabled %v %v", flags, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen fetching the flags of an app")
+		{
+			expectFlags(fetchFlags(provider.ForApp("com.acme.banking"), EvaluationContext{}, t), []string{"Pay"}, t)
+		}
+	}
+}
+
+// TestEnvProvider_ShouldReadTheFlagsFromTheEnvironment checks the flags are read from the prefixed variables
+func TestEnvProvider_ShouldReadTheFlagsFromTheEnvironment(t *testing.T) {
+	t.Logf("Given the flags are defined by the environment")
+	{
+		t.Logf("\tWhen reading the flags")
+		{
+			provider, err := NewEnvProvider([]string{"HOME=/root", EnvFlagPrefix + "Dark=true",
+				EnvFlagPrefix + "Chat=false", EnvFlagPrefix + "com.acme.banking.Pay=1"})
+			test.Ok(err, t)
+			flags := fetchFlags(provider, EvaluationContext{}, t)
+			if len(flags) == 2 && flags["Dark"] && !flags["Chat"] {
+				t.Logf("\t\tThe flags should have been read %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flags should have been read %v %v", flags, test.BallotX)
+			}
+			if fetchFlags(provider.ForApp("com.acme.banking"), EvaluationContext{}, t)["Pay"] {
+				t.Logf("\t\tThe flag of the app should have been read %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flag of the app should have been read %v", test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen a flag is not a boolean")
+		{
+			_, err := NewEnvProvider([]string{EnvFlagPrefix + "Dark=yes please"})
+			if err != nil && strings.Contains(err.Error(), EnvFlagPrefix+"Dark") {
+				t.Logf("\t\tThe flag should have been rejected %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flag should have been rejected %v %v", err, test.BallotX)
+			}
+		}
+	}
+}
+
+// TestNewProvider_ShouldCreateTheRegisteredProviders checks the providers are chosen by name among the registered ones
+func TestNewProvider_ShouldCreateTheRegisteredProviders(t *testing.T) {
+	Register("static", func(config Config) (Provider, error) {
+		return NewStaticProvider(map[string]Definition{"Dark": {Enabled: true}}), nil
+	})
+
+	t.Logf("Given a provider registered under the name static")
+	{
+		t.Logf("\tWhen choosing the static provider")
+		{
+			provider, err := NewProvider(Config{Provider: "static"})
+			test.Ok(err, t)
+			expectFlags(fetchFlags(provider, EvaluationContext{}, t), []string{"Dark"}, t)
+		}
+
+		t.Logf("\tWhen choosing an unknown provider")
+		{
+			_, err := NewProvider(Config{Provider: "launchdarkly"})
+			if err != nil && strings.Contains(err.Error(), "static") && strings.Contains(err.Error(), UnleashProviderName) {
+				t.Logf("\t\tThe error should list the registered providers %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe error should list the registered providers %v %v", err, test.BallotX)
+			}
+		}
+	}
+}
+
+// TestOpenFeatureProvider_ShouldResolveTheFlagsThroughTheClient checks the flags are resolved by the OpenFeature client
+// against the customer
+func TestOpenFeatureProvider_ShouldResolveTheFlagsThroughTheClient(t *testing.T) {
+	client := openFeatureClient(func(flag string, evalCtx openfeature.EvaluationContext) (openfeature.BooleanEvaluationDetails, error) {
+		switch {
+		case flag == "com.acme.banking.Dark" && evalCtx.TargetingKey() == "customer":
+			return booleanDetails(true, "on"), nil
+		case flag == "com.acme.banking.Chat":
+			return booleanDetails(true, ""), errors.New("flag not found")
+		}
+		return booleanDetails(false, ""), nil
+	})
+
+	t.Logf("Given the flags of an app resolved by an OpenFeature client")
+	{
+		provider := NewOpenFeatureProvider(client, []string{"Dark", "Chat"}).ForApp("com.acme.banking")
+
+		t.Logf("\tWhen fetching the toggles of the customer")
+		{
+			toggles, err := provider.FetchToggles(context.Background(), EvaluationContext{UserID: "customer"})
+			if toggles["Dark"].Enabled && toggles["Dark"].Variant.Name == "on" && !toggles["Chat"].Enabled {
+				t.Logf("\t\tThe flags should have been resolved %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flags should have been resolved %+v %v", toggles, test.BallotX)
+			}
+			var failures EvaluationError
+			if errors.As(err, &failures) && len(failures) == 1 && failures["Chat"] != nil {
+				t.Logf("\t\tThe flag failing to resolve should be reported %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flag failing to resolve should be reported %v %v", err, test.BallotX)
+			}
+		}
+	}
+}
+
+// TestNewProvider_ShouldResolveTheFlagsThroughTheOpenFeatureSDK checks the openfeature provider resolves the listed
+// flags with the registered OpenFeature provider chosen by the configuration, and refuses to be created without one
+func TestNewProvider_ShouldResolveTheFlagsThroughTheOpenFeatureSDK(t *testing.T) {
+	flag := func(key string, enabled bool) memprovider.InMemoryFlag {
+		return memprovider.InMemoryFlag{Key: key, State: memprovider.Enabled, DefaultVariant: "blue",
+			Variants: map[string]interface{}{"blue": enabled}}
+	}
+	RegisterOpenFeature("memory", func(config Config) (openfeature.FeatureProvider, error) {
+		return memprovider.NewInMemoryProvider(map[string]memprovider.InMemoryFlag{"Dark": flag("Dark", true),
+			"Chat": flag("Chat", false), "com.acme.banking.Pay": flag("com.acme.banking.Pay", true)}), nil
+	})
+	os.Setenv(FeatureFlagList, "Dark, Chat, Pay")
+	defer os.Unsetenv(FeatureFlagList)
+
+	t.Logf("Given the flags of an in-memory OpenFeature provider registered under the name memory")
+	{
+		t.Logf("\tWhen no OpenFeature provider is chosen")
+		{
+			_, err := NewProvider(Config{Provider: OpenFeatureProviderName, AppName: "openfeature-test"})
+			if err != nil && strings.Contains(err.Error(), "memory") && strings.Contains(err.Error(), OpenFeatureBackendKey) {
+				t.Logf("\t\tThe error should list the registered OpenFeature providers %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe error should list the registered OpenFeature providers %v %v", err, test.BallotX)
+			}
+		}
+
+		provider, err := NewProvider(Config{Provider: OpenFeatureProviderName, OpenFeatureBackend: "memory",
+			AppName: "openfeature-test"})
+		test.Ok(err, t)
+
+		t.Logf("\tWhen fetching the toggles of each app")
+		{
+			toggles, err := provider.FetchToggles(context.Background(), EvaluationContext{UserID: "customer"})
+			expectFlags(toggles.Flags(), []string{"Chat", "Dark"}, t)
+			if toggles["Dark"].Enabled && toggles["Dark"].Variant != nil && toggles["Dark"].Variant.Name == "blue" &&
+				!toggles["Chat"].Enabled {
+				t.Logf("\t\tThe flags should have been resolved with their variant %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flags should have been resolved with their variant %+v %v", toggles, test.BallotX)
+			}
+			var failures EvaluationError
+			if errors.As(err, &failures) && len(failures) == 1 && failures["Pay"] != nil {
+				t.Logf("\t\tThe flag unknown to the default app should be reported %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flag unknown to the default app should be reported %v %v", err, test.BallotX)
+			}
+			flags, _ := provider.ForApp("com.acme.banking").FetchFeatureFlags(context.Background(), EvaluationContext{})
+			expectFlags(flags, []string{"Pay"}, t)
+		}
+	}
+}
+
+// TestNewProvider_ShouldResolveTheFlagsOfTheFileThroughOpenFeature checks the flags defined in a YAML file are
+// resolved through the OpenFeature SDK against the platform and the version of the app
+func TestNewProvider_ShouldResolveTheFlagsOfTheFileThroughOpenFeature(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flags")
+	test.Ok(err, t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "flags.yaml")
+	test.Ok(ioutil.WriteFile(path, []byte(flagsYAML), 0600), t)
+	os.Setenv(FeatureFlagList, "Dark, Chat")
+	defer os.Unsetenv(FeatureFlagList)
+
+	t.Logf("Given the openfeature provider resolves the flags of a YAML file with the file OpenFeature provider")
+	{
+		provider, err := NewProvider(Config{Provider: OpenFeatureProviderName, OpenFeatureBackend: FileProviderName,
+			File: path, AppName: "openfeature-file-test"})
+		test.Ok(err, t)
+
+		t.Logf("\tWhen fetching the toggles on android 5.1")
+		{
+			toggles := fetchToggles(provider, EvaluationContext{Platform: "android", Version: "5.1"}, t)
+			if toggles["Dark"].Enabled && toggles["Dark"].Variant == nil && toggles["Chat"].Enabled &&
+				toggles["Chat"].Variant != nil && toggles["Chat"].Variant.Name == "blue" {
+				t.Logf("\t\tThe flags enabled should have been resolved to true %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flags enabled should have been resolved to true %+v %v", toggles, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen fetching the flags on ios")
+		{
+			flags := fetchFlags(provider, EvaluationContext{Platform: "ios", Version: "5.1"}, t)
+			if flags["Dark"] && !flags["Chat"] {
+				t.Logf("\t\tThe flag of android should be disabled %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flag of android should be disabled %v %v", flags, test.BallotX)
+			}
+		}
+	}
+}
+
+// openFeatureClient an OpenFeature client resolving the flags with a function
+type openFeatureClient func(flag string, evalCtx openfeature.EvaluationContext) (openfeature.BooleanEvaluationDetails, error)
+
+func (f openFeatureClient) BooleanValueDetails(ctx context.Context, flag string, defaultValue bool,
+	evalCtx openfeature.EvaluationContext, options ...openfeature.Option) (openfeature.BooleanEvaluationDetails, error) {
+	return f(flag, evalCtx)
+}
+
+// booleanDetails the resolution of a boolean flag to the given value and variant
+func booleanDetails(value bool, variant string) openfeature.BooleanEvaluationDetails {
+	return openfeature.BooleanEvaluationDetails{Value: value,
+		EvaluationDetails: openfeature.EvaluationDetails{ResolutionDetail: openfeature.ResolutionDetail{Variant: variant}}}
+}
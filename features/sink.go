@@ -0,0 +1,194 @@
+package features
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+
+	// ImpressionSink environment variable choosing the sink of the impressions: LogSinkName, FileSinkName or
+	// WebhookSinkName, the impressions being only counted when empty
+	ImpressionSink = "IMPRESSION_SINK"
+
+	// ImpressionFile environment variable setting the file the file sink appends the impressions to
+	ImpressionFile = "IMPRESSION_FILE"
+
+	// ImpressionWebhookURL environment variable setting the URL the webhook sink posts the impressions to
+	ImpressionWebhookURL = "IMPRESSION_WEBHOOK_URL"
+
+	// ImpressionSampleRate environment variable setting the share of the evaluations sent to the sink, between 0 and
+	// 1, e.g. "0.1", none being sent at "0" and every evaluation, DefaultImpressionSampleRate, when unset
+	ImpressionSampleRate = "IMPRESSION_SAMPLE_RATE"
+
+	// ImpressionBatchSize environment variable setting the number of impressions sent to the sink at once,
+	// DefaultImpressionBatchSize by default
+	ImpressionBatchSize = "IMPRESSION_BATCH_SIZE"
+
+	// ImpressionFlushInterval environment variable setting the interval the impressions queued are sent to the sink
+	// at, e.g. "5s", DefaultImpressionFlushInterval by default
+	ImpressionFlushInterval = "IMPRESSION_FLUSH_INTERVAL"
+
+	// LogSinkName the name of the sink logging the impressions
+	LogSinkName = "log"
+
+	// FileSinkName the name of the sink appending the impressions to a file, one JSON object per line
+	FileSinkName = "file"
+
+	// WebhookSinkName the name of the sink posting the impressions to a webhook, as a JSON array
+	WebhookSinkName = "webhook"
+
+	// webhookTimeout the deadline of the delivery of a batch of impressions to the webhook
+	webhookTimeout = 5 * time.Second
+)
+
+// ImpressionConfig the configuration of the recording of the evaluations of the flags
+type ImpressionConfig struct {
+
+	// Sink the name of the sink of the impressions, the impressions being only counted when empty
+	Sink string
+
+	// File the file the file sink appends the impressions to
+	File string
+
+	// WebhookURL the URL the webhook sink posts the impressions to
+	WebhookURL string
+
+	// SampleRate the share of the evaluations sent to the sink, up to 1, none when zero or below and
+	// DefaultImpressionSampleRate when nil
+	SampleRate *float64
+
+	// BatchSize the number of impressions sent to the sink at once, DefaultImpressionBatchSize when not positive
+	BatchSize int
+
+	// FlushInterval the interval the impressions queued are sent to the sink at, DefaultImpressionFlushInterval when
+	// not positive
+	FlushInterval time.Duration
+}
+
+// Validate checks the configuration of the chosen sink is complete and the sample rate is a share
+func (c ImpressionConfig) Validate() error {
+	switch c.Sink {
+	case "", LogSinkName:
+	case FileSinkName:
+		if c.File == "" {
+			return fmt.Errorf("the file sink takes the path of the file of the impressions in %s", ImpressionFile)
+		}
+	case WebhookSinkName:
+		if u, err := url.Parse(c.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("the URL of the webhook of the impressions is not an http(s) URL: %q", c.WebhookURL)
+		}
+	default:
+		return fmt.Errorf("unknown impression sink %q, expecting one of %s, %s, %s", c.Sink, LogSinkName,
+			FileSinkName, WebhookSinkName)
+	}
+	if c.SampleRate != nil && (*c.SampleRate < 0 || *c.SampleRate > 1) {
+		return fmt.Errorf("the sample rate of the impressions is not between 0 and 1: %v", *c.SampleRate)
+	}
+	return nil
+}
+
+// NewSink creates the sink chosen by the configuration, nil when none is
+func (c ImpressionConfig) NewSink() (Sink, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	switch c.Sink {
+	case LogSinkName:
+		return LogSink{}, nil
+	case FileSinkName:
+		sink, err := NewFileSink(c.File)
+		if err != nil {
+			return nil, err
+		}
+		return sink, nil
+	case WebhookSinkName:
+		return NewWebhookSink(c.WebhookURL), nil
+	}
+	return nil, nil
+}
+
+// LogSink the sink logging the impressions
+type LogSink struct{}
+
+// Send logs the given impressions, one per line
+func (LogSink) Send(impressions []Impression) error {
+	for _, impression := range impressions {
+		log.Infof("Impression of %s%s=%v variant=%q user=%q platform=%q version=%q", appPrefix(impression.App),
+			impression.Flag, impression.Enabled, impression.Variant, impression.UserID, impression.Platform,
+			impression.Version)
+	}
+	return nil
+}
+
+// FileSink the sink appending the impressions to a file, one JSON object per line
+type FileSink struct {
+	sync.Mutex
+	file *os.File
+}
+
+// NewFileSink creates a sink appending the impressions to the given file, created if need be
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the file of the impressions: %w", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Send appends the given impressions to the file
+func (s *FileSink) Send(impressions []Impression) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, impression := range impressions {
+		if err := encoder.Encode(impression); err != nil {
+			return err
+		}
+	}
+	s.Lock()
+	defer s.Unlock()
+	_, err := s.file.Write(buf.Bytes())
+	return err
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	return s.file.Close()
+}
+
+// WebhookSink the sink posting the impressions to a webhook, as a JSON array
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookSink creates a sink posting the impressions to the given URL
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{URL: url, Client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// Send posts the given impressions to the webhook, failing unless it answers with a 2xx status
+func (s *WebhookSink) Send(impressions []Impression) error {
+	body, err := json.Marshal(impressions)
+	if err != nil {
+		return err
+	}
+	resp, err := s.Client.Post(s.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("the webhook answered with the status %d", resp.StatusCode)
+	}
+	return nil
+}
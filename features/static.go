@@ -0,0 +1,73 @@
+package features
+
+import (
+	"context"
+	"github.com/akhettar/app-features-manager/model"
+	"sort"
+	"strings"
+)
+
+// Definition the definition of a flag held by a static provider. The flag is enabled for the platforms and the app
+// versions given, like with the app version strategy in unleash, every platform and version matching when empty.
+type Definition struct {
+
+	// Enabled whether the flag is enabled
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Platforms the platforms the flag is enabled on, e.g. ["android"]
+	Platforms []string `json:"platforms,omitempty" yaml:"platforms"`
+
+	// VersionRange the range of the app versions the flag is enabled for, e.g. ">=5.0"
+	VersionRange string `json:"versionRange,omitempty" yaml:"versionRange"`
+
+	// Variant the variant returned along with the flag when enabled
+	Variant *model.Variant `json:"variant,omitempty" yaml:"variant"`
+}
+
+// StaticProvider the provider evaluating a fixed set of flag definitions, by name. The flags of an app are namespaced
+// by its identifier, e.g. "com.acme.banking.ITFeature", as in unleash.
+type StaticProvider struct {
+	definitions map[string]Definition
+	names       []string
+
+	// App the identifier of the app the flags are evaluated for, the default app when empty
+	App string
+}
+
+// NewStaticProvider creates a provider evaluating the given flag definitions
+func NewStaticProvider(definitions map[string]Definition) *StaticProvider {
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &StaticProvider{definitions: definitions, names: names}
+}
+
+// ForApp returns a provider evaluating the flags of the given app
+func (p *StaticProvider) ForApp(app string) Provider {
+	return &StaticProvider{definitions: p.definitions, names: p.names, App: app}
+}
+
+// FetchFeatureFlags returns whether each flag of the app is enabled for the given context
+func (p *StaticProvider) FetchFeatureFlags(ctx context.Context, evaluation EvaluationContext) (Flags, error) {
+	toggles, err := p.FetchToggles(ctx, evaluation)
+	return toggles.Flags(), err
+}
+
+// FetchToggles returns the toggles of the app for the given context, with the variant of the flags enabled
+func (p *StaticProvider) FetchToggles(ctx context.Context, evaluation EvaluationContext) (Toggles, error) {
+	unleashCtx := evaluation.unleashContext(p.App)
+	results := make(Toggles)
+	for _, flag := range appFlags(p.names, p.App) {
+		definition := p.definitions[appPrefix(p.App)+flag]
+		params := map[string]interface{}{PlatformsParameter: strings.Join(definition.Platforms, CommaSeparator),
+			VersionRangeParameter: definition.VersionRange}
+		toggle := model.Toggle{Enabled: definition.Enabled && AppVersionStrategy{}.IsEnabled(params, &unleashCtx)}
+		if toggle.Enabled {
+			toggle.Variant = definition.Variant
+		}
+		results[flag] = toggle
+	}
+	return results, nil
+}
@@ -1,27 +1,132 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"github.com/akhettar/app-features-manager/api"
 	_ "github.com/akhettar/app-features-manager/docs"
 	"github.com/akhettar/app-features-manager/features"
 	"github.com/akhettar/app-features-manager/repository"
 	"github.com/labstack/gommon/log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 // @BasePath /
 // @title App Status API
 // @version 1.0
+// @description Every route but the health check is also served for a given app under /apps/{appId}, e.g.
+// @description /apps/com.acme.banking/status/version/{version}/{platform}, the data and flags of each app being isolated.
 
 // @license.name Apache 2.0
 // @license.url http://www.apache.org/licenses/LICENSE-2.0.html
 
+// shutdownTimeout the time the requests in flight are given to complete on shutdown
+const shutdownTimeout = 10 * time.Second
+
 // @BasePath /
 func main() {
 
+	migrate := flag.Bool("migrate", false, "migrate the schema of the mongo data store and exit")
+	flag.Parse()
+	if *migrate {
+		migrateSchema(repository.NewRepository())
+		return
+	}
+
 	log.Info("Starting up the server..")
-	router := api.NewAppStatusHandler(repository.NewRepository(),
-		features.NewUnleashClient()).CreateRouter()
+	var repo repository.Repository
+	var mongoRepo *repository.MongoRepository
+	var fileRepo *repository.FileRepository
+	switch repository.GetEnv(repository.Backend, "") {
+	case repository.MemoryBackend:
+		log.Warn("Using the in-memory repository, the data will be lost on shutdown")
+		repo = repository.NewMemoryRepository()
+	case repository.FileBackend:
+		var err error
+		fileRepo, err = repository.NewFileRepository(repository.GetEnv(repository.JournalPath, repository.DefaultJournalPath))
+		if err != nil {
+			log.Fatalf("Failed to open the journal: %v", err)
+		}
+		repo = fileRepo
+	default:
+		mongoRepo = repository.NewRepository()
+		if repository.GetEnv(repository.MigrateOnStart, "true") == "true" {
+			migrateSchema(mongoRepo)
+		}
+		repo = mongoRepo
+	}
+	// refresh the statuses and the flag definitions cached by this replica on the writes of the others
+	var refreshers []func(repository.ChangeEvent)
+	if ttl := repository.GetDurationEnv(repository.CacheTTL, repository.DefaultCacheTTL); ttl > 0 {
+		cachingRepo := repository.NewCachingRepository(repo, repository.CacheConfig{TTL: ttl,
+			NegativeTTL: repository.GetDurationEnv(repository.NegativeCacheTTL, repository.DefaultNegativeCacheTTL),
+			Size:        repository.GetIntEnv(repository.CacheSize, repository.DefaultCacheSize)})
+		refreshers = append(refreshers, cachingRepo.Refresh)
+		repo = cachingRepo
+	}
+	config, err := features.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.Repository = repo
+	provider, err := features.NewProvider(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sink, err := config.Impressions.NewSink()
+	if err != nil {
+		log.Fatal(err)
+	}
+	impressions := features.NewImpressionProvider(provider, sink, config.Impressions)
+	if refresher, ok := provider.(interface{ Refresh(repository.ChangeEvent) }); ok {
+		refreshers = append(refreshers, refresher.Refresh)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if mongoRepo != nil && len(refreshers) > 0 {
+		watcher := repository.NewWatcher(mongoRepo,
+			repository.GetDurationEnv(repository.WatchPollInterval, repository.DefaultWatchPollInterval))
+		for _, refresher := range refreshers {
+			watcher.Subscribe(refresher)
+		}
+		go watcher.Run(ctx)
+	}
+	router := api.NewAppStatusHandler(repo, impressions).CreateRouter()
 	// Start server
-	router.Logger.Fatal(router.Start(":1323"))
+	go func() {
+		if err := router.Start(":1323"); err != nil && err != http.ErrServerClosed {
+			router.Logger.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
+
+	// stop taking requests before flushing and closing what they write to, the deferred calls not running on log.Fatal
 	log.Info("Shutting down the server..")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := router.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("Failed to shut down the server gracefully: %v", err)
+	}
+	if err := impressions.Close(); err != nil {
+		log.Errorf("Failed to flush the impressions: %v", err)
+	}
+	if fileRepo != nil {
+		if err := fileRepo.Close(); err != nil {
+			log.Errorf("Failed to close the journal: %v", err)
+		}
+	}
+}
+
+// Applies the migrations of the schema not applied yet
+func migrateSchema(repo *repository.MongoRepository) {
+	ctx, cancel := context.WithTimeout(context.Background(), repository.MigrationTimeout)
+	defer cancel()
+	if err := repo.Migrate(ctx, repository.Migrations); err != nil {
+		log.Fatalf("Failed to migrate the schema: %v", err)
+	}
+	log.Info("The schema is up to date")
 }
@@ -5,9 +5,12 @@
 package mocks
 
 import (
+	context "context"
 	model "github.com/akhettar/app-features-manager/model"
+	repository "github.com/akhettar/app-features-manager/repository"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
+	time "time"
 )
 
 // MockRepository is a mock of Repository interface
@@ -33,31 +36,237 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// Delete mocks base method
+func (m *MockRepository) Delete(arg0 context.Context, arg1 model.ReleaseKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete
+func (mr *MockRepositoryMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), arg0, arg1)
+}
+
+// DeleteFlag mocks base method
+func (m *MockRepository) DeleteFlag(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFlag", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFlag indicates an expected call of DeleteFlag
+func (mr *MockRepositoryMockRecorder) DeleteFlag(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFlag", reflect.TypeOf((*MockRepository)(nil).DeleteFlag), arg0, arg1)
+}
+
 // Find mocks base method
-func (m *MockRepository) Find(arg0, arg1 string) (model.ReleaseResponse, error) {
+func (m *MockRepository) Find(arg0 context.Context, arg1, arg2 string) (model.ReleaseResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Find", arg0, arg1)
+	ret := m.ctrl.Call(m, "Find", arg0, arg1, arg2)
 	ret0, _ := ret[0].(model.ReleaseResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Find indicates an expected call of Find
-func (mr *MockRepositoryMockRecorder) Find(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Find(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockRepository)(nil).Find), arg0, arg1, arg2)
+}
+
+// FindAt mocks base method
+func (m *MockRepository) FindAt(arg0 context.Context, arg1, arg2 string, arg3 time.Time) (model.ReleaseResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAt", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(model.ReleaseResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAt indicates an expected call of FindAt
+func (mr *MockRepositoryMockRecorder) FindAt(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAt", reflect.TypeOf((*MockRepository)(nil).FindAt), arg0, arg1, arg2, arg3)
+}
+
+// FindAudit mocks base method
+func (m *MockRepository) FindAudit(arg0 context.Context, arg1 model.AuditFilter) ([]model.AuditDAO, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAudit", arg0, arg1)
+	ret0, _ := ret[0].([]model.AuditDAO)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FindAudit indicates an expected call of FindAudit
+func (mr *MockRepositoryMockRecorder) FindAudit(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAudit", reflect.TypeOf((*MockRepository)(nil).FindAudit), arg0, arg1)
+}
+
+// FindFlag mocks base method
+func (m *MockRepository) FindFlag(arg0 context.Context, arg1 string) (model.FlagDAO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindFlag", arg0, arg1)
+	ret0, _ := ret[0].(model.FlagDAO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindFlag indicates an expected call of FindFlag
+func (mr *MockRepositoryMockRecorder) FindFlag(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindFlag", reflect.TypeOf((*MockRepository)(nil).FindFlag), arg0, arg1)
+}
+
+// FindFlags mocks base method
+func (m *MockRepository) FindFlags(arg0 context.Context) ([]model.FlagDAO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindFlags", arg0)
+	ret0, _ := ret[0].([]model.FlagDAO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindFlags indicates an expected call of FindFlags
+func (mr *MockRepositoryMockRecorder) FindFlags(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindFlags", reflect.TypeOf((*MockRepository)(nil).FindFlags), arg0)
+}
+
+// FindThresholds mocks base method
+func (m *MockRepository) FindThresholds(arg0 context.Context, arg1 string, arg2 time.Time) (model.ThresholdsDAO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindThresholds", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.ThresholdsDAO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindThresholds indicates an expected call of FindThresholds
+func (mr *MockRepositoryMockRecorder) FindThresholds(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindThresholds", reflect.TypeOf((*MockRepository)(nil).FindThresholds), arg0, arg1, arg2)
+}
+
+// ForApp mocks base method
+func (m *MockRepository) ForApp(arg0 string) repository.Repository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForApp", arg0)
+	ret0, _ := ret[0].(repository.Repository)
+	return ret0
+}
+
+// ForApp indicates an expected call of ForApp
+func (mr *MockRepositoryMockRecorder) ForApp(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockRepository)(nil).Find), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForApp", reflect.TypeOf((*MockRepository)(nil).ForApp), arg0)
+}
+
+// History mocks base method
+func (m *MockRepository) History(arg0 context.Context, arg1 model.ReleaseKey) ([]model.ReleaseDAO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", arg0, arg1)
+	ret0, _ := ret[0].([]model.ReleaseDAO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// History indicates an expected call of History
+func (mr *MockRepositoryMockRecorder) History(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockRepository)(nil).History), arg0, arg1)
 }
 
 // Insert mocks base method
-func (m *MockRepository) Insert(arg0 interface{}) error {
+func (m *MockRepository) Insert(arg0 context.Context, arg1 interface{}) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Insert", arg0)
+	ret := m.ctrl.Call(m, "Insert", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Insert indicates an expected call of Insert
-func (mr *MockRepositoryMockRecorder) Insert(arg0 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Insert(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockRepository)(nil).Insert), arg0, arg1)
+}
+
+// InsertAudit mocks base method
+func (m *MockRepository) InsertAudit(arg0 context.Context, arg1 model.AuditDAO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertAudit", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertAudit indicates an expected call of InsertAudit
+func (mr *MockRepositoryMockRecorder) InsertAudit(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertAudit", reflect.TypeOf((*MockRepository)(nil).InsertAudit), arg0, arg1)
+}
+
+// InsertThresholds mocks base method
+func (m *MockRepository) InsertThresholds(arg0 context.Context, arg1 model.ThresholdsDAO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertThresholds", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertThresholds indicates an expected call of InsertThresholds
+func (mr *MockRepositoryMockRecorder) InsertThresholds(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertThresholds", reflect.TypeOf((*MockRepository)(nil).InsertThresholds), arg0, arg1)
+}
+
+// List mocks base method
+func (m *MockRepository) List(arg0 context.Context, arg1 model.ReleaseFilter) ([]model.ReleaseDAO, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0, arg1)
+	ret0, _ := ret[0].([]model.ReleaseDAO)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// List indicates an expected call of List
+func (mr *MockRepositoryMockRecorder) List(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockRepository)(nil).List), arg0, arg1)
+}
+
+// SaveFlag mocks base method
+func (m *MockRepository) SaveFlag(arg0 context.Context, arg1 model.FlagDAO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveFlag", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveFlag indicates an expected call of SaveFlag
+func (mr *MockRepositoryMockRecorder) SaveFlag(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveFlag", reflect.TypeOf((*MockRepository)(nil).SaveFlag), arg0, arg1)
+}
+
+// Scheduled mocks base method
+func (m *MockRepository) Scheduled(arg0 context.Context, arg1 string, arg2 time.Time) ([]model.ReleaseDAO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Scheduled", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.ReleaseDAO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Scheduled indicates an expected call of Scheduled
+func (mr *MockRepositoryMockRecorder) Scheduled(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockRepository)(nil).Insert), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scheduled", reflect.TypeOf((*MockRepository)(nil).Scheduled), arg0, arg1, arg2)
 }
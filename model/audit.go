@@ -0,0 +1,102 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	// AuditPublish the action of publishing a release
+	AuditPublish = "publish"
+
+	// AuditUpdate the action of updating the status of an existing release
+	AuditUpdate = "update"
+
+	// AuditDelete the action of deleting a release
+	AuditDelete = "delete"
+
+	// AuditThresholds the action of declaring the version thresholds of a platform
+	AuditThresholds = "thresholds"
+
+	// AuditRollback the action of restoring a release or the thresholds of a platform to a past state
+	AuditRollback = "rollback"
+
+	// AuditFlag the action of defining a flag evaluated by the service
+	AuditFlag = "flag"
+
+	// AuditDeleteFlag the action of deleting the definition of a flag
+	AuditDeleteFlag = "deleteFlag"
+)
+
+// AuditDAO an immutable record of a change made through the API to be stored in the data store
+type AuditDAO struct {
+	Action             string       `bson:"action"`
+	Actor              string       `bson:"actor"`
+	Version            string       `bson:"version,omitempty"`
+	Range              string       `bson:"range,omitempty"`
+	Platform           string       `bson:"platform"`
+	Flag               string       `bson:"flag,omitempty"`
+	PreviousStatus     string       `bson:"previousStatus,omitempty"`
+	NewStatus          string       `bson:"newStatus,omitempty"`
+	Schedule           []Transition `bson:"schedule,omitempty"`
+	PreviousThresholds *Thresholds  `bson:"previousThresholds,omitempty"`
+	NewThresholds      *Thresholds  `bson:"newThresholds,omitempty"`
+	Reason             string       `bson:"reason,omitempty"`
+	RequestID          string       `bson:"requestId,omitempty"`
+	Timestamp          time.Time    `bson:"timestamp"`
+	App                string       `bson:"app,omitempty"`
+}
+
+// Record converts the stored audit record into its representation in the API responses
+func (a AuditDAO) Record() AuditRecord {
+	return AuditRecord{Action: a.Action, Actor: a.Actor, Version: a.Version, Range: a.Range, Platform: a.Platform,
+		Flag: a.Flag, PreviousStatus: a.PreviousStatus, NewStatus: a.NewStatus, Schedule: a.Schedule,
+		PreviousThresholds: a.PreviousThresholds, NewThresholds: a.NewThresholds, Reason: a.Reason, RequestID: a.RequestID,
+		Timestamp: a.Timestamp}
+}
+
+// AuditFilter the criteria to query the audit trail by. Empty criteria match every record.
+type AuditFilter struct {
+	Version  string
+	Range    string
+	Platform string
+	Flag     string
+	Actor    string
+
+	// From the inclusive lower bound of the time window
+	From time.Time
+
+	// To the exclusive upper bound of the time window
+	To time.Time
+
+	// Page the 1-based page number
+	Page int
+
+	// Limit the number of records per page
+	Limit int
+}
+
+// AuditRecord a change made through the API
+type AuditRecord struct {
+	Action             string       `json:"action"`
+	Actor              string       `json:"actor"`
+	Version            string       `json:"version,omitempty"`
+	Range              string       `json:"range,omitempty"`
+	Platform           string       `json:"platform"`
+	Flag               string       `json:"flag,omitempty"`
+	PreviousStatus     string       `json:"previousStatus,omitempty"`
+	NewStatus          string       `json:"newStatus,omitempty"`
+	Schedule           []Transition `json:"schedule,omitempty"`
+	PreviousThresholds *Thresholds  `json:"previousThresholds,omitempty"`
+	NewThresholds      *Thresholds  `json:"newThresholds,omitempty"`
+	Reason             string       `json:"reason,omitempty"`
+	RequestID          string       `json:"requestId,omitempty"`
+	Timestamp          time.Time    `json:"timestamp"`
+}
+
+// AuditListResponse a page of the audit trail, the most recent changes first
+type AuditListResponse struct {
+	Records []AuditRecord `json:"records"`
+	Page    int           `json:"page"`
+	Limit   int           `json:"limit"`
+	Total   int64         `json:"total"`
+}
@@ -0,0 +1,26 @@
+package model
+
+// CacheStats the statistics of the cache of the app statuses since start up
+type CacheStats struct {
+
+	// Hits the queries served from the cache, including the versions cached as not found
+	Hits uint64 `json:"hits"`
+
+	// Misses the queries served from the data store
+	Misses uint64 `json:"misses"`
+
+	// Shared the queries served by waiting for the same query already sent to the data store
+	Shared uint64 `json:"shared"`
+
+	// Evictions the statuses evicted before expiring to bound the size of the cache
+	Evictions uint64 `json:"evictions"`
+
+	// Invalidations the writes invalidating the cached statuses of a platform
+	Invalidations uint64 `json:"invalidations"`
+
+	// Entries the number of statuses currently cached
+	Entries int `json:"entries"`
+
+	// HitRatio the ratio of the queries served from the cache, zero when none was received
+	HitRatio float64 `json:"hitRatio"`
+}
@@ -0,0 +1,53 @@
+package model
+
+import "net/http"
+
+const (
+	// ErrorBadRequest the error code of the requests failing validation
+	ErrorBadRequest = "bad_request"
+
+	// ErrorUnauthorized the error code of the requests without a valid JWT token
+	ErrorUnauthorized = "unauthorized"
+
+	// ErrorForbidden the error code of the requests the JWT token is not allowed to make
+	ErrorForbidden = "forbidden"
+
+	// ErrorNotFound the error code of the requests addressing data that does not exist
+	ErrorNotFound = "not_found"
+
+	// ErrorConflict the error code of the requests conflicting with the data already stored
+	ErrorConflict = "conflict"
+
+	// ErrorUnavailable the error code of the requests the data store could not serve
+	ErrorUnavailable = "unavailable"
+
+	// ErrorTimeout the error code of the requests the data store did not serve in time
+	ErrorTimeout = "timeout"
+
+	// ErrorInternal the error code of the failures of the service
+	ErrorInternal = "internal"
+)
+
+// ErrorCode returns the machine-readable error code of the given HTTP status, any other client error being a bad request
+func ErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrorBadRequest
+	case http.StatusUnauthorized:
+		return ErrorUnauthorized
+	case http.StatusForbidden:
+		return ErrorForbidden
+	case http.StatusNotFound:
+		return ErrorNotFound
+	case http.StatusConflict:
+		return ErrorConflict
+	case http.StatusServiceUnavailable:
+		return ErrorUnavailable
+	case http.StatusGatewayTimeout:
+		return ErrorTimeout
+	}
+	if status < http.StatusInternalServerError {
+		return ErrorBadRequest
+	}
+	return ErrorInternal
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"github.com/go-playground/validator/v10"
+	"regexp"
+	"time"
+)
+
+// flagName the pattern of the names of the flags, which cannot hold the dots namespacing the flags of the apps
+var flagName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,100}$`)
+
+// Strategy a strategy enabling a flag for the requests matching every criterion it sets. A strategy setting no
+// criterion matches every request.
+type Strategy struct {
+
+	// Rollout the percentage of the customers the flag is enabled for, each customer being assigned a stable bucket
+	// by hashing its identifier along with the group
+	Rollout *int `json:"rollout,omitempty" bson:"rollout,omitempty"`
+
+	// SessionRollout the percentage of the sessions the flag is enabled for, hashed like the customers
+	SessionRollout *int `json:"sessionRollout,omitempty" bson:"sessionRollout,omitempty"`
+
+	// GroupID the group the customers and sessions are hashed with, the name of the flag when empty. Flags sharing
+	// a group are enabled for the same customers at the same percentage.
+	GroupID string `json:"groupId,omitempty" bson:"groupId,omitempty"`
+
+	// UserIDs the customers the flag is enabled for
+	UserIDs []string `json:"userIds,omitempty" bson:"userIds,omitempty"`
+
+	// Platforms the platforms the flag is enabled on, e.g. ["android"]
+	Platforms []string `json:"platforms,omitempty" bson:"platforms,omitempty"`
+
+	// VersionRange the range of the app versions the flag is enabled for, e.g. ">=5.0"
+	VersionRange string `json:"versionRange,omitempty" bson:"versionRange,omitempty"`
+
+	// From the time the flag is enabled from, inclusive
+	From *time.Time `json:"from,omitempty" bson:"from,omitempty"`
+
+	// Until the time the flag is enabled until, exclusive
+	Until *time.Time `json:"until,omitempty" bson:"until,omitempty"`
+}
+
+// FlagDAO the definition of a feature flag evaluated by the service itself, stored in the data store
+type FlagDAO struct {
+	Name        string     `bson:"name"`
+	Description string     `bson:"description,omitempty"`
+	Enabled     bool       `bson:"enabled"`
+	Strategies  []Strategy `bson:"strategies,omitempty"`
+	Variant     *Variant   `bson:"variant,omitempty"`
+	Updated     time.Time  `bson:"updated"`
+	App         string     `bson:"app,omitempty"`
+}
+
+// Record converts the stored flag into its representation in the API responses
+func (f FlagDAO) Record() FlagRecord {
+	return FlagRecord{Name: f.Name, Description: f.Description, Enabled: f.Enabled, Strategies: f.Strategies,
+		Variant: f.Variant, Updated: f.Updated}
+}
+
+// FlagRequest is the payload to defining a flag. The flag is enabled for the requests matching any of its strategies,
+// or for every request when it has none, and disabled altogether when not enabled.
+type FlagRequest struct {
+	Description string     `json:"description,omitempty"`
+	Enabled     bool       `json:"enabled"`
+	Strategies  []Strategy `json:"strategies,omitempty"`
+	Variant     *Variant   `json:"variant,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+}
+
+// FlagRequestStructLevelValidation validates the strategies of the flag
+func FlagRequestStructLevelValidation(sl validator.StructLevel) {
+	req := sl.Current().Interface().(FlagRequest)
+	for _, strategy := range req.Strategies {
+		for _, percentage := range []*int{strategy.Rollout, strategy.SessionRollout} {
+			if percentage != nil && (*percentage < 0 || *percentage > 100) {
+				sl.ReportError(*percentage, "rollout", "Rollout", "max", "100")
+			}
+		}
+		for _, platform := range strategy.Platforms {
+			if _, e := Platform(platform).Value(); e != nil {
+				sl.ReportError(platform, "platforms", "Platforms", "", "")
+			}
+		}
+		if strategy.VersionRange != "" {
+			if _, e := ParseConstraint(strategy.VersionRange); e != nil {
+				sl.ReportError(strategy.VersionRange, "versionRange", "VersionRange", "semver", "")
+			}
+		}
+		if strategy.From != nil && strategy.Until != nil && !strategy.Until.After(*strategy.From) {
+			sl.ReportError(*strategy.Until, "until", "Until", "gtfield", "from")
+		}
+	}
+	if req.Variant != nil && req.Variant.Name == "" {
+		sl.ReportError(req.Variant.Name, "variant", "Variant", "required", "")
+	}
+}
+
+// ValidFlagName tells whether the given name is a valid flag name: letters, digits, underscores and dashes
+func ValidFlagName(name string) bool {
+	return flagName.MatchString(name)
+}
+
+// FlagRecord the definition of a flag
+type FlagRecord struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Enabled     bool       `json:"enabled"`
+	Strategies  []Strategy `json:"strategies,omitempty"`
+	Variant     *Variant   `json:"variant,omitempty"`
+	Updated     time.Time  `json:"updated"`
+}
+
+// FlagListResponse the definitions of the flags of the app, sorted by name
+type FlagListResponse struct {
+	Flags []FlagRecord `json:"flags"`
+}
@@ -0,0 +1,36 @@
+package model
+
+// FlagImpressions the number of evaluations of a flag since start up, by result
+type FlagImpressions struct {
+
+	// Flag the name of the flag, namespaced by the identifier of its app
+	Flag string `json:"flag"`
+
+	// Enabled the evaluations enabling the flag
+	Enabled uint64 `json:"enabled"`
+
+	// Disabled the evaluations disabling the flag
+	Disabled uint64 `json:"disabled"`
+
+	// Variants the evaluations enabling the flag, by variant
+	Variants map[string]uint64 `json:"variants,omitempty"`
+}
+
+// ImpressionStats the evaluations of the flags since start up, along with the impressions sent to the sink
+type ImpressionStats struct {
+
+	// Flags the evaluations of each flag, sorted by name
+	Flags []FlagImpressions `json:"flags"`
+
+	// Sampled the impressions sampled to be sent to the sink
+	Sampled uint64 `json:"sampled"`
+
+	// Sent the impressions sent to the sink
+	Sent uint64 `json:"sent"`
+
+	// Dropped the impressions sampled but dropped as the queue of the sink was full
+	Dropped uint64 `json:"dropped"`
+
+	// Failed the impressions the sink failed to receive
+	Failed uint64 `json:"failed"`
+}
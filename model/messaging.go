@@ -0,0 +1,112 @@
+package model
+
+import (
+	"github.com/go-playground/validator/v10"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale the locale of the messages shown when none of the languages accepted by the client is available
+const DefaultLocale = "default"
+
+// localePattern matches the language tags messages can be published for, e.g. "fr", "fr-CA" or "zh-Hant-TW"
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{1,8})*$`)
+
+// Message the copy prompting the users to upgrade the app
+type Message struct {
+	Title   string `json:"title,omitempty" bson:"title,omitempty"`
+	Message string `json:"message,omitempty" bson:"message,omitempty"`
+	Button  string `json:"button,omitempty" bson:"button,omitempty"`
+}
+
+// Messaging the store link and the upgrade copy, per locale, published along with a release or the version thresholds
+// of a platform
+type Messaging struct {
+	StoreURL string             `json:"storeUrl,omitempty" bson:"storeUrl,omitempty"`
+	Messages map[string]Message `json:"messages,omitempty" bson:"messages,omitempty"`
+}
+
+// IsZero tells whether neither a store link nor any message is given
+func (m Messaging) IsZero() bool {
+	return m.StoreURL == "" && len(m.Messages) == 0
+}
+
+// Localize picks the message best matching the languages of the given Accept-Language header. The languages are
+// tried by descending quality, each one falling back to its less specific tags (e.g. fr-CA then fr), before falling
+// back to the default message. The locale of the message is returned along with it.
+func (m Messaging) Localize(acceptLanguage string) (Message, string, bool) {
+	messages := make(map[string]string, len(m.Messages))
+	for locale := range m.Messages {
+		messages[strings.ToLower(locale)] = locale
+	}
+	for _, tag := range acceptedLanguages(acceptLanguage) {
+		for tag != "" {
+			if locale, ok := messages[tag]; ok {
+				return m.Messages[locale], locale, true
+			}
+			i := strings.LastIndex(tag, "-")
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	message, ok := m.Messages[DefaultLocale]
+	return message, DefaultLocale, ok
+}
+
+// acceptedLanguages parses the Accept-Language header into the lower cased language tags it accepts, sorted by
+// descending quality. Languages of quality zero and the wildcard are left out, the latter being served by the
+// default message.
+func acceptedLanguages(header string) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+	var languages []language
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					q = 0
+				}
+				quality = q
+			}
+		}
+		if quality > 0 {
+			languages = append(languages, language{tag, quality})
+		}
+	}
+	sort.SliceStable(languages, func(i, j int) bool { return languages[i].quality > languages[j].quality })
+
+	tags := make([]string, 0, len(languages))
+	for _, l := range languages {
+		tags = append(tags, l.tag)
+	}
+	return tags
+}
+
+// validateMessaging validates the store link is an absolute URL and the messages are published for valid locales
+func validateMessaging(sl validator.StructLevel, m Messaging) {
+	if m.StoreURL != "" {
+		if u, e := url.Parse(m.StoreURL); e != nil || u.Scheme == "" {
+			sl.ReportError(m.StoreURL, "storeUrl", "StoreURL", "url", "")
+		}
+	}
+	for locale := range m.Messages {
+		if locale != DefaultLocale && !localePattern.MatchString(locale) {
+			sl.ReportError(locale, "messages", "Messages", "locale", "")
+		}
+	}
+}
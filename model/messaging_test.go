@@ -0,0 +1,58 @@
+package model_test
+
+import (
+	. "github.com/akhettar/app-features-manager/model"
+	"github.com/akhettar/app-features-manager/test"
+	"testing"
+)
+
+func TestMessaging_Localize(t *testing.T) {
+
+	messaging := Messaging{StoreURL: "https://apps.apple.com/app/id123", Messages: map[string]Message{
+		DefaultLocale: {Title: "Update available"},
+		"fr":          {Title: "Mise à jour disponible"},
+		"fr-CA":       {Title: "Mise à jour offerte"},
+		"de":          {Title: "Update verfügbar"},
+	}}
+
+	expectations := map[string]string{
+		"":                           DefaultLocale,
+		"fr-CA":                      "fr-CA",
+		"fr-ca":                      "fr-CA",
+		"fr-BE":                      "fr",
+		"fr-Latn-BE":                 "fr",
+		"es, de;q=0.5":               "de",
+		"en;q=0.8, de;q=0.9":         "de",
+		"de;q=0, fr;q=0.1":           "fr",
+		"es, *;q=0.5":                DefaultLocale,
+		"pt-BR,pt;q=0.9,en-US;q=0.8": DefaultLocale,
+	}
+
+	t.Logf("Given messages published in english by default, in french, canadian french and german")
+	{
+		for header, expected := range expectations {
+			t.Logf("\tWhen localizing the message for the Accept-Language header \"%s\"", header)
+			{
+				message, locale, ok := messaging.Localize(header)
+				if ok && locale == expected && message == messaging.Messages[expected] {
+					t.Logf("\t\tThe message should be the %s one %v", expected, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe message should be the %s one but was %s %v", expected, locale, test.BallotX)
+				}
+			}
+		}
+	}
+
+	t.Logf("Given messages published without a default")
+	{
+		messaging := Messaging{Messages: map[string]Message{"fr": {Title: "Mise à jour disponible"}}}
+		t.Logf("\tWhen localizing the message for a language not published")
+		{
+			if _, _, ok := messaging.Localize("en-GB"); !ok {
+				t.Logf("\t\tNo message should be found %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tNo message should be found %v", test.BallotX)
+			}
+		}
+	}
+}
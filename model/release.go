@@ -0,0 +1,146 @@
+package model
+
+import (
+	"github.com/go-playground/validator/v10"
+	"time"
+)
+
+const (
+	// DefaultPageLimit the number of releases returned per page when not specified
+	DefaultPageLimit = 20
+
+	// MaxPageLimit the maximum number of releases returned per page
+	MaxPageLimit = 100
+)
+
+// ReleaseKey identifies a release by its platform and either its version or its version range
+type ReleaseKey struct {
+	Platform string
+	Version  string
+	Range    string
+}
+
+// String returns a readable representation of the key, e.g. "ios 3.2.0" or "ios <3.2.0"
+func (k ReleaseKey) String() string {
+	if k.Range != "" {
+		return k.Platform + " " + k.Range
+	}
+	return k.Platform + " " + k.Version
+}
+
+// ReleaseFilter the criteria to list releases by. Empty criteria match every release.
+type ReleaseFilter struct {
+	Platform string
+
+	// Status the status the releases were published with, regardless of their scheduled transitions
+	Status string
+
+	// Page the 1-based page number
+	Page int
+
+	// Limit the number of releases per page
+	Limit int
+
+	// Ascending sorts the releases by ascending released date rather than the most recent first
+	Ascending bool
+}
+
+// Key returns the key identifying the release
+func (r ReleaseDAO) Key() ReleaseKey {
+	return ReleaseKey{Platform: r.Platform, Version: r.Version, Range: r.Range}
+}
+
+// Record converts the stored release into its representation in the API responses
+func (r ReleaseDAO) Record() ReleaseRecord {
+	return ReleaseRecord{Version: r.Version, Range: r.Range, Platform: r.Platform, Status: r.Status, Released: r.Released,
+		Schedule: r.Schedule, Messaging: r.Messaging}
+}
+
+// ReleaseRecord a published state of a release
+type ReleaseRecord struct {
+	Messaging
+	Version  string       `json:"version,omitempty"`
+	Range    string       `json:"range,omitempty"`
+	Platform string       `json:"platform"`
+	Status   string       `json:"status"`
+	Released time.Time    `json:"released"`
+	Schedule []Transition `json:"schedule,omitempty"`
+}
+
+// ReleaseListResponse a page of releases in their current state
+type ReleaseListResponse struct {
+	Releases []ReleaseRecord `json:"releases"`
+	Page     int             `json:"page"`
+	Limit    int             `json:"limit"`
+	Total    int64           `json:"total"`
+}
+
+// ReleaseHistoryResponse the current status of a release along with every state it was published with, oldest first
+type ReleaseHistoryResponse struct {
+	Version  string          `json:"version,omitempty"`
+	Range    string          `json:"range,omitempty"`
+	Platform string          `json:"platform"`
+	Status   string          `json:"status"`
+	History  []ReleaseRecord `json:"history"`
+}
+
+// StatusUpdateRequest is the payload to updating the status of a release. The new schedule replaces the one the
+// release was previously published with, whereas the store link and messages are kept unless new ones are given.
+type StatusUpdateRequest struct {
+	Messaging
+	Status   string       `json:"status" validate:"required"`
+	Schedule []Transition `json:"schedule,omitempty"`
+	Reason   string       `json:"reason,omitempty"`
+}
+
+// RollbackRequest is the payload to restoring a release, or every release of a platform when neither a version nor a
+// range is given, to the state it had at a given time
+type RollbackRequest struct {
+	Platform string    `json:"platform" validate:"required"`
+	Version  string    `json:"version,omitempty"`
+	Range    string    `json:"range,omitempty"`
+	To       time.Time `json:"to" validate:"required"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// RollbackRequestStructLevelValidation validates the rollback targets a valid platform and a time in the past
+func RollbackRequestStructLevelValidation(sl validator.StructLevel) {
+	req := sl.Current().Interface().(RollbackRequest)
+	if _, e := Platform(req.Platform).Value(); e != nil {
+		sl.ReportError(req.Platform, "platform", "Platform", "", "")
+	}
+	if req.Version != "" && req.Range != "" {
+		sl.ReportError(req.Range, "range", "Range", "excluded_with", "version")
+	}
+	if req.To.After(time.Now()) {
+		sl.ReportError(req.To, "to", "To", "ltnow", "")
+	}
+}
+
+// RollbackResponse the outcome of a rollback. Releases first published after the rollback time are left untouched
+// and reported as skipped, since history is never deleted by a rollback.
+type RollbackResponse struct {
+	Restored   []ReleaseRecord `json:"restored"`
+	Skipped    []ReleaseRecord `json:"skipped"`
+	Thresholds *Thresholds     `json:"thresholds,omitempty"`
+}
+
+// StateAt returns the state a release had at the given time from its history sorted by released date
+func StateAt(history []ReleaseDAO, at time.Time) (ReleaseDAO, bool) {
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].Released.After(at) {
+			return history[i], true
+		}
+	}
+	return ReleaseDAO{}, false
+}
+
+// StatusUpdateRequestStructLevelValidation validates the new status and schedule of the release
+func StatusUpdateRequestStructLevelValidation(sl validator.StructLevel) {
+	req := sl.Current().Interface().(StatusUpdateRequest)
+	if _, e := Status(req.Status).Value(); e != nil {
+		sl.ReportError(req.Status, "status", "Status", "", "")
+	}
+	validateSchedule(sl, req.Schedule)
+	validateMessaging(sl, req.Messaging)
+}
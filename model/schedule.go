@@ -0,0 +1,79 @@
+package model
+
+import (
+	"github.com/go-playground/validator/v10"
+	"time"
+)
+
+// Transition a change of the status of a release scheduled to take effect at a given time, e.g. deprecating a
+// version on a sunset date
+type Transition struct {
+	Status    string    `json:"status" bson:"status"`
+	Effective time.Time `json:"effective" bson:"effective"`
+}
+
+// ScheduledTransition an upcoming transition of a release from its status at the time to its new status
+type ScheduledTransition struct {
+	Version   string    `json:"version,omitempty"`
+	Range     string    `json:"range,omitempty"`
+	Platform  string    `json:"platform"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Effective time.Time `json:"effective"`
+}
+
+// TransitionListResponse the upcoming transitions of the releases, the soonest first
+type TransitionListResponse struct {
+	Transitions []ScheduledTransition `json:"transitions"`
+}
+
+// StatusAt returns the status of the release at the given time: the status of the latest transition of its schedule
+// effective by then, or the status the release was published with if none is.
+func (r ReleaseDAO) StatusAt(at time.Time) string {
+	status := r.Status
+	for _, transition := range r.Schedule {
+		if transition.Effective.After(at) {
+			break
+		}
+		status = transition.Status
+	}
+	return status
+}
+
+// Upcoming returns the transitions of the schedule of the release taking effect after the given time
+func (r ReleaseDAO) Upcoming(after time.Time) []Transition {
+	for i, transition := range r.Schedule {
+		if transition.Effective.After(after) {
+			return r.Schedule[i:]
+		}
+	}
+	return nil
+}
+
+// Transitions returns the upcoming transitions of the release after the given time along with the status each
+// transitions from
+func (r ReleaseDAO) Transitions(after time.Time) []ScheduledTransition {
+	var transitions []ScheduledTransition
+	from := r.StatusAt(after)
+	for _, transition := range r.Upcoming(after) {
+		transitions = append(transitions, ScheduledTransition{Version: r.Version, Range: r.Range, Platform: r.Platform,
+			From: from, To: transition.Status, Effective: transition.Effective})
+		from = transition.Status
+	}
+	return transitions
+}
+
+// validateSchedule validates each transition of the schedule is to a valid status and takes effect in the future,
+// after the previous one
+func validateSchedule(sl validator.StructLevel, schedule []Transition) {
+	previous := time.Now()
+	for _, transition := range schedule {
+		if _, e := Status(transition.Status).Value(); e != nil {
+			sl.ReportError(transition.Status, "schedule", "Schedule", "status", "")
+		}
+		if !transition.Effective.After(previous) {
+			sl.ReportError(transition.Effective, "schedule", "Schedule", "gtfield", "")
+		}
+		previous = transition.Effective
+	}
+}
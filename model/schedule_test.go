@@ -0,0 +1,50 @@
+package model_test
+
+import (
+	. "github.com/akhettar/app-features-manager/model"
+	"github.com/akhettar/app-features-manager/test"
+	"testing"
+	"time"
+)
+
+func TestReleaseDAO_StatusAt(t *testing.T) {
+
+	sunset := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2027, 1, 15, 0, 0, 0, 0, time.UTC)
+	release := ReleaseDAO{Version: "3.2.0", Platform: "ios", Status: Supported,
+		Schedule: []Transition{{Status: Deprecated, Effective: sunset}, {Status: Unsupported, Effective: end}}}
+
+	t.Logf("Given a release scheduled to be deprecated on %v then unsupported on %v", sunset, end)
+	{
+		expectations := []struct {
+			at       time.Time
+			expected string
+		}{
+			{sunset.Add(-time.Second), Supported},
+			{sunset, Deprecated},
+			{end.Add(-time.Second), Deprecated},
+			{end.Add(time.Hour), Unsupported},
+		}
+		for _, tc := range expectations {
+			t.Logf("\tWhen evaluating the status at %v", tc.at)
+			{
+				if status := release.StatusAt(tc.at); status == tc.expected {
+					t.Logf("\t\tThe status should be %s %v", tc.expected, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe status should be %s but was %s %v", tc.expected, status, test.BallotX)
+				}
+			}
+		}
+
+		t.Logf("\tWhen listing the transitions still to come after the sunset")
+		{
+			transitions := release.Transitions(sunset)
+			if len(transitions) == 1 && transitions[0].From == Deprecated && transitions[0].To == Unsupported &&
+				transitions[0].Effective.Equal(end) {
+				t.Logf("\t\tOnly the last transition should be listed %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tOnly the last transition should be listed %+v %v", transitions, test.BallotX)
+			}
+		}
+	}
+}
@@ -0,0 +1,541 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version a semantic version as defined by https://semver.org. Short forms such as "1" or "1.0" are accepted
+// and the missing components default to zero.
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	PreRelease []string
+	Build      []string
+}
+
+// ParseVersion parses the given string into a semantic version. A leading "v" is tolerated.
+func ParseVersion(s string) (Version, error) {
+	parts, v, err := parsePartial(s)
+	if err != nil {
+		return Version{}, err
+	}
+	core := strings.SplitN(strings.SplitN(s, "+", 2)[0], "-", 2)[0]
+	if parts == 0 || (parts > 0 && parts <= strings.Count(core, ".")) {
+		return Version{}, fmt.Errorf("invalid version \"%s\": wildcards are not allowed", s)
+	}
+	return v, nil
+}
+
+// String returns the canonical representation of the version
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.PreRelease) > 0 {
+		s += "-" + strings.Join(v.PreRelease, ".")
+	}
+	if len(v.Build) > 0 {
+		s += "+" + strings.Join(v.Build, ".")
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 depending on the precedence of v compared to o. Build metadata is ignored as
+// required by the semver specification.
+func (v Version) Compare(o Version) int {
+	if c := compareUint(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+
+	// a version without pre-release has a higher precedence than one with
+	switch {
+	case len(v.PreRelease) == 0 && len(o.PreRelease) == 0:
+		return 0
+	case len(v.PreRelease) == 0:
+		return 1
+	case len(o.PreRelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.PreRelease) && i < len(o.PreRelease); i++ {
+		if c := compareIdentifier(v.PreRelease[i], o.PreRelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(v.PreRelease), len(o.PreRelease))
+}
+
+// Constraint a semantic version range. The supported syntax is a space or comma separated list of comparators
+// which must all hold, e.g. "<3.2.0", ">=1.0.0 <2.0.0", "3.2.x", "~3.2.1", "^2.1" or "*".
+type Constraint struct {
+	raw   string
+	lower *bound
+	upper *bound
+}
+
+// bound one end of a version interval
+type bound struct {
+	version   Version
+	inclusive bool
+}
+
+// ParseConstraint parses a version range expression
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: strings.TrimSpace(s)}
+	fields := strings.FieldsFunc(c.raw, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) == 0 {
+		return Constraint{}, errors.New("empty version range")
+	}
+
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+
+		// allow a space between the operator and the version, e.g. "< 3.2.0"
+		if isOperator(field) && i+1 < len(fields) {
+			i++
+			field += fields[i]
+		}
+		lower, upper, err := parseComparator(field)
+		if err != nil {
+			return Constraint{}, fmt.Errorf("invalid version range \"%s\": %v", s, err)
+		}
+		c.lower = tighterLower(c.lower, lower)
+		c.upper = tighterUpper(c.upper, upper)
+	}
+
+	if c.lower != nil && c.upper != nil {
+		cmp := c.lower.version.Compare(c.upper.version)
+		if cmp > 0 || (cmp == 0 && !(c.lower.inclusive && c.upper.inclusive)) {
+			return Constraint{}, fmt.Errorf("invalid version range \"%s\": no version can satisfy it", s)
+		}
+	}
+	return c, nil
+}
+
+// String returns the range expression as it was published
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// Check returns true if the given version satisfies the constraint
+func (c Constraint) Check(v Version) bool {
+	if c.lower != nil {
+		cmp := v.Compare(c.lower.version)
+		if cmp < 0 || (cmp == 0 && !c.lower.inclusive) {
+			return false
+		}
+	}
+	if c.upper != nil {
+		cmp := v.Compare(c.upper.version)
+		if cmp > 0 || (cmp == 0 && !c.upper.inclusive) {
+			return false
+		}
+	}
+	return true
+}
+
+// CompareSpecificity returns a positive number if c is more specific than o, a negative one if it is less specific
+// and zero if neither can be preferred. An exact version beats any range, a range contained in another beats it, e.g.
+// ">=3.0.0 <4.0.0" beats "<4.0.0", then the narrowest range wins, e.g. "3.2.x" beats "^3.2.1" and "<3.2.0" beats
+// ">=3.0.0 <4.0.0" as it cuts most of it. The component the ranges are cut at, then the number of ends they are
+// bounded by, only break the ties.
+func (c Constraint) CompareSpecificity(o Constraint) int {
+	if cmp := compareBool(c.exact(), o.exact()); cmp != 0 {
+		return cmp
+	}
+	if cmp := c.contains(o); cmp != 0 {
+		return cmp
+	}
+	if cmp := c.compareWidth(o); cmp != 0 {
+		return cmp
+	}
+	if cmp := compareInt(c.precision(), o.precision()); cmp != 0 {
+		return cmp
+	}
+	return compareInt(c.bounds(), o.bounds())
+}
+
+// contains returns a positive number if c lies within o, a negative one if o lies within c and zero if the ranges
+// are equal or neither contains the other
+func (c Constraint) contains(o Constraint) int {
+	lower, upper := compareEnd(c.lower, o.lower, compareLower), compareEnd(c.upper, o.upper, compareUpper)
+	switch {
+	case lower >= 0 && upper >= 0 && lower+upper > 0:
+		return 1
+	case lower <= 0 && upper <= 0 && lower+upper < 0:
+		return -1
+	}
+	return 0
+}
+
+// compareWidth returns a positive number if c is narrower than o. A range bounded on both ends is compared with
+// another by width, and with a half-open one by the share of it the half-open range cuts off.
+func (c Constraint) compareWidth(o Constraint) int {
+	switch {
+	case c.bounds() == 2 && o.bounds() == 2:
+		return compareSpan(width(o.lower.version, o.upper.version), width(c.lower.version, c.upper.version))
+	case c.bounds() == 1 && o.bounds() == 2:
+		return c.cuts(o)
+	case c.bounds() == 2 && o.bounds() == 1:
+		return -o.cuts(c)
+	}
+	return 0
+}
+
+// cuts returns a positive number if the half-open range c keeps a narrower part of the bounded range o than it cuts
+// off, e.g. "<3.2.0" of ">=3.0.0 <4.0.0", a negative one if it keeps a wider part, and zero when the ranges only
+// share the versions of a bound
+func (c Constraint) cuts(o Constraint) int {
+	var kept, cut [3]int64
+	if c.upper != nil {
+		kept, cut = width(o.lower.version, c.upper.version), width(c.upper.version, o.upper.version)
+	} else {
+		kept, cut = width(c.lower.version, o.upper.version), width(o.lower.version, c.lower.version)
+	}
+	if compareSpan(kept, [3]int64{}) <= 0 {
+		return 0
+	}
+	return compareSpan(cut, kept)
+}
+
+// exact returns true if the constraint matches a single version only
+func (c Constraint) exact() bool {
+	return c.lower != nil && c.upper != nil && c.lower.inclusive && c.upper.inclusive &&
+		c.lower.version.Compare(c.upper.version) == 0
+}
+
+// bounds returns the number of ends the range is bounded by
+func (c Constraint) bounds() int {
+	n := 0
+	if c.lower != nil {
+		n++
+	}
+	if c.upper != nil {
+		n++
+	}
+	return n
+}
+
+// precision returns the finest component the range is cut at: 3 for the patch, 2 for the minor, 1 for the major
+// version, and 0 when the range is unbounded. It only breaks the ties between ranges of the same width.
+func (c Constraint) precision() int {
+	n := 0
+	for _, b := range []*bound{c.lower, c.upper} {
+		switch {
+		case b == nil:
+		case b.version.Patch != 0:
+			n = 3
+		case b.version.Minor != 0 && n < 2:
+			n = 2
+		case n < 1:
+			n = 1
+		}
+	}
+	return n
+}
+
+// parseComparator converts a single comparator into the lower and upper bound it imposes. Nil means unbounded.
+func parseComparator(s string) (*bound, *bound, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", ">", "<", "=", "~", "^"} {
+		if strings.HasPrefix(s, candidate) {
+			op = candidate
+			break
+		}
+	}
+	parts, v, err := parsePartial(strings.TrimPrefix(s, op))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// "*" or "x" matches everything
+	if parts == 0 {
+		if op == "<" || op == ">" {
+			return nil, nil, fmt.Errorf("\"%s\" can never be satisfied", s)
+		}
+		return nil, nil, nil
+	}
+	full := parts == 3 || parts < 0
+
+	// the first version of the range described by a partial version, e.g. 3.2 -> 3.2.0-0
+	floor := v
+	if !full {
+		floor = lowest(v.Major, v.Minor, v.Patch)
+	}
+
+	switch op {
+	case "", "=":
+		if full {
+			return &bound{v, true}, &bound{v, true}, nil
+		}
+		return &bound{floor, true}, &bound{bump(v, parts), false}, nil
+	case ">=":
+		return &bound{floor, true}, nil, nil
+	case ">":
+		if full {
+			return &bound{v, false}, nil, nil
+		}
+		return &bound{bump(v, parts), true}, nil, nil
+	case "<":
+		return nil, &bound{floor, false}, nil
+	case "<=":
+		if full {
+			return nil, &bound{v, true}, nil
+		}
+		return nil, &bound{bump(v, parts), false}, nil
+	case "~":
+		if parts == 1 {
+			return &bound{floor, true}, &bound{bump(v, 1), false}, nil
+		}
+		return &bound{floor, true}, &bound{bump(v, 2), false}, nil
+	}
+
+	// caret allows changes that do not modify the left-most non-zero component
+	switch {
+	case v.Major > 0 || parts == 1:
+		return &bound{floor, true}, &bound{bump(v, 1), false}, nil
+	case v.Minor > 0 || parts == 2:
+		return &bound{floor, true}, &bound{bump(v, 2), false}, nil
+	}
+	return &bound{floor, true}, &bound{bump(v, 3), false}, nil
+}
+
+// parsePartial parses a possibly partial version. It returns the number of components given (0 to 3), or -1 if
+// the version carries a pre-release or build metadata.
+func parsePartial(s string) (int, Version, error) {
+	var v Version
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "v"), "V")
+	if s == "" {
+		return 0, v, errors.New("empty version")
+	}
+
+	extended := false
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		build, err := parseIdentifiers(s[i+1:], false)
+		if err != nil {
+			return 0, v, fmt.Errorf("invalid build metadata in \"%s\": %v", s, err)
+		}
+		v.Build, s, extended = build, s[:i], true
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		pre, err := parseIdentifiers(s[i+1:], true)
+		if err != nil {
+			return 0, v, fmt.Errorf("invalid pre-release in \"%s\": %v", s, err)
+		}
+		v.PreRelease, s, extended = pre, s[:i], true
+	}
+
+	components := strings.Split(s, ".")
+	if len(components) > 3 {
+		return 0, v, fmt.Errorf("invalid version \"%s\"", s)
+	}
+	numbers := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	parts := 0
+	for i, component := range components {
+		if component == "x" || component == "X" || component == "*" {
+			// nothing may follow a wildcard
+			for _, rest := range components[i+1:] {
+				if rest != "x" && rest != "X" && rest != "*" {
+					return 0, v, fmt.Errorf("invalid version \"%s\"", s)
+				}
+			}
+			break
+		}
+		n, err := parseNumber(component)
+		if err != nil {
+			return 0, v, fmt.Errorf("invalid version \"%s\": %v", s, err)
+		}
+		*numbers[i] = n
+		parts++
+	}
+
+	if extended {
+		if parts != 3 {
+			return 0, v, fmt.Errorf("invalid version \"%s\": pre-release and build require a full version", s)
+		}
+		return -1, v, nil
+	}
+	return parts, v, nil
+}
+
+// parseIdentifiers parses dot separated pre-release or build identifiers
+func parseIdentifiers(s string, numeric bool) ([]string, error) {
+	ids := strings.Split(s, ".")
+	for _, id := range ids {
+		if id == "" {
+			return nil, errors.New("empty identifier")
+		}
+		for _, r := range id {
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+				return nil, fmt.Errorf("invalid character in \"%s\"", id)
+			}
+		}
+		if numeric && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return nil, fmt.Errorf("leading zero in \"%s\"", id)
+		}
+	}
+	return ids, nil
+}
+
+// parseNumber parses a version component without leading zeros
+func parseNumber(s string) (uint64, error) {
+	if s == "" || !isNumeric(s) {
+		return 0, fmt.Errorf("\"%s\" is not a number", s)
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("leading zero in \"%s\"", s)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// lowest returns the lowest possible version with the given components, i.e. its first pre-release
+func lowest(major, minor, patch uint64) Version {
+	return Version{Major: major, Minor: minor, Patch: patch, PreRelease: []string{"0"}}
+}
+
+// bump returns the lowest version above every version sharing the first n components of v
+func bump(v Version, n int) Version {
+	switch n {
+	case 1:
+		return lowest(v.Major+1, 0, 0)
+	case 2:
+		return lowest(v.Major, v.Minor+1, 0)
+	}
+	return lowest(v.Major, v.Minor, v.Patch+1)
+}
+
+func tighterLower(a, b *bound) *bound {
+	if a == nil {
+		return b
+	}
+	if b == nil || compareLower(a, b) >= 0 {
+		return a
+	}
+	return b
+}
+
+func tighterUpper(a, b *bound) *bound {
+	if a == nil {
+		return b
+	}
+	if b == nil || compareUpper(a, b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// compareLower returns a positive number if lower bound a is tighter than b
+func compareLower(a, b *bound) int {
+	if cmp := a.version.Compare(b.version); cmp != 0 {
+		return cmp
+	}
+	return compareBool(!a.inclusive, !b.inclusive)
+}
+
+// compareUpper returns a positive number if upper bound a is tighter than b
+func compareUpper(a, b *bound) int {
+	if cmp := b.version.Compare(a.version); cmp != 0 {
+		return cmp
+	}
+	return compareBool(!a.inclusive, !b.inclusive)
+}
+
+// compareEnd compares the bounds of an end of two ranges with the given comparison, a missing bound being the
+// loosest
+func compareEnd(a, b *bound, compare func(a, b *bound) int) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return compare(a, b)
+}
+
+// width returns the distance between two versions, component by component, e.g. 0.1.-1 from 3.2.1 to 3.3.0
+func width(from, to Version) [3]int64 {
+	return [3]int64{int64(to.Major) - int64(from.Major), int64(to.Minor) - int64(from.Minor),
+		int64(to.Patch) - int64(from.Patch)}
+}
+
+// compareSpan compares the distances between versions, the most significant component first
+func compareSpan(a, b [3]int64) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// compareIdentifier compares pre-release identifiers: numeric ones compare numerically and always have a lower
+// precedence than alphanumeric ones
+func compareIdentifier(a, b string) int {
+	an, bn := isNumeric(a), isNumeric(b)
+	switch {
+	case an && bn:
+		if cmp := compareInt(len(a), len(b)); cmp != 0 {
+			return cmp
+		}
+		return strings.Compare(a, b)
+	case an:
+		return -1
+	case bn:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func isOperator(s string) bool {
+	switch s {
+	case ">=", "<=", ">", "<", "=", "~", "^":
+		return true
+	}
+	return false
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareInt(a, b int) int {
+	return compareUint(uint64(a), uint64(b))
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
@@ -0,0 +1,166 @@
+package model_test
+
+import (
+	. "github.com/akhettar/app-features-manager/model"
+	"github.com/akhettar/app-features-manager/test"
+	"testing"
+)
+
+func TestVersion_Compare(t *testing.T) {
+
+	t.Logf("Given versions ordered by semver precedence")
+	{
+		ordered := []string{"1.0.0-0", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+			"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.2", "2.0.0", "10.0.0"}
+		t.Logf("\tWhen comparing each version with the next one")
+		{
+			for i := 0; i < len(ordered)-1; i++ {
+				a, b := mustParseVersion(ordered[i], t), mustParseVersion(ordered[i+1], t)
+				if a.Compare(b) < 0 && b.Compare(a) > 0 {
+					t.Logf("\t\t%s should precede %s %v", ordered[i], ordered[i+1], test.CheckMark)
+				} else {
+					t.Errorf("\t\t%s should precede %s %v", ordered[i], ordered[i+1], test.BallotX)
+				}
+			}
+		}
+
+		t.Logf("\tWhen comparing versions which only differ by build metadata")
+		{
+			a, b := mustParseVersion("1.0.0+20130313144700", t), mustParseVersion("1.0.0+exp.sha.5114f85", t)
+			if a.Compare(b) == 0 {
+				t.Logf("\t\tThe versions should have the same precedence %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe versions should have the same precedence %v", test.BallotX)
+			}
+		}
+	}
+}
+
+func TestParseVersion_Invalid(t *testing.T) {
+
+	t.Logf("Given invalid versions")
+	{
+		for _, v := range []string{"", "1.x", "1.0.0.0", "01.0.0", "1.0.0-", "1.0.0-01", "1.0-beta", "a.b.c"} {
+			t.Logf("\tWhen parsing the version \"%s\"", v)
+			{
+				if _, err := ParseVersion(v); err != nil {
+					t.Logf("\t\tThe parsing should have failed: %v %v", err, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe parsing should have failed %v", test.BallotX)
+				}
+			}
+		}
+	}
+}
+
+func TestConstraint_Check(t *testing.T) {
+
+	tests := []struct {
+		constraint string
+		matching   []string
+		others     []string
+	}{
+		{"<3.2.0", []string{"3.1.9", "3.2.0-beta", "0.1"}, []string{"3.2.0", "3.2.1"}},
+		{"3.2.x", []string{"3.2.0-beta", "3.2.0", "3.2.9+build.1"}, []string{"3.1.9", "3.3.0-alpha", "3.3.0"}},
+		{">=1.0.0 <2.0.0", []string{"1.0.0", "1.9.9"}, []string{"1.0.0-rc.1", "2.0.0"}},
+		{">= 1.0, < 2", []string{"1.0.0-beta", "1.5.0"}, []string{"0.9.9", "2.0.0-rc.1"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.2.2", "1.3.0"}},
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"1.2.2", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0"}},
+		{"1.2.3-beta.1", []string{"1.2.3-beta.1+build"}, []string{"1.2.3", "1.2.3-beta.2"}},
+		{"*", []string{"0.0.1", "99.0.0"}, nil},
+	}
+
+	for _, tc := range tests {
+		t.Logf("Given the version range \"%s\"", tc.constraint)
+		{
+			c, err := ParseConstraint(tc.constraint)
+			if err != nil {
+				t.Fatalf("\t\tThe range should be valid: %v %v", err, test.BallotX)
+			}
+			t.Logf("\tWhen checking versions within the range")
+			{
+				for _, v := range tc.matching {
+					if c.Check(mustParseVersion(v, t)) {
+						t.Logf("\t\t%s should satisfy the range %v", v, test.CheckMark)
+					} else {
+						t.Errorf("\t\t%s should satisfy the range %v", v, test.BallotX)
+					}
+				}
+			}
+			t.Logf("\tWhen checking versions outside of the range")
+			{
+				for _, v := range tc.others {
+					if !c.Check(mustParseVersion(v, t)) {
+						t.Logf("\t\t%s should not satisfy the range %v", v, test.CheckMark)
+					} else {
+						t.Errorf("\t\t%s should not satisfy the range %v", v, test.BallotX)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestConstraint_CompareSpecificity(t *testing.T) {
+
+	t.Logf("Given version ranges ordered from the most to the least specific")
+	{
+		ordered := []string{"3.2.1", "3.2.x", "<3.2.0", ">=3.0.0 <4.0.0", ">=3.0.0 <5.0.0", "<4.0.0", "*"}
+		t.Logf("\tWhen comparing each range with the next one")
+		{
+			for i := 0; i < len(ordered)-1; i++ {
+				a, _ := ParseConstraint(ordered[i])
+				b, _ := ParseConstraint(ordered[i+1])
+				if a.CompareSpecificity(b) > 0 && b.CompareSpecificity(a) < 0 {
+					t.Logf("\t\t\"%s\" should be more specific than \"%s\" %v", ordered[i], ordered[i+1], test.CheckMark)
+				} else {
+					t.Errorf("\t\t\"%s\" should be more specific than \"%s\" %v", ordered[i], ordered[i+1], test.BallotX)
+				}
+			}
+		}
+	}
+
+	t.Logf("Given broad version ranges overlapping a narrower one")
+	{
+		pairs := [][2]string{{"3.2.x", "^3.2.1"}, {"3.2.x", ">=3.2.1"}, {"~3.2.1", "^3.2.1"}, {"3.2.x", ">=3.0.0 <4.0.0"}}
+		t.Logf("\tWhen comparing the narrower range with the broad ones")
+		{
+			for _, pair := range pairs {
+				a, _ := ParseConstraint(pair[0])
+				b, _ := ParseConstraint(pair[1])
+				if a.CompareSpecificity(b) > 0 && b.CompareSpecificity(a) < 0 {
+					t.Logf("\t\t\"%s\" should be more specific than \"%s\" %v", pair[0], pair[1], test.CheckMark)
+				} else {
+					t.Errorf("\t\t\"%s\" should be more specific than \"%s\" %v", pair[0], pair[1], test.BallotX)
+				}
+			}
+		}
+	}
+}
+
+func TestParseConstraint_Invalid(t *testing.T) {
+
+	t.Logf("Given invalid version ranges")
+	{
+		for _, c := range []string{"", "<", ">=abc", ">2.0.0 <1.0.0", "<*", "1.x.2"} {
+			t.Logf("\tWhen parsing the range \"%s\"", c)
+			{
+				if _, err := ParseConstraint(c); err != nil {
+					t.Logf("\t\tThe parsing should have failed: %v %v", err, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe parsing should have failed %v", test.BallotX)
+				}
+			}
+		}
+	}
+}
+
+// Helper function
+func mustParseVersion(s string, t *testing.T) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		t.Fatalf("\t\tThe version \"%s\" should be valid: %v %v", s, err, test.BallotX)
+	}
+	return v
+}
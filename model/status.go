@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
+	"regexp"
 	"time"
 )
 
@@ -13,14 +14,29 @@ const (
 
 	// AppPlatform the app platform
 	AppPlatform = "platform"
+
+	// AppRange the version range of a release policy
+	AppRange = "range"
+
+	// AppID the identifier of the app, i.e. its bundle ID or package name
+	AppID = "appId"
+
+	// FlagName the name of a flag evaluated by the service
+	FlagName = "flag"
 )
 
+// appPattern matches the app identifiers, e.g. the bundle ID "com.acme.banking" or the package name "com.acme.banking_beta"
+var appPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,254}$`)
+
 // Status hold the status of the released version
 type Status string
 
 // Platform mobile platform
 type Platform string
 
+// App the identifier of a mobile app
+type App string
+
 const (
 	Latest      Status = "latest"
 	Deprecated         = "deprecated"
@@ -51,20 +67,42 @@ func (p Platform) Value() (string, error) {
 	return "", errors.New(fmt.Sprintf("Supported values:%s,%s,%s,%s", Ios, Android, Windows, Blackberry))
 }
 
-// ReleaseDAO instance of the app status to be stored in the data store
+// Value returns the app identifier or an error if it is not a valid bundle ID or package name
+func (a App) Value() (string, error) {
+	if appPattern.MatchString(string(a)) {
+		return string(a), nil
+	}
+	return "", errors.New(fmt.Sprintf("Invalid app identifier:%s", a))
+}
+
+// ReleaseDAO instance of the app status to be stored in the data store. A release either targets a single
+// version or, when Range is set, every version matching the semantic version range. The status it was published
+// with is superseded over time by the transitions of its schedule, sorted by effective time.
 type ReleaseDAO struct {
-	Version  string    `bson:"version"`
-	Status   string    `bson:"status"`
-	Platform string    `bson:"platform"`
-	Released time.Time `bson:"released"`
+	Version  string       `bson:"version"`
+	Range    string       `bson:"range,omitempty"`
+	Status   string       `bson:"status"`
+	Platform string       `bson:"platform"`
+	Released time.Time    `bson:"released"`
+	Schedule []Transition `bson:"schedule,omitempty"`
+	App      string       `bson:"app,omitempty"`
+
+	Messaging `bson:",inline"`
 }
 
-// ReleaseRequest is the payload to releasing the app version
+// ReleaseRequest is the payload to releasing the app version. Either a version or a version range
+// (e.g. "<3.2.0", "3.2.x", ">=1.0.0 <2.0.0") must be given. The optional schedule lists the future transitions of
+// the status, e.g. deprecated on 2026-11-01 then unsupported on 2027-01-15. The store link and the upgrade messages
+// per locale are returned to the clients querying the status of the release.
 type (
 	ReleaseRequest struct {
-		Version  string `json:"version" validate:"required"`
-		Platform string `json:"platform" validate:"required"`
-		Status   string `json:"status"`
+		Messaging
+		Version  string       `json:"version" validate:"required_without=Range"`
+		Range    string       `json:"range" validate:"required_without=Version"`
+		Platform string       `json:"platform" validate:"required"`
+		Status   string       `json:"status"`
+		Schedule []Transition `json:"schedule,omitempty"`
+		Reason   string       `json:"reason,omitempty"`
 	}
 
 	ReleaseRequestValidator struct {
@@ -72,6 +110,7 @@ type (
 	}
 )
 
+// Validate validates the given request
 func (cv *ReleaseRequestValidator) Validate(i interface{}) error {
 	return cv.Validator.Struct(i)
 }
@@ -89,19 +128,143 @@ func ReleaseRequestStructLevelValidation(sl validator.StructLevel) {
 	if _, e := Platform(req.Platform).Value(); e != nil {
 		sl.ReportError(req.Platform, "platform", "Platform", "", "")
 	}
+	if req.Version != "" && req.Range != "" {
+		sl.ReportError(req.Range, "range", "Range", "excluded_with", "version")
+	}
+	if req.Range != "" {
+		if _, e := ParseConstraint(req.Range); e != nil {
+			sl.ReportError(req.Range, "range", "Range", "semver", "")
+		}
+	}
+	validateSchedule(sl, req.Schedule)
+	validateMessaging(sl, req.Messaging)
+}
+
+// Thresholds the minimum supported, minimum recommended and latest versions of a platform
+type Thresholds struct {
+	MinSupported   string `json:"minSupported,omitempty" bson:"minSupported,omitempty"`
+	MinRecommended string `json:"minRecommended,omitempty" bson:"minRecommended,omitempty"`
+	Latest         string `json:"latest,omitempty" bson:"latest,omitempty"`
+}
+
+// Status derives the status of the given version from the thresholds: versions below the minimum supported one are
+// unsupported, versions below the minimum recommended one are deprecated, and versions from the latest one onward
+// are the latest. Any other version is supported. Undeclared or invalid thresholds are ignored.
+func (t Thresholds) Status(version Version) string {
+	if compareThreshold(version, t.MinSupported) < 0 {
+		return Unsupported
+	}
+	if compareThreshold(version, t.MinRecommended) < 0 {
+		return Deprecated
+	}
+	if t.Latest != "" && compareThreshold(version, t.Latest) >= 0 {
+		return string(Latest)
+	}
+	return Supported
+}
+
+// ThresholdsDAO the version thresholds declared for a platform to be stored in the data store. The status of any
+// version without an explicit release is derived from the latest thresholds of its platform.
+type ThresholdsDAO struct {
+	Thresholds `bson:",inline"`
+	Messaging  `bson:",inline"`
+	Platform   string    `bson:"platform"`
+	Released   time.Time `bson:"released"`
+	App        string    `bson:"app,omitempty"`
+}
+
+// ThresholdsRequest is the payload to declaring the version thresholds of a platform, along with the store link and
+// the upgrade messages returned for the versions whose status derives from them
+type ThresholdsRequest struct {
+	Thresholds
+	Messaging
+	Platform string `json:"platform" validate:"required"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ThresholdsRequestStructLevelValidation validates the thresholds are valid versions in ascending order
+func ThresholdsRequestStructLevelValidation(sl validator.StructLevel) {
+
+	req := sl.Current().Interface().(ThresholdsRequest)
+
+	if _, e := Platform(req.Platform).Value(); e != nil {
+		sl.ReportError(req.Platform, "platform", "Platform", "", "")
+	}
+	if req.MinSupported == "" && req.MinRecommended == "" && req.Latest == "" {
+		sl.ReportError(req.Latest, "latest", "Latest", "required_without_all", "")
+	}
+
+	// each declared threshold must be a valid version not lower than the previous ones
+	var previous *Version
+	thresholds := []struct{ value, field string }{
+		{req.MinSupported, "minSupported"}, {req.MinRecommended, "minRecommended"}, {req.Latest, "latest"},
+	}
+	for _, threshold := range thresholds {
+		if threshold.value == "" {
+			continue
+		}
+		v, e := ParseVersion(threshold.value)
+		if e != nil {
+			sl.ReportError(threshold.value, threshold.field, threshold.field, "semver", "")
+			continue
+		}
+		if previous != nil && v.Compare(*previous) < 0 {
+			sl.ReportError(threshold.value, threshold.field, threshold.field, "gtefield", "")
+		}
+		previous = &v
+	}
+	validateMessaging(sl, req.Messaging)
 }
 
-// ReleaseResponse is the query app status response
+// ReleaseResponse is the query app status response. The message is the one best matching the languages accepted by
+// the client among the messages published with the status. The toggles, holding the variants of the flags, are only
+// returned to the clients opting into them. The flags are stale when evaluated from the last toggles known or from
+// their defaults rather than from live data, e.g. while unleash is unreachable. The flags which could not be evaluated
+// in time are missing from the flags, the reason being given by name in the flag errors. The reasons the flags were
+// forced off, e.g. as a prerequisite is disabled, are only returned to the clients debugging them.
 type ReleaseResponse struct {
-	Status string          `json:"status"`
-	Flags  map[string]bool `json:"flags"`
+	Status     string            `json:"status"`
+	StoreURL   string            `json:"storeUrl,omitempty"`
+	Locale     string            `json:"locale,omitempty"`
+	Message    *Message          `json:"message,omitempty"`
+	Flags      map[string]bool   `json:"flags"`
+	Toggles    map[string]Toggle `json:"toggles,omitempty"`
+	Stale      bool              `json:"stale,omitempty"`
+	FlagErrors map[string]string `json:"flagErrors,omitempty"`
+	Reasons    map[string]string `json:"reasons,omitempty"`
+	Messaging  Messaging         `json:"-"`
+
+	// Until the time the status holds until, i.e. the next transition scheduled for the release, zero when none is
+	Until time.Time `json:"-"`
+}
+
+// Localize sets the store link and the message best matching the given Accept-Language header
+func (r *ReleaseResponse) Localize(acceptLanguage string) {
+	r.StoreURL = r.Messaging.StoreURL
+	if message, locale, ok := r.Messaging.Localize(acceptLanguage); ok {
+		r.Message, r.Locale = &message, locale
+	}
 }
 
 // ErrorResponse a generic error response
 type ErrorResponse struct {
 	Message string `json:"message"`
-	Code    int    `json:code`
+
+	// Code the HTTP status of the response
+	Code int `json:"code"`
+
+	// Error the machine-readable code of the error, e.g. "not_found"
+	Error string `json:"error,omitempty"`
 }
 
 // EmptyBody for version not found
 type EmptyBody struct{}
+
+// compareThreshold compares the version with the given threshold, returning zero if the threshold is not set
+func compareThreshold(version Version, threshold string) int {
+	t, err := ParseVersion(threshold)
+	if err != nil {
+		return 0
+	}
+	return version.Compare(t)
+}
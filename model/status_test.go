@@ -0,0 +1,39 @@
+package model_test
+
+import (
+	. "github.com/akhettar/app-features-manager/model"
+	"github.com/akhettar/app-features-manager/test"
+	"testing"
+)
+
+func TestThresholds_Status(t *testing.T) {
+
+	tests := []struct {
+		thresholds Thresholds
+		expected   map[string]string
+	}{
+		{Thresholds{MinSupported: "3.0.0", MinRecommended: "3.2.0", Latest: "4.1.0"},
+			map[string]string{"2.9.9": Unsupported, "3.0.0-rc.1": Unsupported, "3.1.0": Deprecated, "3.2.0": Supported,
+				"4.1.0": string(Latest), "4.2.0-beta": string(Latest)}},
+		{Thresholds{MinSupported: "3.0.0"},
+			map[string]string{"2.0.0": Unsupported, "3.0.0": Supported, "9.0.0": Supported}},
+		{Thresholds{Latest: "4.1.0"},
+			map[string]string{"1.0.0": Supported, "4.1.0": string(Latest)}},
+	}
+
+	for _, tc := range tests {
+		t.Logf("Given the version thresholds %+v", tc.thresholds)
+		{
+			for version, expected := range tc.expected {
+				t.Logf("\tWhen deriving the status of the version \"%s\"", version)
+				{
+					if status := tc.thresholds.Status(mustParseVersion(version, t)); status == expected {
+						t.Logf("\t\tThe status should be %s %v", expected, test.CheckMark)
+					} else {
+						t.Errorf("\t\tThe status should be %s but was %s %v", expected, status, test.BallotX)
+					}
+				}
+			}
+		}
+	}
+}
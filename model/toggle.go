@@ -0,0 +1,22 @@
+package model
+
+// Toggle the evaluation of a feature flag for a customer: whether it is enabled and the variant the customer is
+// assigned to, if the flag has variants. The reason tells why the flag was forced off, e.g. a prerequisite disabled,
+// and is only returned in the debug responses.
+type Toggle struct {
+	Enabled bool     `json:"enabled"`
+	Variant *Variant `json:"variant,omitempty"`
+	Reason  string   `json:"-"`
+}
+
+// Variant a variant of a feature flag, e.g. a branch of an experiment, with its optional payload
+type Variant struct {
+	Name    string   `json:"name"`
+	Payload *Payload `json:"payload,omitempty"`
+}
+
+// Payload the payload of a variant, whose value is typed "string" or "json"
+type Payload struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
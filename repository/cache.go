@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"github.com/akhettar/app-features-manager/model"
+	"sync"
+	"time"
+)
+
+const (
+
+	// CacheTTL environment variable setting the time the statuses are cached for, e.g. "10s", the cache being
+	// disabled when zero
+	CacheTTL = "CACHE_TTL"
+
+	// NegativeCacheTTL environment variable setting the time the versions found in no release are cached for
+	NegativeCacheTTL = "CACHE_NEGATIVE_TTL"
+
+	// CacheSize environment variable setting the maximum number of statuses cached
+	CacheSize = "CACHE_SIZE"
+
+	// DefaultCacheTTL the default time the statuses are cached for
+	DefaultCacheTTL = 10 * time.Second
+
+	// DefaultNegativeCacheTTL the default time the versions found in no release are cached for
+	DefaultNegativeCacheTTL = 5 * time.Second
+
+	// DefaultCacheSize the default maximum number of statuses cached
+	DefaultCacheSize = 10000
+)
+
+// CacheConfig the bounds of the cache of the statuses
+type CacheConfig struct {
+
+	// TTL the time the statuses are cached for
+	TTL time.Duration
+
+	// NegativeTTL the time the versions found in no release are cached for
+	NegativeTTL time.Duration
+
+	// Size the maximum number of statuses cached, the least recently used being evicted first
+	Size int
+}
+
+// CachingRepository a repository caching the statuses queried through Find in front of another repository. The
+// concurrent queries of a status missing from the cache are sent once to the data store, and the versions found in
+// no release are cached as well. The writes through the repository invalidate the statuses of the platform they
+// change, but the writes of other replicas are only seen once the cached statuses expire, unless a Watcher refreshes
+// the repository. A status is never cached past the next transition scheduled for its release.
+type CachingRepository struct {
+	Repository
+	cache *statusCache
+
+	// App the identifier of the app the data is scoped to, the default app when empty
+	App string
+}
+
+// cacheKey the key of a cached status
+type cacheKey struct {
+	app, platform, version string
+}
+
+// cacheEntry a cached status, or ErrNotFound, along with the time it expires
+type cacheEntry struct {
+	key      cacheKey
+	response model.ReleaseResponse
+	err      error
+	expires  time.Time
+}
+
+// flight a query of the data store the concurrent misses of the same status wait for
+type flight struct {
+	done     chan struct{}
+	response model.ReleaseResponse
+	err      error
+}
+
+// statusCache the cache shared by the views of the repository scoped to each app
+type statusCache struct {
+	sync.Mutex
+	CacheConfig
+	entries map[cacheKey]*list.Element
+	lru     *list.List
+	flights map[cacheKey]*flight
+
+	// epoch the number of invalidations, the queries sent before an invalidation not being cached
+	epoch uint64
+	stats model.CacheStats
+}
+
+// NewCachingRepository creates a repository caching the statuses queried from the given one
+func NewCachingRepository(repo Repository, config CacheConfig) *CachingRepository {
+	return &CachingRepository{Repository: repo, cache: &statusCache{CacheConfig: config,
+		entries: make(map[cacheKey]*list.Element), lru: list.New(), flights: make(map[cacheKey]*flight)}}
+}
+
+// ForApp returns a view of the repository scoped to the data of the given app, sharing the cache
+func (repo *CachingRepository) ForApp(app string) Repository {
+	return &CachingRepository{Repository: repo.Repository.ForApp(app), cache: repo.cache, App: app}
+}
+
+// Find query the status of the app for given version from the cache, falling back to the data store
+func (repo *CachingRepository) Find(ctx context.Context, version, platform string) (model.ReleaseResponse, error) {
+	key := cacheKey{app: repo.App, platform: platform, version: version}
+	return repo.cache.get(ctx, key, func(ctx context.Context) (model.ReleaseResponse, error) {
+		return repo.Repository.Find(ctx, version, platform)
+	})
+}
+
+// Insert stores a new state of a release and invalidates the cached statuses of its platform
+func (repo *CachingRepository) Insert(ctx context.Context, body interface{}) error {
+	err := repo.Repository.Insert(ctx, body)
+	switch release := body.(type) {
+	case model.ReleaseDAO:
+		repo.cache.invalidate(repo.App, release.Platform)
+	case *model.ReleaseDAO:
+		repo.cache.invalidate(repo.App, release.Platform)
+	default:
+		repo.cache.invalidate(repo.App, "")
+	}
+	return err
+}
+
+// InsertThresholds stores new version thresholds and invalidates the cached statuses of their platform
+func (repo *CachingRepository) InsertThresholds(ctx context.Context, thresholds model.ThresholdsDAO) error {
+	err := repo.Repository.InsertThresholds(ctx, thresholds)
+	repo.cache.invalidate(repo.App, thresholds.Platform)
+	return err
+}
+
+// Delete removes the given release and invalidates the cached statuses of its platform
+func (repo *CachingRepository) Delete(ctx context.Context, key model.ReleaseKey) error {
+	err := repo.Repository.Delete(ctx, key)
+	repo.cache.invalidate(repo.App, key.Platform)
+	return err
+}
+
+// Refresh invalidates the cached statuses the given change may have changed, e.g. as notified by a Watcher of the
+// writes of the other replicas. The changes of the flag definitions change no status.
+func (repo *CachingRepository) Refresh(event ChangeEvent) {
+	switch {
+	case event.All:
+		repo.cache.invalidateAll()
+	case !event.Flags:
+		repo.cache.invalidate(event.App, event.Platform)
+	}
+}
+
+// Stats returns the statistics of the cache
+func (repo *CachingRepository) Stats() model.CacheStats {
+	repo.cache.Lock()
+	defer repo.cache.Unlock()
+	stats := repo.cache.stats
+	stats.Entries = repo.cache.lru.Len()
+	if total := stats.Hits + stats.Misses + stats.Shared; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// get returns the cached status of the given key, loading it when missing or expired. The concurrent misses of the
+// same status wait for the first one to load it, each until its own context is done, and load it again themselves
+// when the client of the first one cancelled it.
+func (c *statusCache) get(ctx context.Context, key cacheKey,
+	load func(ctx context.Context) (model.ReleaseResponse, error)) (model.ReleaseResponse, error) {
+
+	for {
+		c.Lock()
+		if element, ok := c.entries[key]; ok {
+			entry := element.Value.(*cacheEntry)
+			if time.Now().Before(entry.expires) {
+				c.lru.MoveToFront(element)
+				c.stats.Hits++
+				c.Unlock()
+				return entry.response, entry.err
+			}
+			c.remove(element)
+		}
+		f, ok := c.flights[key]
+		if !ok {
+			break
+		}
+		c.stats.Shared++
+		c.Unlock()
+		select {
+		case <-f.done:
+			if !cancelled(f.err) || ctx.Err() != nil {
+				return f.response, f.err
+			}
+		case <-ctx.Done():
+			return model.ReleaseResponse{}, &UnavailableError{Op: "find", Err: ctx.Err()}
+		}
+	}
+	f := &flight{done: make(chan struct{})}
+	c.flights[key] = f
+	c.stats.Misses++
+	epoch := c.epoch
+	c.Unlock()
+
+	f.response, f.err = load(ctx)
+
+	c.Lock()
+	delete(c.flights, key)
+	if epoch == c.epoch {
+		c.store(key, f.response, f.err)
+	}
+	c.Unlock()
+	close(f.done)
+	return f.response, f.err
+}
+
+// cancelled tells whether the given error is the cancellation of the operation by its client
+func cancelled(err error) bool {
+	var unavailable *UnavailableError
+	return errors.As(err, &unavailable) && !unavailable.Timeout()
+}
+
+// store caches the status of the given key, or the given error when not found, evicting the least recently used
+// statuses beyond the size of the cache
+func (c *statusCache) store(key cacheKey, response model.ReleaseResponse, err error) {
+	now := time.Now()
+	var expires time.Time
+	switch {
+	case err == nil:
+		expires = now.Add(c.TTL)
+		if !response.Until.IsZero() && response.Until.Before(expires) {
+			expires = response.Until
+		}
+	case errors.Is(err, ErrNotFound):
+		expires = now.Add(c.NegativeTTL)
+	default:
+		return
+	}
+	if !expires.After(now) || c.Size <= 0 {
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, response: response, err: err, expires: expires})
+	for c.lru.Len() > c.Size {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// invalidate drops the cached statuses of the given platform of the given app, or of every platform when none is
+// given, and keeps the queries in flight from being cached
+func (c *statusCache) invalidate(app, platform string) {
+	c.Lock()
+	defer c.Unlock()
+	c.epoch++
+	c.stats.Invalidations++
+	for key, element := range c.entries {
+		if key.app == app && (platform == "" || key.platform == platform) {
+			c.remove(element)
+		}
+	}
+}
+
+// invalidateAll drops every cached status and keeps the queries in flight from being cached
+func (c *statusCache) invalidateAll() {
+	c.Lock()
+	defer c.Unlock()
+	c.epoch++
+	c.stats.Invalidations++
+	c.entries = make(map[cacheKey]*list.Element)
+	c.lru.Init()
+}
+
+// remove drops the given entry from the cache
+func (c *statusCache) remove(element *list.Element) {
+	delete(c.entries, element.Value.(*cacheEntry).key)
+	c.lru.Remove(element)
+}
@@ -0,0 +1,199 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/akhettar/app-features-manager/model"
+	. "github.com/akhettar/app-features-manager/repository"
+	"github.com/akhettar/app-features-manager/test"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingRepository a repository counting the status queries it serves, each waiting for the gate when set
+type countingRepository struct {
+	Repository
+	finds int32
+	gate  chan struct{}
+}
+
+func (repo *countingRepository) Find(ctx context.Context, version, platform string) (model.ReleaseResponse, error) {
+	atomic.AddInt32(&repo.finds, 1)
+	if repo.gate != nil {
+		<-repo.gate
+	}
+	return repo.Repository.Find(ctx, version, platform)
+}
+
+// TestCachingRepository_ShouldServeTheStatusesFromTheCache checks the statuses are cached until a write invalidates them
+func TestCachingRepository_ShouldServeTheStatusesFromTheCache(t *testing.T) {
+	counting := &countingRepository{Repository: NewMemoryRepository()}
+	repo := NewCachingRepository(counting, CacheConfig{TTL: time.Minute, NegativeTTL: time.Minute, Size: 100})
+
+	t.Logf("Given a release was published")
+	{
+		mustInsert(repo, model.ReleaseDAO{Version: "1.0.0", Platform: "ios", Status: model.Supported, Released: time.Now()}, t)
+
+		t.Logf("\tWhen querying its status and an unknown version twice")
+		{
+			for i := 0; i < 2; i++ {
+				expectCachedStatus(repo, "1.0.0", "ios", model.Supported, t)
+				expectCachedStatus(repo, "9.0.0", "ios", "", t)
+			}
+			expectFinds(counting, 2, t)
+			stats := repo.Stats()
+			if stats.Hits == 2 && stats.Misses == 2 && stats.Entries == 2 && stats.HitRatio == 0.5 {
+				t.Logf("\t\tThe statistics should count 2 hits and 2 misses %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe statistics should count 2 hits and 2 misses %+v %v", stats, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen publishing a new state of the release")
+		{
+			mustInsert(repo, model.ReleaseDAO{Version: "1.0.0", Platform: "ios", Status: model.Deprecated, Released: time.Now()}, t)
+			expectCachedStatus(repo, "1.0.0", "ios", model.Deprecated, t)
+			expectFinds(counting, 3, t)
+		}
+
+		t.Logf("\tWhen querying the status for another app")
+		{
+			expectCachedStatus(repo.ForApp("com.acme.other"), "1.0.0", "ios", "", t)
+		}
+	}
+}
+
+// TestCachingRepository_ShouldBoundTheCache checks the statuses expire and the least recently used are evicted
+func TestCachingRepository_ShouldBoundTheCache(t *testing.T) {
+	counting := &countingRepository{Repository: NewMemoryRepository()}
+	repo := NewCachingRepository(counting, CacheConfig{TTL: time.Minute, NegativeTTL: time.Millisecond, Size: 2})
+
+	t.Logf("Given releases of three versions, one of them scheduled to be deprecated shortly")
+	{
+		mustInsert(repo, model.ReleaseDAO{Version: "1.0.0", Platform: "ios", Status: model.Supported, Released: time.Now()}, t)
+		mustInsert(repo, model.ReleaseDAO{Version: "2.0.0", Platform: "ios", Status: model.Supported, Released: time.Now()}, t)
+		mustInsert(repo, model.ReleaseDAO{Version: "3.0.0", Platform: "ios", Status: model.Supported, Released: time.Now(),
+			Schedule: []model.Transition{{Status: model.Deprecated, Effective: time.Now().Add(200 * time.Millisecond)}}}, t)
+
+		t.Logf("\tWhen querying the statuses of more versions than the cache holds")
+		{
+			expectCachedStatus(repo, "1.0.0", "ios", model.Supported, t)
+			expectCachedStatus(repo, "2.0.0", "ios", model.Supported, t)
+			expectCachedStatus(repo, "1.0.0", "ios", model.Supported, t)
+			expectCachedStatus(repo, "3.0.0", "ios", model.Supported, t)
+			expectCachedStatus(repo, "1.0.0", "ios", model.Supported, t)
+			expectFinds(counting, 3, t)
+			expectCachedStatus(repo, "2.0.0", "ios", model.Supported, t)
+			expectFinds(counting, 4, t)
+			if stats := repo.Stats(); stats.Evictions == 2 && stats.Entries == 2 {
+				t.Logf("\t\tThe least recently used statuses should have been evicted %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe least recently used statuses should have been evicted %+v %v", stats, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen querying the statuses once expired")
+		{
+			expectCachedStatus(repo, "3.0.0", "ios", model.Supported, t)
+			expectCachedStatus(repo, "9.0.0", "ios", "", t)
+			time.Sleep(250 * time.Millisecond)
+			expectCachedStatus(repo, "3.0.0", "ios", model.Deprecated, t)
+			expectCachedStatus(repo, "9.0.0", "ios", "", t)
+			expectFinds(counting, 8, t)
+		}
+	}
+}
+
+// TestCachingRepository_ShouldQueryTheMissesOnce checks the concurrent queries of a status missing from the cache are
+// sent once to the data store
+func TestCachingRepository_ShouldQueryTheMissesOnce(t *testing.T) {
+	counting := &countingRepository{Repository: NewMemoryRepository(), gate: make(chan struct{})}
+	repo := NewCachingRepository(counting, CacheConfig{TTL: time.Minute, NegativeTTL: time.Minute, Size: 100})
+
+	t.Logf("Given a release was published")
+	{
+		mustInsert(repo, model.ReleaseDAO{Version: "1.0.0", Platform: "ios", Status: model.Supported, Released: time.Now()}, t)
+
+		t.Logf("\tWhen querying its status concurrently")
+		{
+			var wg sync.WaitGroup
+			statuses := make([]string, 10)
+			for i := range statuses {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					result, err := repo.Find(context.Background(), "1.0.0", "ios")
+					if err == nil {
+						statuses[i] = result.Status
+					}
+				}(i)
+			}
+			for repo.Stats().Misses+repo.Stats().Shared < uint64(len(statuses)) {
+				time.Sleep(time.Millisecond)
+			}
+			close(counting.gate)
+			wg.Wait()
+
+			expectFinds(counting, 1, t)
+			for _, status := range statuses {
+				if status != model.Supported {
+					t.Errorf("\t\tEvery query should have been served %v %v", statuses, test.BallotX)
+					return
+				}
+			}
+			t.Logf("\t\tEvery query should have been served %v", test.CheckMark)
+		}
+	}
+}
+
+// TestCachingRepository_ShouldRefreshTheChangedStatuses checks the changes notified by a watcher invalidate the statuses
+func TestCachingRepository_ShouldRefreshTheChangedStatuses(t *testing.T) {
+	counting := &countingRepository{Repository: NewMemoryRepository()}
+	repo := NewCachingRepository(counting, CacheConfig{TTL: time.Minute, NegativeTTL: time.Minute, Size: 100})
+
+	t.Logf("Given the statuses of two platforms were cached")
+	{
+		expectCachedStatus(repo, "1.0.0", "ios", "", t)
+		expectCachedStatus(repo, "1.0.0", "android", "", t)
+
+		t.Logf("\tWhen another replica changes the releases of a platform")
+		{
+			repo.Refresh(ChangeEvent{Platform: "ios"})
+			expectCachedStatus(repo, "1.0.0", "ios", "", t)
+			expectCachedStatus(repo, "1.0.0", "android", "", t)
+			expectFinds(counting, 3, t)
+		}
+
+		t.Logf("\tWhen another replica deletes a release")
+		{
+			repo.Refresh(ChangeEvent{All: true})
+			expectCachedStatus(repo, "1.0.0", "ios", "", t)
+			expectCachedStatus(repo, "1.0.0", "android", "", t)
+			expectFinds(counting, 5, t)
+		}
+	}
+}
+
+// expectCachedStatus checks the current status of the given version, not found when the expected status is empty
+func expectCachedStatus(repo Repository, version, platform, expected string, t *testing.T) {
+	result, err := repo.Find(context.Background(), version, platform)
+	if expected == "" {
+		expectNotFound(err, t)
+		return
+	}
+	if err == nil && result.Status == expected {
+		t.Logf("\t\tThe status of %s %s should be \"%s\" %v", platform, version, expected, test.CheckMark)
+	} else {
+		t.Errorf("\t\tThe status of %s %s should be \"%s\" %v %v %v", platform, version, expected, result.Status, err, test.BallotX)
+	}
+}
+
+// expectFinds checks the number of status queries the data store served
+func expectFinds(repo *countingRepository, expected int32, t *testing.T) {
+	if finds := atomic.LoadInt32(&repo.finds); finds == expected {
+		t.Logf("\t\tThe data store should have served %d queries %v", expected, test.CheckMark)
+	} else {
+		t.Errorf("\t\tThe data store should have served %d queries, not %d %v", expected, finds, test.BallotX)
+	}
+}
@@ -0,0 +1,253 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/akhettar/app-features-manager/model"
+	. "github.com/akhettar/app-features-manager/repository"
+	"github.com/akhettar/app-features-manager/test"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ConformanceCollection the collection the mongo repository is checked against, kept apart from the other tests
+const ConformanceCollection = "conformance"
+
+// TestRepository_Conformance checks every repository implementation available to the tests honours the same contract
+func TestRepository_Conformance(t *testing.T) {
+	journal := tempJournal(t)
+	defer os.RemoveAll(filepath.Dir(journal))
+	fileRepo, err := NewFileRepository(journal)
+	if err != nil {
+		t.Fatalf("Failed to open the journal %v %v", err, test.BallotX)
+	}
+	defer fileRepo.Close()
+
+	implementations := map[string]Repository{"memory": NewMemoryRepository(), "file": fileRepo,
+		"cached": NewCachingRepository(NewMemoryRepository(), CacheConfig{TTL: time.Minute, NegativeTTL: time.Minute, Size: 100})}
+	if MongoClient != nil {
+		implementations["mongo"] = &MongoRepository{Client: MongoClient,
+			DBInfo: DBInfo{Database: DefaultDBName, Collection: ConformanceCollection}}
+	}
+	for name, repo := range implementations {
+		t.Run(name, func(t *testing.T) {
+			conformance(repo, t)
+		})
+	}
+}
+
+// conformance runs the contract of the repository against the given empty repository
+func conformance(repo Repository, t *testing.T) {
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	t.Logf("Given two states of the release ios 1.0.0 and version range policies for android")
+	{
+		mustInsert(repo, model.ReleaseDAO{Version: "1.0.0", Platform: "ios", Status: "supported", Released: base}, t)
+		mustInsert(repo, &model.ReleaseDAO{Version: "1.0.0", Platform: "ios", Status: "deprecated", Released: base.Add(2 * time.Second)}, t)
+		mustInsert(repo, model.ReleaseDAO{Range: "<2.0.0", Platform: "android", Status: "unsupported", Released: base}, t)
+		mustInsert(repo, model.ReleaseDAO{Range: "1.x", Platform: "android", Status: "deprecated", Released: base}, t)
+
+		t.Logf("\tWhen querying the status of the versions")
+		{
+			expectStatus(repo, "1.0.0", "ios", time.Now(), "deprecated", t)
+			expectStatus(repo, "1.0.0", "ios", base.Add(time.Second), "supported", t)
+			expectStatus(repo, "1.0.0", "ios", base.Add(-time.Second), "", t)
+			expectStatus(repo, "1.5.0", "android", time.Now(), "deprecated", t)
+			expectStatus(repo, "0.5.0", "android", time.Now(), "unsupported", t)
+			expectStatus(repo, "3.0.0", "android", time.Now(), "", t)
+		}
+
+		t.Logf("\tWhen querying the history of the releases")
+		{
+			history, err := repo.History(context.Background(), model.ReleaseKey{Platform: "ios", Version: "1.0.0"})
+			if err == nil && len(history) == 2 && history[0].Status == "supported" && history[1].Status == "deprecated" {
+				t.Logf("\t\tThe history should be sorted by released date %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe history should be sorted by released date %v %v %v", history, err, test.BallotX)
+			}
+			history, err = repo.History(context.Background(), model.ReleaseKey{Platform: "android", Range: "<2.0.0"})
+			if err == nil && len(history) == 1 {
+				t.Logf("\t\tThe range policies should be addressed by range %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe range policies should be addressed by range %v %v %v", history, err, test.BallotX)
+			}
+			_, err = repo.History(context.Background(), model.ReleaseKey{Platform: "ios", Version: "9.9.9"})
+			expectNotFound(err, t)
+		}
+	}
+
+	t.Logf("Given a narrow version range policy for linux overlapped by broad ones")
+	{
+		mustInsert(repo, model.ReleaseDAO{Range: "3.2.x", Platform: "linux", Status: "unsupported", Released: base}, t)
+		mustInsert(repo, model.ReleaseDAO{Range: "^3.2.1", Platform: "linux", Status: "supported", Released: base}, t)
+		mustInsert(repo, model.ReleaseDAO{Range: ">=3.2.1", Platform: "linux", Status: "latest", Released: base}, t)
+
+		t.Logf("\tWhen querying the status of the versions")
+		{
+			expectStatus(repo, "3.2.5", "linux", time.Now(), "unsupported", t)
+			expectStatus(repo, "3.4.0", "linux", time.Now(), "supported", t)
+			expectStatus(repo, "4.1.0", "linux", time.Now(), "latest", t)
+		}
+	}
+
+	t.Logf("Given version thresholds for windows")
+	{
+		err := repo.InsertThresholds(context.Background(), model.ThresholdsDAO{Platform: "windows", Released: base,
+			Thresholds: model.Thresholds{MinSupported: "2.0.0"}})
+		test.Ok(err, t)
+
+		t.Logf("\tWhen querying the status of a version without release")
+		{
+			expectStatus(repo, "1.0.0", "windows", time.Now(), "unsupported", t)
+			_, err := repo.FindThresholds(context.Background(), "blackberry", time.Now())
+			expectNotFound(err, t)
+		}
+	}
+
+	t.Logf("Given a second ios release and a scheduled one")
+	{
+		mustInsert(repo, model.ReleaseDAO{Version: "1.1.0", Platform: "ios", Status: "supported", Released: base.Add(3 * time.Second)}, t)
+		mustInsert(repo, model.ReleaseDAO{Version: "1.2.0", Platform: "ios", Status: "latest", Released: base.Add(4 * time.Second),
+			Schedule: []model.Transition{{Status: "supported", Effective: time.Now().Add(time.Hour)}}}, t)
+
+		t.Logf("\tWhen listing the ios releases")
+		{
+			releases, total, err := repo.List(context.Background(), model.ReleaseFilter{Platform: "ios", Limit: 2})
+			if err == nil && total == 3 && len(releases) == 2 && releases[0].Version == "1.2.0" && releases[1].Version == "1.1.0" {
+				t.Logf("\t\tThe most recent releases should be listed first %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe most recent releases should be listed first %v %v %v %v", releases, total, err, test.BallotX)
+			}
+			releases, total, err = repo.List(context.Background(), model.ReleaseFilter{Platform: "ios", Limit: 2, Page: 2, Ascending: true})
+			if err == nil && total == 3 && len(releases) == 1 && releases[0].Version == "1.2.0" {
+				t.Logf("\t\tThe pages should follow the sort order %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe pages should follow the sort order %v %v %v %v", releases, total, err, test.BallotX)
+			}
+			releases, total, err = repo.List(context.Background(), model.ReleaseFilter{Status: "deprecated", Page: 5})
+			if err == nil && total == 2 && releases != nil && len(releases) == 0 {
+				t.Logf("\t\tA page past the end should be empty %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tA page past the end should be empty %v %v %v %v", releases, total, err, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen querying the scheduled releases")
+		{
+			releases, err := repo.Scheduled(context.Background(), "", time.Now())
+			if err == nil && len(releases) == 1 && releases[0].Version == "1.2.0" {
+				t.Logf("\t\tOnly the release with upcoming transitions should be found %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tOnly the release with upcoming transitions should be found %v %v %v", releases, err, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen querying the releases of another app")
+		{
+			_, err := repo.ForApp("com.acme.one").Find(context.Background(), "1.1.0", "ios")
+			expectNotFound(err, t)
+		}
+	}
+
+	t.Logf("Given the release ios 1.0.0 is deleted")
+	{
+		test.Ok(repo.Delete(context.Background(), model.ReleaseKey{Platform: "ios", Version: "1.0.0"}), t)
+
+		t.Logf("\tWhen querying then deleting the release again")
+		{
+			_, err := repo.History(context.Background(), model.ReleaseKey{Platform: "ios", Version: "1.0.0"})
+			expectNotFound(err, t)
+			expectNotFound(repo.Delete(context.Background(), model.ReleaseKey{Platform: "ios", Version: "1.0.0"}), t)
+		}
+	}
+
+	t.Logf("Given three changes recorded in the audit trail")
+	{
+		for i, actor := range []string{"alice", "bob", "alice"} {
+			err := repo.InsertAudit(context.Background(), model.AuditDAO{Action: model.AuditPublish, Actor: actor, Platform: "ios",
+				Timestamp: base.Add(time.Duration(i) * time.Second)})
+			test.Ok(err, t)
+		}
+
+		t.Logf("\tWhen querying the changes of alice")
+		{
+			records, total, err := repo.FindAudit(context.Background(), model.AuditFilter{Actor: "alice", Limit: 1})
+			if err == nil && total == 2 && len(records) == 1 && records[0].Timestamp.Equal(base.Add(2*time.Second)) {
+				t.Logf("\t\tThe most recent change should be found first %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe most recent change should be found first %v %v %v %v", records, total, err, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen querying the changes of a time window")
+		{
+			records, total, err := repo.FindAudit(context.Background(), model.AuditFilter{From: base.Add(time.Second), To: base.Add(2 * time.Second)})
+			if err == nil && total == 1 && len(records) == 1 && records[0].Actor == "bob" {
+				t.Logf("\t\tOnly the change within the window should be found %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tOnly the change within the window should be found %v %v %v %v", records, total, err, test.BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given two flags of the default app, redefined, and a flag of another app")
+	{
+		for _, flag := range []model.FlagDAO{{Name: "Dark", Updated: base}, {Name: "Chat", Updated: base},
+			{Name: "Dark", Enabled: true, Updated: base.Add(time.Second)}} {
+			test.Ok(repo.SaveFlag(context.Background(), flag), t)
+		}
+		test.Ok(repo.ForApp("com.acme.other").SaveFlag(context.Background(), model.FlagDAO{Name: "Pay", Updated: base}), t)
+
+		t.Logf("\tWhen querying the flags")
+		{
+			flags, err := repo.FindFlags(context.Background())
+			if err == nil && len(flags) == 2 && flags[0].Name == "Chat" && flags[1].Name == "Dark" && flags[1].Enabled {
+				t.Logf("\t\tThe latest definitions of the app should be found by name %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe latest definitions of the app should be found by name %v %v %v", flags, err, test.BallotX)
+			}
+			_, err = repo.FindFlag(context.Background(), "Pay")
+			expectNotFound(err, t)
+		}
+
+		t.Logf("\tWhen deleting a flag twice")
+		{
+			test.Ok(repo.DeleteFlag(context.Background(), "Chat"), t)
+			_, err := repo.FindFlag(context.Background(), "Chat")
+			expectNotFound(err, t)
+			expectNotFound(repo.DeleteFlag(context.Background(), "Chat"), t)
+		}
+	}
+}
+
+// Helper function
+func mustInsert(repo Repository, release interface{}, t *testing.T) {
+	if err := repo.Insert(context.Background(), release); err != nil {
+		t.Fatalf("\t\tThe insert should have been successful %v %v", err, test.BallotX)
+	}
+}
+
+// Helper function asserting the status of the version, an empty status meaning it should not be found
+func expectStatus(repo Repository, version, platform string, at time.Time, expected string, t *testing.T) {
+	result, err := repo.FindAt(context.Background(), version, platform, at)
+	if expected == "" {
+		expectNotFound(err, t)
+		return
+	}
+	if err == nil && result.Status == expected {
+		t.Logf("\t\tThe status of %s %s at %v should be %v %v", platform, version, at, expected, test.CheckMark)
+	} else {
+		t.Errorf("\t\tThe status of %s %s at %v should be %v but was %v %v %v", platform, version, at, expected,
+			result.Status, err, test.BallotX)
+	}
+}
+
+// Helper function
+func expectNotFound(err error, t *testing.T) {
+	if err != nil && err.Error() == NotFoundErrorMessage {
+		t.Logf("\t\tThe query should have failed with status %v %v", NotFoundErrorMessage, test.CheckMark)
+	} else {
+		t.Errorf("\t\tThe query should have failed with status %v %v %v", NotFoundErrorMessage, err, test.BallotX)
+	}
+}
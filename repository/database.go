@@ -1,14 +1,15 @@
 package repository
 
 import (
-	"errors"
+	"context"
 	"github.com/akhettar/app-features-manager/model"
 	"github.com/labstack/gommon/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"golang.org/x/net/context"
 	"os"
+	"strconv"
+	"time"
 )
 
 const (
@@ -16,6 +17,21 @@ const (
 	// MongoURI environment variable
 	MongoURI = "MONGO_URI"
 
+	// Backend environment variable selecting the repository implementation, mongo by default
+	Backend = "REPOSITORY"
+
+	// MemoryBackend the backend keeping the data in memory, for local runs and tests
+	MemoryBackend = "memory"
+
+	// FileBackend the backend persisting the data to a local journal file, for deployments without a mongo instance
+	FileBackend = "file"
+
+	// JournalPath environment variable setting the path of the journal file of the file backend
+	JournalPath = "JOURNAL_PATH"
+
+	// DefaultJournalPath the journal file of the file backend, relative to the working directory
+	DefaultJournalPath = "app-features.journal"
+
 	// DefaultMongoHost for running the app as a standalone server.
 	DefaultMongoHost = "mongodb://localhost"
 
@@ -36,6 +52,40 @@ const (
 
 	// Collection the vault entry of the db collection
 	Collection = "MONGO_COLLECTION"
+
+	// ThresholdsSuffix the suffix of the collection holding the platform version thresholds
+	ThresholdsSuffix = "_thresholds"
+
+	// AuditSuffix the suffix of the collection holding the audit trail
+	AuditSuffix = "_audit"
+
+	// FlagsSuffix the suffix of the collection holding the definitions of the flags evaluated by the service
+	FlagsSuffix = "_flags"
+
+	// MigrationsSuffix the suffix of the collection recording the migrations applied to the schema
+	MigrationsSuffix = "_migrations"
+
+	// MigrateOnStart environment variable telling whether the server migrates the schema on start up, "true" by
+	// default. The schema can otherwise be migrated by running the server with the -migrate flag.
+	MigrateOnStart = "MONGO_MIGRATE"
+
+	// ReadTimeout environment variable setting the deadline of the queries, e.g. "2s"
+	ReadTimeout = "MONGO_READ_TIMEOUT"
+
+	// WriteTimeout environment variable setting the deadline of the inserts and deletes, e.g. "5s"
+	WriteTimeout = "MONGO_WRITE_TIMEOUT"
+)
+
+const (
+
+	// DefaultReadTimeout the default deadline of the queries
+	DefaultReadTimeout = 2 * time.Second
+
+	// DefaultWriteTimeout the default deadline of the inserts and deletes
+	DefaultWriteTimeout = 5 * time.Second
+
+	// MigrationTimeout the deadline of the migrations of the schema, building the indexes of large collections
+	MigrationTimeout = 10 * time.Minute
 )
 
 // DBInfo the database info
@@ -51,20 +101,92 @@ type DBInfo struct {
 	Collection string
 }
 
+// ThresholdsCollection the name of the collection holding the platform version thresholds
+func (info DBInfo) ThresholdsCollection() string {
+	return info.Collection + ThresholdsSuffix
+}
+
+// AuditCollection the name of the collection holding the audit trail
+func (info DBInfo) AuditCollection() string {
+	return info.Collection + AuditSuffix
+}
+
+// FlagsCollection the name of the collection holding the definitions of the flags evaluated by the service
+func (info DBInfo) FlagsCollection() string {
+	return info.Collection + FlagsSuffix
+}
+
+// MigrationsCollection the name of the collection recording the migrations applied to the schema
+func (info DBInfo) MigrationsCollection() string {
+	return info.Collection + MigrationsSuffix
+}
+
+// ResumeTokensCollection the name of the collection holding the resume tokens of the change streams of the replicas
+func (info DBInfo) ResumeTokensCollection() string {
+	return info.Collection + ResumeTokensSuffix
+}
+
+// Timeouts the deadlines of the operations on the data store. The operations are only bound by the deadline of the
+// context they are given when zero.
+type Timeouts struct {
+
+	// Read the deadline of the queries
+	Read time.Duration
+
+	// Write the deadline of the inserts and deletes
+	Write time.Duration
+}
+
+// read bounds the context of a query by the read deadline
+func (t Timeouts) read(ctx context.Context) (context.Context, context.CancelFunc) {
+	return bound(ctx, t.Read)
+}
+
+// write bounds the context of an insert or a delete by the write deadline
+func (t Timeouts) write(ctx context.Context) (context.Context, context.CancelFunc) {
+	return bound(ctx, t.Write)
+}
+
+func bound(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 // MongoRepository type
 type MongoRepository struct {
 	*mongo.Client
 	DBInfo
+	Timeouts
+
+	// App the identifier of the app the data is scoped to, the default app when empty
+	App string
 }
 
-// Repository interface
+// Repository interface. Every operation is bound by the given context: ErrNotFound is returned when no data matches
+// and an UnavailableError when the context is done before the data store completes the operation.
 type Repository interface {
-	Insert(body interface{}) error
-	Find(version, platform string) (model.ReleaseResponse, error)
+	Insert(ctx context.Context, body interface{}) error
+	Find(ctx context.Context, version, platform string) (model.ReleaseResponse, error)
+	FindAt(ctx context.Context, version, platform string, at time.Time) (model.ReleaseResponse, error)
+	InsertThresholds(ctx context.Context, thresholds model.ThresholdsDAO) error
+	FindThresholds(ctx context.Context, platform string, at time.Time) (model.ThresholdsDAO, error)
+	List(ctx context.Context, filter model.ReleaseFilter) ([]model.ReleaseDAO, int64, error)
+	Scheduled(ctx context.Context, platform string, after time.Time) ([]model.ReleaseDAO, error)
+	History(ctx context.Context, key model.ReleaseKey) ([]model.ReleaseDAO, error)
+	Delete(ctx context.Context, key model.ReleaseKey) error
+	InsertAudit(ctx context.Context, record model.AuditDAO) error
+	FindAudit(ctx context.Context, filter model.AuditFilter) ([]model.AuditDAO, int64, error)
+	SaveFlag(ctx context.Context, flag model.FlagDAO) error
+	FindFlag(ctx context.Context, name string) (model.FlagDAO, error)
+	FindFlags(ctx context.Context) ([]model.FlagDAO, error)
+	DeleteFlag(ctx context.Context, name string) error
+	ForApp(app string) Repository
 }
 
 // NewRepository function to create an instance of Mongo repository
-func NewRepository() Repository {
+func NewRepository() *MongoRepository {
 
 	// default value
 	url := GetEnv(MongoURI, DefaultMongoHost)
@@ -86,18 +208,44 @@ func NewRepository() Repository {
 		log.Fatal(err)
 	}
 	dbInfo := DBInfo{url, dbname, GetEnv(Collection, DefaultCollection)}
+	timeouts := Timeouts{Read: GetDurationEnv(ReadTimeout, DefaultReadTimeout), Write: GetDurationEnv(WriteTimeout, DefaultWriteTimeout)}
 	log.Printf("Connected to Document DB %s, %s, %s", clientOptions.Hosts, dbInfo.Database, dbInfo.Collection)
-	return &MongoRepository{client, dbInfo}
+	return &MongoRepository{Client: client, DBInfo: dbInfo, Timeouts: timeouts}
+}
+
+// ForApp returns a view of the repository scoped to the data of the given app. The data of the default app, i.e. the
+// data stored before apps were introduced, is not tagged with any app.
+func (repo *MongoRepository) ForApp(app string) Repository {
+	return &MongoRepository{Client: repo.Client, DBInfo: repo.DBInfo, Timeouts: repo.Timeouts, App: app}
 }
 
 // Insert into data store
-func (repo *MongoRepository) Insert(body interface{}) error {
-	_, err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.Collection).InsertOne(context.TODO(), &body)
-	return err
+func (repo *MongoRepository) Insert(ctx context.Context, body interface{}) error {
+	ctx, cancel := repo.write(ctx)
+	defer cancel()
+	switch release := body.(type) {
+	case *model.ReleaseDAO:
+		release.App = repo.App
+	case model.ReleaseDAO:
+		release.App = repo.App
+		body = release
+	}
+	_, err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.Collection).InsertOne(ctx, &body)
+	return failure(ctx, "insert", err)
+}
+
+// Find query the status of the app for given version shall return the latest. When no release was published for
+// the exact version, the status is resolved from the range policies of the platform the version satisfies, and
+// failing that derived from the version thresholds of the platform. The scheduled transitions of the resolved
+// release are evaluated against the current time.
+func (repo *MongoRepository) Find(ctx context.Context, version, platform string) (model.ReleaseResponse, error) {
+	return repo.FindAt(ctx, version, platform, time.Now())
 }
 
-// Find query the status of the app for given version shall return the latest
-func (repo *MongoRepository) Find(version, platform string) (model.ReleaseResponse, error) {
+// FindAt query the status the app had for given version at the given time, ignoring anything released afterwards
+func (repo *MongoRepository) FindAt(ctx context.Context, version, platform string, at time.Time) (model.ReleaseResponse, error) {
+	ctx, cancel := repo.read(ctx)
+	defer cancel()
 
 	var results []*model.ReleaseDAO
 	findOptions := options.Find()
@@ -105,31 +253,324 @@ func (repo *MongoRepository) Find(version, platform string) (model.ReleaseRespon
 	sortMap["released"] = 1
 	findOptions = findOptions.SetSort(sortMap)
 
-	// query
-	cursor, err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.Collection).Find(context.TODO(),
-		bson.M{model.AppVersion: version, model.AppPlatform: platform}, findOptions)
+	// query the releases of the exact version along with all the range policies of the platform
+	query := repo.scope(bson.M{model.AppPlatform: platform, "released": bson.M{"$lte": at}, "$or": []bson.M{
+		{model.AppVersion: version},
+		{model.AppRange: bson.M{"$exists": true}},
+	}})
+	cursor, err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.Collection).Find(ctx,
+		query, findOptions)
 
 	if err != nil {
-		return model.ReleaseResponse{}, err
+		return model.ReleaseResponse{}, failure(ctx, "find", err)
 	}
+	defer cursor.Close(ctx)
 
 	// Iterating through the cursor allows us to decode documents one at a time
-	for cursor.Next(context.TODO()) {
+	for cursor.Next(ctx) {
 		var result model.ReleaseDAO
 		err := cursor.Decode(&result)
 		if err != nil {
 			log.Error("Failed to decode document queried from the DB")
-			return model.ReleaseResponse{}, err
+			return model.ReleaseResponse{}, failure(ctx, "find", err)
 		}
 		results = append(results, &result)
 	}
+	if err := cursor.Err(); err != nil {
+		return model.ReleaseResponse{}, failure(ctx, "find", err)
+	}
+
+	return status(version, at, results, func() (model.ThresholdsDAO, error) {
+		return repo.FindThresholds(ctx, platform, at)
+	})
+}
+
+// InsertThresholds stores new version thresholds for a platform, superseding the previous ones
+func (repo *MongoRepository) InsertThresholds(ctx context.Context, thresholds model.ThresholdsDAO) error {
+	ctx, cancel := repo.write(ctx)
+	defer cancel()
+	thresholds.App = repo.App
+	_, err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.ThresholdsCollection()).InsertOne(ctx, &thresholds)
+	return failure(ctx, "insert thresholds", err)
+}
+
+// FindThresholds query the version thresholds of the given platform in effect at the given time
+func (repo *MongoRepository) FindThresholds(ctx context.Context, platform string, at time.Time) (model.ThresholdsDAO, error) {
+	ctx, cancel := repo.read(ctx)
+	defer cancel()
+	var result model.ThresholdsDAO
+	findOptions := options.FindOne().SetSort(bson.M{"released": -1})
+	err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.ThresholdsCollection()).FindOne(ctx,
+		repo.scope(bson.M{model.AppPlatform: platform, "released": bson.M{"$lte": at}}), findOptions).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return result, ErrNotFound
+	}
+	return result, failure(ctx, "find thresholds", err)
+}
+
+// List query the releases matching the filter in their current state, i.e. the latest state each was published with
+func (repo *MongoRepository) List(ctx context.Context, filter model.ReleaseFilter) ([]model.ReleaseDAO, int64, error) {
+	ctx, cancel := repo.read(ctx)
+	defer cancel()
+
+	order := -1
+	if filter.Ascending {
+		order = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = model.DefaultPageLimit
+	}
+	skip := int64(0)
+	if filter.Page > 1 {
+		skip = int64(filter.Page-1) * int64(filter.Limit)
+	}
+
+	pipeline := repo.currentState(filter.Platform)
+	if filter.Status != "" {
+		pipeline = append(pipeline, bson.M{"$match": bson.M{"status": filter.Status}})
+	}
+	pipeline = append(pipeline,
+		bson.M{"$sort": bson.D{{Key: "released", Value: order}, {Key: "_id", Value: 1}}},
+		bson.M{"$facet": bson.M{
+			"total":    []bson.M{{"$count": "count"}},
+			"releases": []bson.M{{"$skip": skip}, {"$limit": int64(filter.Limit)}},
+		}})
+
+	cursor, err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.Collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, failure(ctx, "list", err)
+	}
+	defer cursor.Close(ctx)
 
-	// decode the response
-	var result model.ReleaseDAO
+	var page struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Releases []model.ReleaseDAO `bson:"releases"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&page); err != nil {
+			log.Error("Failed to decode releases queried from the DB")
+			return nil, 0, failure(ctx, "list", err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, 0, failure(ctx, "list", err)
+	}
+	if len(page.Total) == 0 {
+		return []model.ReleaseDAO{}, 0, nil
+	}
+	return page.Releases, page.Total[0].Count, nil
+}
+
+// Scheduled query the releases in their current state with transitions scheduled after the given time, the releases
+// of every platform when none is given
+func (repo *MongoRepository) Scheduled(ctx context.Context, platform string, after time.Time) ([]model.ReleaseDAO, error) {
+	ctx, cancel := repo.read(ctx)
+	defer cancel()
+	pipeline := append(repo.currentState(platform), bson.M{"$match": bson.M{"schedule.effective": bson.M{"$gt": after}}})
+	cursor, err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.Collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, failure(ctx, "list scheduled", err)
+	}
+
+	var results []model.ReleaseDAO
+	if err := cursor.All(ctx, &results); err != nil {
+		log.Error("Failed to decode releases queried from the DB")
+		return nil, failure(ctx, "list scheduled", err)
+	}
+	return results, nil
+}
+
+// currentState builds the aggregation stages reducing the releases of the given platform, or of every platform,
+// to their current state: the last one each was published with
+func (repo *MongoRepository) currentState(platform string) []bson.M {
+	match := bson.M{}
+	if platform != "" {
+		match[model.AppPlatform] = platform
+	}
+	return append([]bson.M{{"$match": repo.scope(match)}},
+		bson.M{"$sort": bson.M{"released": 1}},
+		bson.M{"$group": bson.M{
+			"_id":             bson.M{model.AppPlatform: "$platform", model.AppVersion: "$version", model.AppRange: "$range"},
+			model.AppPlatform: bson.M{"$last": "$platform"},
+			model.AppVersion:  bson.M{"$last": "$version"},
+			model.AppRange:    bson.M{"$last": bson.M{"$ifNull": []interface{}{"$range", ""}}},
+			"status":          bson.M{"$last": "$status"},
+			"released":        bson.M{"$last": "$released"},
+			"schedule":        bson.M{"$last": "$schedule"},
+			"storeUrl":        bson.M{"$last": "$storeUrl"},
+			"messages":        bson.M{"$last": "$messages"},
+		}})
+}
+
+// History query every state the given release was published with, sorted by released date
+func (repo *MongoRepository) History(ctx context.Context, key model.ReleaseKey) ([]model.ReleaseDAO, error) {
+	ctx, cancel := repo.read(ctx)
+	defer cancel()
+	findOptions := options.Find().SetSort(bson.M{"released": 1})
+	cursor, err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.Collection).Find(ctx,
+		repo.scope(keyFilter(key)), findOptions)
+	if err != nil {
+		return nil, failure(ctx, "find history", err)
+	}
+
+	var results []model.ReleaseDAO
+	if err := cursor.All(ctx, &results); err != nil {
+		log.Error("Failed to decode documents queried from the DB")
+		return nil, failure(ctx, "find history", err)
+	}
 	if len(results) == 0 {
-		return model.ReleaseResponse{Status: result.Status}, errors.New(NotFoundErrorMessage)
+		return nil, ErrNotFound
+	}
+	return results, nil
+}
+
+// Delete removes the given release along with its whole history
+func (repo *MongoRepository) Delete(ctx context.Context, key model.ReleaseKey) error {
+	ctx, cancel := repo.write(ctx)
+	defer cancel()
+	result, err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.Collection).DeleteMany(ctx,
+		repo.scope(keyFilter(key)))
+	if err != nil {
+		return failure(ctx, "delete", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// InsertAudit appends a record to the audit trail. Audit records are never updated nor deleted.
+func (repo *MongoRepository) InsertAudit(ctx context.Context, record model.AuditDAO) error {
+	ctx, cancel := repo.write(ctx)
+	defer cancel()
+	record.App = repo.App
+	_, err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.AuditCollection()).InsertOne(ctx, &record)
+	return failure(ctx, "insert audit", err)
+}
+
+// FindAudit query the audit records matching the filter, the most recent first
+func (repo *MongoRepository) FindAudit(ctx context.Context, filter model.AuditFilter) ([]model.AuditDAO, int64, error) {
+	ctx, cancel := repo.read(ctx)
+	defer cancel()
+	if filter.Limit <= 0 {
+		filter.Limit = model.DefaultPageLimit
+	}
+	skip := int64(0)
+	if filter.Page > 1 {
+		skip = int64(filter.Page-1) * int64(filter.Limit)
+	}
+
+	query := repo.scope(bson.M{})
+	for field, value := range map[string]string{model.AppVersion: filter.Version, model.AppRange: filter.Range,
+		model.AppPlatform: filter.Platform, "flag": filter.Flag, "actor": filter.Actor} {
+		if value != "" {
+			query[field] = value
+		}
+	}
+	window := bson.M{}
+	if !filter.From.IsZero() {
+		window["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		window["$lt"] = filter.To
 	}
-	return model.ReleaseResponse{Status: results[len(results)-1].Status}, err
+	if len(window) > 0 {
+		query["timestamp"] = window
+	}
+
+	collection := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.AuditCollection())
+	total, err := collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, failure(ctx, "count audit", err)
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).SetSkip(skip).SetLimit(int64(filter.Limit))
+	cursor, err := collection.Find(ctx, query, findOptions)
+	if err != nil {
+		return nil, 0, failure(ctx, "find audit", err)
+	}
+
+	results := []model.AuditDAO{}
+	if err := cursor.All(ctx, &results); err != nil {
+		log.Error("Failed to decode audit records queried from the DB")
+		return nil, 0, failure(ctx, "find audit", err)
+	}
+	return results, total, nil
+}
+
+// SaveFlag stores the definition of a flag, replacing the previous one
+func (repo *MongoRepository) SaveFlag(ctx context.Context, flag model.FlagDAO) error {
+	ctx, cancel := repo.write(ctx)
+	defer cancel()
+	flag.App = repo.App
+	_, err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.FlagsCollection()).ReplaceOne(ctx,
+		repo.scope(bson.M{"name": flag.Name}), &flag, options.Replace().SetUpsert(true))
+	return failure(ctx, "save flag", err)
+}
+
+// FindFlag query the definition of the given flag
+func (repo *MongoRepository) FindFlag(ctx context.Context, name string) (model.FlagDAO, error) {
+	ctx, cancel := repo.read(ctx)
+	defer cancel()
+	var flag model.FlagDAO
+	err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.FlagsCollection()).FindOne(ctx,
+		repo.scope(bson.M{"name": name})).Decode(&flag)
+	if err == mongo.ErrNoDocuments {
+		return flag, ErrNotFound
+	}
+	return flag, failure(ctx, "find flag", err)
+}
+
+// FindFlags query the definitions of every flag, sorted by name
+func (repo *MongoRepository) FindFlags(ctx context.Context) ([]model.FlagDAO, error) {
+	ctx, cancel := repo.read(ctx)
+	defer cancel()
+	cursor, err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.FlagsCollection()).Find(ctx,
+		repo.scope(bson.M{}), options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, failure(ctx, "find flags", err)
+	}
+
+	results := []model.FlagDAO{}
+	if err := cursor.All(ctx, &results); err != nil {
+		log.Error("Failed to decode flags queried from the DB")
+		return nil, failure(ctx, "find flags", err)
+	}
+	return results, nil
+}
+
+// DeleteFlag removes the definition of the given flag
+func (repo *MongoRepository) DeleteFlag(ctx context.Context, name string) error {
+	ctx, cancel := repo.write(ctx)
+	defer cancel()
+	result, err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.FlagsCollection()).DeleteOne(ctx,
+		repo.scope(bson.M{"name": name}))
+	if err != nil {
+		return failure(ctx, "delete flag", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scope restricts the query to the data of the app the repository is scoped to
+func (repo *MongoRepository) scope(query bson.M) bson.M {
+	if repo.App == "" {
+		query["app"] = nil
+	} else {
+		query["app"] = repo.App
+	}
+	return query
+}
+
+// keyFilter the query matching every document of the given release
+func keyFilter(key model.ReleaseKey) bson.M {
+	if key.Range != "" {
+		return bson.M{model.AppPlatform: key.Platform, model.AppRange: key.Range}
+	}
+	return bson.M{model.AppPlatform: key.Platform, model.AppVersion: key.Version, model.AppRange: bson.M{"$exists": false}}
 }
 
 // GetEnv env variable or fall back to default
@@ -140,3 +581,31 @@ func GetEnv(key, fallback string) string {
 	}
 	return value
 }
+
+// GetIntEnv integer env variable or fall back to default when not set or invalid
+func GetIntEnv(key string, fallback int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		log.Warnf("Invalid integer %q of %s, falling back to %d", value, key, fallback)
+		return fallback
+	}
+	return i
+}
+
+// GetDurationEnv duration env variable, e.g. "1.5s", or fall back to default when not set or invalid
+func GetDurationEnv(key string, fallback time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warnf("Invalid duration %q of %s, falling back to %v", value, key, fallback)
+		return fallback
+	}
+	return duration
+}
@@ -1,8 +1,11 @@
-package repository
+package repository_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/akhettar/app-features-manager/model"
+	. "github.com/akhettar/app-features-manager/repository"
 	"github.com/akhettar/app-features-manager/test"
 	"os"
 	"testing"
@@ -17,7 +20,7 @@ func TestMongoRepository_Insert(t *testing.T) {
 		t.Logf("\tWhen Sending Publish app status request to endpoint:  \"%s\"", "\\status")
 		{
 			status := model.ReleaseDAO{Status: "supported", Version: "1.0", Platform: "ios", Released: time.Now()}
-			err := RepositoryUnderTest.Insert(status)
+			err := RepositoryUnderTest.Insert(context.Background(), status)
 			if err == nil {
 				t.Logf("\t\tThe insert should have been successful %v", test.CheckMark)
 			} else {
@@ -38,7 +41,7 @@ func TestMongoRepository_FindSuccess(t *testing.T) {
 			publishStatus("1.0", "ios")
 
 			// query
-			result, err := RepositoryUnderTest.Find("1.0", "ios")
+			result, err := RepositoryUnderTest.Find(context.Background(), "1.0", "ios")
 			if err == nil {
 				t.Logf("\t\tThe query should have been successful %v", test.CheckMark)
 			} else {
@@ -62,7 +65,7 @@ func TestMongoRepository_AppStatusNotFound(t *testing.T) {
 		t.Logf("\tWhen Sending Query status of the app for given version: \"%s\"", "1.0")
 		{
 			// query
-			_, err := RepositoryUnderTest.Find("2.0", "ios")
+			_, err := RepositoryUnderTest.Find(context.Background(), "2.0", "ios")
 			if err != nil && err.Error() == NotFoundErrorMessage {
 				t.Logf("\t\tThe query should have failed with status %v %v", err.Error(), test.CheckMark)
 			} else {
@@ -72,6 +75,324 @@ func TestMongoRepository_AppStatusNotFound(t *testing.T) {
 	}
 }
 
+// TestMongoRepository_FindResolvesVersionRange should resolve the status from the most specific range policy
+func TestMongoRepository_FindResolvesVersionRange(t *testing.T) {
+
+	t.Logf("Given range policies were published for the windows platform")
+	{
+		publishRange("<3.2.0", "unsupported", "windows")
+		publishRange("3.2.x", "deprecated", "windows")
+		publishRange(">=3.0.0 <4.0.0", "supported", "windows")
+		publishStatus("3.2.5", "windows")
+
+		expectations := map[string]string{"2.9.0": "unsupported", "3.1.9": "unsupported", "3.2.0-beta.1": "deprecated", "3.2.1+build.7": "deprecated",
+			"3.2.5": "supported", "3.5.0": "supported"}
+		for version, expected := range expectations {
+			t.Logf("\tWhen Sending Query status of the app for given version: \"%s\"", version)
+			{
+				result, err := RepositoryUnderTest.Find(context.Background(), version, "windows")
+				if err == nil && result.Status == expected {
+					t.Logf("\t\tThe status should have been resolved to %v %v", expected, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe status should have been resolved to %v but was %v %v %v", expected, result.Status, err, test.BallotX)
+				}
+			}
+		}
+
+		t.Logf("\tWhen Sending Query status of the app for a version outside of any range: \"%s\"", "4.0.0")
+		{
+			_, err := RepositoryUnderTest.Find(context.Background(), "4.0.0", "windows")
+			if err != nil && err.Error() == NotFoundErrorMessage {
+				t.Logf("\t\tThe query should have failed with status %v %v", NotFoundErrorMessage, test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe query should have failed with status %v %v", NotFoundErrorMessage, test.BallotX)
+			}
+		}
+	}
+
+	t.Logf("Given broad range policies overlapping a narrower one were published for the linux platform")
+	{
+		publishRange("3.2.x", "deprecated", "linux")
+		publishRange("^3.2.1", "supported", "linux")
+		publishRange(">=3.2.1", "latest", "linux")
+
+		expectations := map[string]string{"3.2.5": "deprecated", "3.4.0": "supported", "4.1.0": "latest"}
+		for version, expected := range expectations {
+			t.Logf("\tWhen Sending Query status of the app for given version: \"%s\"", version)
+			{
+				result, err := RepositoryUnderTest.Find(context.Background(), version, "linux")
+				if err == nil && result.Status == expected {
+					t.Logf("\t\tThe status should have been resolved to %v %v", expected, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe status should have been resolved to %v but was %v %v %v", expected, result.Status, err, test.BallotX)
+				}
+			}
+		}
+	}
+}
+
+// TestMongoRepository_FindDerivesStatusFromThresholds should derive the status from the platform thresholds
+func TestMongoRepository_FindDerivesStatusFromThresholds(t *testing.T) {
+
+	t.Logf("Given version thresholds were declared for the blackberry platform")
+	{
+		thresholds := model.ThresholdsDAO{Platform: "blackberry", Released: time.Now(),
+			Thresholds: model.Thresholds{MinSupported: "2.0.0", MinRecommended: "2.5.0", Latest: "3.0.0"}}
+		if err := RepositoryUnderTest.InsertThresholds(context.Background(), thresholds); err != nil {
+			t.Fatalf("\t\tThe insert should have been successful %v", test.BallotX)
+		}
+		publishStatus("1.0.0", "blackberry")
+
+		expectations := map[string]string{"1.9.9": "unsupported", "2.4.0": "deprecated", "2.9.0": "supported",
+			"3.0.0": "latest", "1.0.0": "supported"}
+		for version, expected := range expectations {
+			t.Logf("\tWhen Sending Query status of the app for given version: \"%s\"", version)
+			{
+				result, err := RepositoryUnderTest.Find(context.Background(), version, "blackberry")
+				if err == nil && result.Status == expected {
+					t.Logf("\t\tThe status should have been resolved to %v %v", expected, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe status should have been resolved to %v but was %v %v %v", expected, result.Status, err, test.BallotX)
+				}
+			}
+		}
+	}
+}
+
+// TestMongoRepository_FindAtIgnoresLaterReleases should resolve the status a version had at a given time
+func TestMongoRepository_FindAtIgnoresLaterReleases(t *testing.T) {
+
+	t.Logf("Given a version was published then made unsupported on the ios platform")
+	{
+		publishStatus("10.0.0", "ios")
+		time.Sleep(10 * time.Millisecond)
+		before := time.Now()
+		time.Sleep(10 * time.Millisecond)
+		RepositoryUnderTest.Insert(context.Background(), model.ReleaseDAO{Status: "unsupported", Version: "10.0.0", Platform: "ios", Released: time.Now()})
+
+		t.Logf("\tWhen Sending Query status of the app at a time before the update")
+		{
+			result, err := RepositoryUnderTest.FindAt(context.Background(), "10.0.0", "ios", before)
+			if err == nil && result.Status == "supported" {
+				t.Logf("\t\tThe status should be the one published before that time %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe status should be the one published before that time %v %v %v", result, err, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen Sending Query status of the app at a time before its first release")
+		{
+			_, err := RepositoryUnderTest.FindAt(context.Background(), "10.0.0", "ios", before.Add(-time.Hour))
+			if err != nil && err.Error() == NotFoundErrorMessage {
+				t.Logf("\t\tThe query should have failed with status %v %v", NotFoundErrorMessage, test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe query should have failed with status %v %v", NotFoundErrorMessage, test.BallotX)
+			}
+		}
+	}
+}
+
+// TestMongoRepository_ScheduledListsUpcomingTransitions should only list the releases with transitions to come
+func TestMongoRepository_ScheduledListsUpcomingTransitions(t *testing.T) {
+
+	t.Logf("Given a release of the windows platform scheduled to be deprecated in an hour")
+	{
+		now := time.Now()
+		RepositoryUnderTest.Insert(context.Background(), model.ReleaseDAO{Status: "supported", Version: "12.0.0", Platform: "windows", Released: now,
+			Schedule: []model.Transition{{Status: "deprecated", Effective: now.Add(time.Hour)}}})
+
+		t.Logf("\tWhen querying the releases with transitions scheduled from now")
+		{
+			releases, err := RepositoryUnderTest.Scheduled(context.Background(), "windows", now)
+			if err == nil && len(releases) == 1 && releases[0].Version == "12.0.0" {
+				t.Logf("\t\tThe scheduled release should be listed %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe scheduled release should be listed %v %v %v", releases, err, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen querying the releases with transitions scheduled after the deprecation")
+		{
+			releases, err := RepositoryUnderTest.Scheduled(context.Background(), "windows", now.Add(2*time.Hour))
+			if err == nil && len(releases) == 0 {
+				t.Logf("\t\tNo release should be listed %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tNo release should be listed %v %v %v", releases, err, test.BallotX)
+			}
+		}
+	}
+}
+
+// TestMongoRepository_ForAppIsolatesTheDataOfEachApp should only query the data of the app the repository is scoped to
+func TestMongoRepository_ForAppIsolatesTheDataOfEachApp(t *testing.T) {
+
+	t.Logf("Given a release of the app com.acme.one")
+	{
+		one := RepositoryUnderTest.ForApp("com.acme.one")
+		if err := one.Insert(context.Background(), model.ReleaseDAO{Status: "deprecated", Version: "13.0.0", Platform: "ios", Released: time.Now()}); err != nil {
+			t.Fatalf("\t\tThe insert should have been successful %v", test.BallotX)
+		}
+
+		t.Logf("\tWhen Sending Query status of the app com.acme.one")
+		{
+			result, err := one.Find(context.Background(), "13.0.0", "ios")
+			if err == nil && result.Status == "deprecated" {
+				t.Logf("\t\tThe release should be found %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe release should be found %v %v %v", result, err, test.BallotX)
+			}
+		}
+
+		for name, repo := range map[string]Repository{"com.acme.two": RepositoryUnderTest.ForApp("com.acme.two"),
+			"the default app": RepositoryUnderTest} {
+			t.Logf("\tWhen Sending Query status of %s", name)
+			{
+				_, err := repo.Find(context.Background(), "13.0.0", "ios")
+				if err != nil && err.Error() == NotFoundErrorMessage {
+					t.Logf("\t\tThe query should have failed with status %v %v", NotFoundErrorMessage, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe query should have failed with status %v %v", NotFoundErrorMessage, test.BallotX)
+				}
+			}
+		}
+	}
+}
+
+// TestMongoRepository_ListHistoryAndDelete should manage the releases in their current state
+func TestMongoRepository_ListHistoryAndDelete(t *testing.T) {
+
+	t.Logf("Given releases were published for the android platform")
+	{
+		publishStatus("9.0.0", "android")
+		time.Sleep(10 * time.Millisecond)
+		publishStatus("9.1.0", "android")
+		time.Sleep(10 * time.Millisecond)
+		RepositoryUnderTest.Insert(context.Background(), model.ReleaseDAO{Status: "deprecated", Version: "9.0.0", Platform: "android", Released: time.Now()})
+
+		t.Logf("\tWhen listing the deprecated android releases")
+		{
+			releases, total, err := RepositoryUnderTest.List(context.Background(), model.ReleaseFilter{Platform: "android", Status: "deprecated", Limit: 10})
+			if err == nil && total == 1 && len(releases) == 1 && releases[0].Version == "9.0.0" {
+				t.Logf("\t\tOnly the release currently deprecated should be listed %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tOnly the release currently deprecated should be listed %v %v %v", releases, err, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen listing the second page of android releases")
+		{
+			releases, total, err := RepositoryUnderTest.List(context.Background(), model.ReleaseFilter{Platform: "android", Page: 2, Limit: 1})
+			if err == nil && total == 2 && len(releases) == 1 && releases[0].Version == "9.1.0" {
+				t.Logf("\t\tThe least recently released should be on the second page %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe least recently released should be on the second page %v %v %v", releases, err, test.BallotX)
+			}
+		}
+
+		key := model.ReleaseKey{Platform: "android", Version: "9.0.0"}
+		t.Logf("\tWhen querying the history of the release %s", key)
+		{
+			history, err := RepositoryUnderTest.History(context.Background(), key)
+			if err == nil && len(history) == 2 && history[1].Status == "deprecated" {
+				t.Logf("\t\tBoth states of the release should be returned in order %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tBoth states of the release should be returned in order %v %v %v", history, err, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen deleting the release %s", key)
+		{
+			err := RepositoryUnderTest.Delete(context.Background(), key)
+			_, findErr := RepositoryUnderTest.History(context.Background(), key)
+			if err == nil && findErr != nil && findErr.Error() == NotFoundErrorMessage {
+				t.Logf("\t\tThe release should have been deleted %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe release should have been deleted %v %v %v", err, findErr, test.BallotX)
+			}
+		}
+	}
+}
+
+// TestMongoRepository_FindAudit should filter the audit trail by actor and time window
+func TestMongoRepository_FindAudit(t *testing.T) {
+
+	t.Logf("Given changes were recorded in the audit trail")
+	{
+		start := time.Now()
+		records := []model.AuditDAO{
+			{Action: model.AuditPublish, Actor: "alice", Version: "5.0.0", Platform: "ios", NewStatus: "supported", Timestamp: start},
+			{Action: model.AuditUpdate, Actor: "bob", Version: "5.0.0", Platform: "ios", PreviousStatus: "supported",
+				NewStatus: "deprecated", Timestamp: start.Add(time.Minute)},
+			{Action: model.AuditDelete, Actor: "alice", Version: "5.0.0", Platform: "ios", PreviousStatus: "deprecated",
+				Timestamp: start.Add(time.Hour)},
+		}
+		for _, record := range records {
+			if err := RepositoryUnderTest.InsertAudit(context.Background(), record); err != nil {
+				t.Fatalf("\t\tThe insert should have been successful %v", test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen querying the changes made by alice within the first half hour")
+		{
+			filter := model.AuditFilter{Version: "5.0.0", Actor: "alice", From: start.Add(-time.Second), To: start.Add(30 * time.Minute)}
+			results, total, err := RepositoryUnderTest.FindAudit(context.Background(), filter)
+			if err == nil && total == 1 && len(results) == 1 && results[0].Action == model.AuditPublish {
+				t.Logf("\t\tOnly the publication should be returned %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tOnly the publication should be returned %v %v %v", results, err, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen querying every change of the release")
+		{
+			results, total, err := RepositoryUnderTest.FindAudit(context.Background(), model.AuditFilter{Version: "5.0.0", Platform: "ios"})
+			if err == nil && total == 3 && results[0].Action == model.AuditDelete {
+				t.Logf("\t\tThe changes should be returned most recent first %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe changes should be returned most recent first %v %v %v", results, err, test.BallotX)
+			}
+		}
+	}
+}
+
+// TestMongoRepository_FindHonoursTheDeadline checks the queries whose deadline passed fail as unavailable
+func TestMongoRepository_FindHonoursTheDeadline(t *testing.T) {
+	if MongoClient == nil {
+		t.Skip("the deadlines only apply to the mongo repository")
+	}
+
+	t.Logf("Given a release was published")
+	{
+		publishStatus("14.0.0", "ios")
+		repo := &MongoRepository{Client: MongoClient, DBInfo: DBInfo{Database: DefaultDBName, Collection: DefaultCollection},
+			Timeouts: Timeouts{Read: time.Nanosecond}}
+
+		t.Logf("\tWhen querying its status with a read deadline too short")
+		{
+			_, err := repo.Find(context.Background(), "14.0.0", "ios")
+			var unavailable *UnavailableError
+			if errors.As(err, &unavailable) && unavailable.Timeout() {
+				t.Logf("\t\tThe query should have timed out %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe query should have timed out %v %v", err, test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen querying its status with a cancelled context")
+		{
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := RepositoryUnderTest.Find(ctx, "14.0.0", "ios")
+			var unavailable *UnavailableError
+			if errors.As(err, &unavailable) && !unavailable.Timeout() {
+				t.Logf("\t\tThe query should have been cancelled %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe query should have been cancelled %v %v", err, test.BallotX)
+			}
+		}
+	}
+}
+
 func TestNewRepository(t *testing.T) {
 	os.Setenv(ENVIRONMENT, "dev")
 	go NewRepository()
@@ -80,10 +401,18 @@ func TestNewRepository(t *testing.T) {
 // Helper function
 func publishStatus(version, platform string) {
 	status := model.ReleaseDAO{Status: "supported", Version: version, Platform: platform, Released: time.Now()}
-	err := RepositoryUnderTest.Insert(status)
+	err := RepositoryUnderTest.Insert(context.Background(), status)
 	if err != nil {
 		fmt.Printf("\t\tThe insert should have been successful %v", test.CheckMark)
 	} else {
 		fmt.Errorf("\t\tThe insert should have been successful %v", test.CheckMark)
 	}
 }
+
+// Helper function
+func publishRange(versionRange, status, platform string) {
+	release := model.ReleaseDAO{Status: status, Range: versionRange, Platform: platform, Released: time.Now()}
+	if err := RepositoryUnderTest.Insert(context.Background(), release); err != nil {
+		fmt.Printf("\t\tThe insert should have been successful %v", test.BallotX)
+	}
+}
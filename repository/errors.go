@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// duplicateKeyCode the code of the mongo write errors violating a unique index
+const duplicateKeyCode = 11000
+
+var (
+	// ErrNotFound the error of the queries matching no data
+	ErrNotFound = errors.New(NotFoundErrorMessage)
+
+	// ErrConflict the error of the writes conflicting with the data already stored
+	ErrConflict = errors.New("conflict")
+
+	// ErrUnavailable the error of the operations the data store did not complete. The errors matching it are
+	// UnavailableError values telling whether the deadline of the operation passed.
+	ErrUnavailable = errors.New("unavailable")
+
+	// ErrInvalid the error of the operations given data the data store cannot hold
+	ErrInvalid = errors.New("invalid")
+)
+
+// UnavailableError the error of an operation the data store did not complete, either because its deadline passed or
+// because it was cancelled, e.g. by the client disconnecting
+type UnavailableError struct {
+
+	// Op the operation on the data store, e.g. "find"
+	Op string
+
+	// Err the cause, either context.DeadlineExceeded or context.Canceled
+	Err error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("the data store is unavailable to %s: %v", e.Op, e.Err)
+}
+
+// Unwrap returns the cause of the error
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// Is tells whether the target is ErrUnavailable, for the error to be matched by errors.Is
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// Timeout tells whether the deadline of the operation passed
+func (e *UnavailableError) Timeout() bool {
+	return errors.Is(e.Err, context.DeadlineExceeded)
+}
+
+// failure types the error of an operation on the data store, reporting the operations whose context is done as
+// unavailable and the writes violating a unique index as conflicts
+func failure(ctx context.Context, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return &UnavailableError{Op: op, Err: ctx.Err()}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &UnavailableError{Op: op, Err: err}
+	}
+	if duplicateKey(err) {
+		return fmt.Errorf("%w: %s: %v", ErrConflict, op, err)
+	}
+	return err
+}
+
+// duplicateKey tells whether the given error is the violation of a unique index, reported either by a write or by a
+// command such as an upserting findAndModify
+func duplicateKey(err error) bool {
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) {
+		for _, e := range writeErr.WriteErrors {
+			if e.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	}
+	var commandErr mongo.CommandError
+	return errors.As(err, &commandErr) && commandErr.Code == duplicateKeyCode
+}
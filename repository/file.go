@@ -0,0 +1,445 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/akhettar/app-features-manager/model"
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	journalRelease    = "release"
+	journalThresholds = "thresholds"
+	journalAudit      = "audit"
+	journalDelete     = "delete"
+	journalFlag       = "flag"
+	journalDeleteFlag = "deleteFlag"
+
+	// compactionMinRecords the number of records the journal must hold before it is considered for compaction
+	compactionMinRecords = 1000
+
+	// maxRecordSize the largest record accepted when replaying the journal, the maximum size of a BSON document
+	maxRecordSize = 16 * 1024 * 1024
+)
+
+var (
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errCorruptRecord = errors.New("corrupt journal record")
+)
+
+// FileRepository a repository persisting its data to an append-only journal file, for small deployments and edge
+// sites running without a mongo instance. Every change is appended to the journal and synced to disk before being
+// applied to the in-memory state the queries are served from, hence it follows the semantics of MemoryRepository.
+// A record torn by a crash is discarded when the journal is replayed on start up, and the journal is compacted once
+// the records of the deleted releases and of the replaced flags make up most of it.
+type FileRepository struct {
+	*MemoryRepository
+	*journal
+}
+
+// journal the append-only file the changes are recorded to, shared by the views of the repository scoped to each
+// app. Every record is a BSON document followed by its CRC-32C checksum.
+type journal struct {
+	sync.Mutex
+	path    string
+	file    *os.File
+	size    int64
+	records int
+}
+
+// journalEntry a change recorded to the journal. The documents are not omitted when empty since the releases and the
+// thresholds are deemed empty by the IsZero method of their messaging.
+type journalEntry struct {
+	Op         string               `bson:"op"`
+	App        string               `bson:"app,omitempty"`
+	Release    *model.ReleaseDAO    `bson:"release"`
+	Thresholds *model.ThresholdsDAO `bson:"thresholds"`
+	Audit      *model.AuditDAO      `bson:"audit"`
+	Key        *model.ReleaseKey    `bson:"key"`
+	Flag       *model.FlagDAO       `bson:"flag,omitempty"`
+	Name       string               `bson:"name,omitempty"`
+}
+
+// NewFileRepository opens the repository journaled to the given file, creating the file when missing, and replays
+// the journal
+func NewFileRepository(path string) (*FileRepository, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	repo := &FileRepository{MemoryRepository: &MemoryRepository{memoryStore: &memoryStore{}},
+		journal: &journal{path: path, file: file}}
+	if err := repo.replay(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if repo.stale() {
+		if err := repo.compact(); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	log.Infof("Replayed %d records of the journal %s", repo.records, path)
+	return repo, nil
+}
+
+// ForApp returns a view of the repository scoped to the data of the given app
+func (repo *FileRepository) ForApp(app string) Repository {
+	return &FileRepository{MemoryRepository: &MemoryRepository{memoryStore: repo.memoryStore, App: app},
+		journal: repo.journal}
+}
+
+// Insert stores a new state of a release, given either as a model.ReleaseDAO or a pointer to it
+func (repo *FileRepository) Insert(ctx context.Context, body interface{}) error {
+	var release model.ReleaseDAO
+	switch value := body.(type) {
+	case model.ReleaseDAO:
+		release = value
+	case *model.ReleaseDAO:
+		release = *value
+	default:
+		return fmt.Errorf("%w: unsupported document type %T", ErrInvalid, body)
+	}
+
+	repo.journal.Lock()
+	defer repo.journal.Unlock()
+	if err := repo.append(journalEntry{Op: journalRelease, App: repo.App, Release: &release}); err != nil {
+		return err
+	}
+	return repo.MemoryRepository.Insert(ctx, body)
+}
+
+// InsertThresholds stores new version thresholds for a platform, superseding the previous ones
+func (repo *FileRepository) InsertThresholds(ctx context.Context, thresholds model.ThresholdsDAO) error {
+	repo.journal.Lock()
+	defer repo.journal.Unlock()
+	if err := repo.append(journalEntry{Op: journalThresholds, App: repo.App, Thresholds: &thresholds}); err != nil {
+		return err
+	}
+	return repo.MemoryRepository.InsertThresholds(ctx, thresholds)
+}
+
+// InsertAudit appends a record to the audit trail. Audit records are never updated nor deleted.
+func (repo *FileRepository) InsertAudit(ctx context.Context, record model.AuditDAO) error {
+	repo.journal.Lock()
+	defer repo.journal.Unlock()
+	if err := repo.append(journalEntry{Op: journalAudit, App: repo.App, Audit: &record}); err != nil {
+		return err
+	}
+	return repo.MemoryRepository.InsertAudit(ctx, record)
+}
+
+// Delete removes the given release along with its whole history
+func (repo *FileRepository) Delete(ctx context.Context, key model.ReleaseKey) error {
+	repo.journal.Lock()
+	defer repo.journal.Unlock()
+	if _, err := repo.History(ctx, key); err != nil {
+		return err
+	}
+	if err := repo.append(journalEntry{Op: journalDelete, App: repo.App, Key: &key}); err != nil {
+		return err
+	}
+	if err := repo.MemoryRepository.Delete(ctx, key); err != nil {
+		return err
+	}
+	repo.compactStale()
+	return nil
+}
+
+// SaveFlag stores the definition of a flag, replacing the previous one
+func (repo *FileRepository) SaveFlag(ctx context.Context, flag model.FlagDAO) error {
+	repo.journal.Lock()
+	defer repo.journal.Unlock()
+	if err := repo.append(journalEntry{Op: journalFlag, App: repo.App, Flag: &flag}); err != nil {
+		return err
+	}
+	if err := repo.MemoryRepository.SaveFlag(ctx, flag); err != nil {
+		return err
+	}
+	repo.compactStale()
+	return nil
+}
+
+// DeleteFlag removes the definition of the given flag
+func (repo *FileRepository) DeleteFlag(ctx context.Context, name string) error {
+	repo.journal.Lock()
+	defer repo.journal.Unlock()
+	if _, err := repo.FindFlag(ctx, name); err != nil {
+		return err
+	}
+	if err := repo.append(journalEntry{Op: journalDeleteFlag, App: repo.App, Name: name}); err != nil {
+		return err
+	}
+	if err := repo.MemoryRepository.DeleteFlag(ctx, name); err != nil {
+		return err
+	}
+	repo.compactStale()
+	return nil
+}
+
+// Compact rewrites the journal with the current state of the repository only, dropping the records of the deleted
+// releases. The compacted journal is written to a temporary file which then atomically replaces the journal.
+func (repo *FileRepository) Compact() error {
+	repo.journal.Lock()
+	defer repo.journal.Unlock()
+	return repo.compact()
+}
+
+// Close closes the journal file. The repository must not be used afterwards.
+func (repo *FileRepository) Close() error {
+	repo.journal.Lock()
+	defer repo.journal.Unlock()
+	return repo.file.Close()
+}
+
+// replay applies the records of the journal to the in-memory state. A record torn at the end of the journal by a
+// crash in the middle of a write is truncated, whereas a corrupt record followed by other records fails the replay,
+// even when its corrupt length runs past the end of the journal.
+func (repo *FileRepository) replay() error {
+	info, err := repo.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(repo.file)
+	var offset int64
+	for {
+		entry, size, err := readRecord(reader)
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			if err := repo.apply(entry); err != nil {
+				return fmt.Errorf("failed to replay the record of the journal %s at offset %d: %v", repo.path, offset, err)
+			}
+			offset += size
+			repo.records++
+			continue
+		}
+		if err != io.ErrUnexpectedEOF && offset+size < info.Size() {
+			return fmt.Errorf("the journal %s is corrupt at offset %d: %v", repo.path, offset, err)
+		}
+		follows, err := recordFollows(repo.file, offset, info.Size())
+		if err != nil {
+			return err
+		}
+		if follows {
+			return fmt.Errorf("the journal %s is corrupt at offset %d: the length of the record runs past the "+
+				"records following it", repo.path, offset)
+		}
+		log.Warnf("Discarding the %d bytes of the record torn at the end of the journal %s", info.Size()-offset, repo.path)
+		if err := repo.file.Truncate(offset); err != nil {
+			return err
+		}
+		if err := repo.file.Sync(); err != nil {
+			return err
+		}
+		break
+	}
+	repo.size = offset
+	return nil
+}
+
+// apply applies a record of the journal to the in-memory state
+func (repo *FileRepository) apply(entry journalEntry) error {
+	view := repo.MemoryRepository.ForApp(entry.App)
+	switch {
+	case entry.Op == journalRelease && entry.Release != nil:
+		return view.Insert(context.Background(), *entry.Release)
+	case entry.Op == journalThresholds && entry.Thresholds != nil:
+		return view.InsertThresholds(context.Background(), *entry.Thresholds)
+	case entry.Op == journalAudit && entry.Audit != nil:
+		return view.InsertAudit(context.Background(), *entry.Audit)
+	case entry.Op == journalDelete && entry.Key != nil:
+		return view.Delete(context.Background(), *entry.Key)
+	case entry.Op == journalFlag && entry.Flag != nil:
+		return view.SaveFlag(context.Background(), *entry.Flag)
+	case entry.Op == journalDeleteFlag && entry.Name != "":
+		return view.DeleteFlag(context.Background(), entry.Name)
+	}
+	return errCorruptRecord
+}
+
+// stale tells whether the records of the deleted releases and of the replaced flags make up most of the journal
+func (repo *FileRepository) stale() bool {
+	repo.memoryStore.RLock()
+	defer repo.memoryStore.RUnlock()
+	live := len(repo.releases) + len(repo.thresholds) + len(repo.audit) + len(repo.flags)
+	return repo.records >= compactionMinRecords && repo.records > 2*live
+}
+
+// compactStale compacts the journal when stale. The change is applied whether or not the journal can be compacted,
+// which is attempted again on the next change superseding records. The journal must be locked.
+func (repo *FileRepository) compactStale() {
+	if repo.stale() {
+		if err := repo.compact(); err != nil {
+			log.Errorf("Failed to compact the journal %s: %v", repo.path, err)
+		}
+	}
+}
+
+// compact rewrites the journal with the current state of the repository. The journal must be locked.
+func (repo *FileRepository) compact() error {
+	repo.memoryStore.RLock()
+	entries := make([]journalEntry, 0, len(repo.releases)+len(repo.thresholds)+len(repo.audit)+len(repo.flags))
+	for i := range repo.releases {
+		release := repo.releases[i]
+		entries = append(entries, journalEntry{Op: journalRelease, App: release.App, Release: &release})
+	}
+	for i := range repo.thresholds {
+		thresholds := repo.thresholds[i]
+		entries = append(entries, journalEntry{Op: journalThresholds, App: thresholds.App, Thresholds: &thresholds})
+	}
+	for i := range repo.audit {
+		record := repo.audit[i]
+		entries = append(entries, journalEntry{Op: journalAudit, App: record.App, Audit: &record})
+	}
+	for i := range repo.flags {
+		flag := repo.flags[i]
+		entries = append(entries, journalEntry{Op: journalFlag, App: flag.App, Flag: &flag})
+	}
+	repo.memoryStore.RUnlock()
+
+	temp := repo.path + ".compact"
+	file, err := os.OpenFile(temp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	size, err := writeRecords(file, entries)
+	if err == nil {
+		err = os.Rename(temp, repo.path)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(temp)
+		return err
+	}
+	syncDir(repo.path)
+
+	// the handle of the temporary file now refers to the journal
+	repo.file.Close()
+	repo.file, repo.size, repo.records = file, size, len(entries)
+	return nil
+}
+
+// append records the entry at the end of the journal and syncs it to disk. A failed write is truncated so that the
+// journal never holds a partial record.
+func (j *journal) append(entry journalEntry) error {
+	record, err := encodeRecord(entry)
+	if err != nil {
+		return err
+	}
+	if _, err = j.file.WriteAt(record, j.size); err == nil {
+		err = j.file.Sync()
+	}
+	if err != nil {
+		j.file.Truncate(j.size)
+		return err
+	}
+	j.size += int64(len(record))
+	j.records++
+	return nil
+}
+
+// writeRecords writes the entries to the given file and syncs it, returning the size written
+func writeRecords(file *os.File, entries []journalEntry) (int64, error) {
+	writer := bufio.NewWriter(file)
+	var size int64
+	for _, entry := range entries {
+		record, err := encodeRecord(entry)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := writer.Write(record); err != nil {
+			return 0, err
+		}
+		size += int64(len(record))
+	}
+	if err := writer.Flush(); err != nil {
+		return 0, err
+	}
+	return size, file.Sync()
+}
+
+// encodeRecord encodes the entry as a BSON document followed by its checksum
+func encodeRecord(entry journalEntry) ([]byte, error) {
+	document, err := bson.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	record := make([]byte, len(document)+crc32.Size)
+	copy(record, document)
+	binary.LittleEndian.PutUint32(record[len(document):], crc32.Checksum(document, crcTable))
+	return record, nil
+}
+
+// readRecord reads the next record of the journal along with its size, as declared by the length of its document.
+// io.EOF is returned at the end of the journal and io.ErrUnexpectedEOF when the record is cut short.
+func readRecord(reader io.Reader) (journalEntry, int64, error) {
+	var entry journalEntry
+	var header [4]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return entry, 0, err
+	}
+	length := binary.LittleEndian.Uint32(header[:])
+	size := int64(length) + crc32.Size
+	if length < 5 || length > maxRecordSize {
+		return entry, size, errCorruptRecord
+	}
+
+	record := make([]byte, size)
+	copy(record, header[:])
+	if _, err := io.ReadFull(reader, record[len(header):]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return entry, size, err
+	}
+	document := record[:length]
+	if crc32.Checksum(document, crcTable) != binary.LittleEndian.Uint32(record[length:]) {
+		return entry, size, errCorruptRecord
+	}
+	if err := bson.Unmarshal(document, &entry); err != nil {
+		return entry, size, errCorruptRecord
+	}
+	return entry, size, nil
+}
+
+// recordFollows tells whether a valid record starts past the given offset of the file, before the given end, in which
+// case the record at the offset is corrupt rather than the last one torn by a crash. A record is deemed valid when
+// its checksum matches.
+func recordFollows(file *os.File, offset, end int64) (bool, error) {
+	tail := make([]byte, end-offset)
+	if _, err := file.ReadAt(tail, offset); err != nil {
+		return false, err
+	}
+	for i := 1; i+4 <= len(tail); i++ {
+		length := int(binary.LittleEndian.Uint32(tail[i:]))
+		if length < 5 || length > maxRecordSize || i+length+crc32.Size > len(tail) {
+			continue
+		}
+		if crc32.Checksum(tail[i:i+length], crcTable) == binary.LittleEndian.Uint32(tail[i+length:]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// syncDir syncs the directory of the given file for a rename to be durable. Syncing a directory is not supported on
+// every platform, hence it is best effort.
+func syncDir(path string) {
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+}
@@ -0,0 +1,202 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/akhettar/app-features-manager/model"
+	. "github.com/akhettar/app-features-manager/repository"
+	"github.com/akhettar/app-features-manager/test"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestFileRepository_ShouldReplayTheJournal checks the data survives reopening the journal
+func TestFileRepository_ShouldReplayTheJournal(t *testing.T) {
+	journal := tempJournal(t)
+	defer os.RemoveAll(filepath.Dir(journal))
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	t.Logf("Given releases, thresholds, flags, a deleted release and the release of another app journaled to a file")
+	{
+		repo := openJournal(journal, t)
+		mustInsert(repo, model.ReleaseDAO{Version: "1.0.0", Platform: "ios", Status: "deprecated", Released: base.Add(time.Second)}, t)
+		mustInsert(repo, model.ReleaseDAO{Version: "1.0.0", Platform: "ios", Status: "supported", Released: base}, t)
+		mustInsert(repo, model.ReleaseDAO{Version: "2.0.0", Platform: "ios", Status: "supported", Released: base}, t)
+		mustInsert(repo.ForApp("com.acme.other"), model.ReleaseDAO{Version: "3.0.0", Platform: "ios", Status: "supported", Released: base}, t)
+		thresholds := model.ThresholdsDAO{Thresholds: model.Thresholds{MinSupported: "2.0.0"}, Platform: "android", Released: base}
+		if err := repo.InsertThresholds(context.Background(), thresholds); err != nil {
+			t.Fatalf("\t\tThe thresholds should have been stored %v %v", err, test.BallotX)
+		}
+		if err := repo.Delete(context.Background(), model.ReleaseKey{Platform: "ios", Version: "2.0.0"}); err != nil {
+			t.Fatalf("\t\tThe release should have been deleted %v %v", err, test.BallotX)
+		}
+		test.Ok(repo.SaveFlag(context.Background(), model.FlagDAO{Name: "Dark", Enabled: true, Updated: base}), t)
+		test.Ok(repo.SaveFlag(context.Background(), model.FlagDAO{Name: "Chat", Updated: base}), t)
+		test.Ok(repo.DeleteFlag(context.Background(), "Chat"), t)
+		repo.Close()
+
+		t.Logf("\tWhen reopening the journal")
+		{
+			repo := openJournal(journal, t)
+			defer repo.Close()
+			expectStatus(repo, "1.0.0", "ios", time.Now(), "deprecated", t)
+			expectStatus(repo, "2.0.0", "ios", time.Now(), "", t)
+			expectStatus(repo, "3.0.0", "ios", time.Now(), "", t)
+			expectStatus(repo.ForApp("com.acme.other"), "3.0.0", "ios", time.Now(), "supported", t)
+			expectStatus(repo, "1.0.0", "android", time.Now(), "unsupported", t)
+			if flags, err := repo.FindFlags(context.Background()); err == nil && len(flags) == 1 && flags[0].Enabled {
+				t.Logf("\t\tThe flags should have been replayed %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe flags should have been replayed %v %v %v", flags, err, test.BallotX)
+			}
+		}
+	}
+}
+
+// TestFileRepository_ShouldDiscardATornRecord checks a record cut short by a crash is dropped on start up
+func TestFileRepository_ShouldDiscardATornRecord(t *testing.T) {
+	journal := tempJournal(t)
+	defer os.RemoveAll(filepath.Dir(journal))
+
+	t.Logf("Given a journal whose last record was cut short")
+	{
+		repo := openJournal(journal, t)
+		mustInsert(repo, model.ReleaseDAO{Version: "1.0.0", Platform: "ios", Status: "supported", Released: time.Now()}, t)
+		mustInsert(repo, model.ReleaseDAO{Version: "2.0.0", Platform: "ios", Status: "supported", Released: time.Now()}, t)
+		repo.Close()
+		info, err := os.Stat(journal)
+		test.Ok(err, t)
+		test.Ok(os.Truncate(journal, info.Size()-3), t)
+
+		t.Logf("\tWhen reopening the journal")
+		{
+			repo := openJournal(journal, t)
+			expectStatus(repo, "1.0.0", "ios", time.Now(), "supported", t)
+			expectStatus(repo, "2.0.0", "ios", time.Now(), "", t)
+			mustInsert(repo, model.ReleaseDAO{Version: "3.0.0", Platform: "ios", Status: "supported", Released: time.Now()}, t)
+			repo.Close()
+		}
+
+		t.Logf("\tWhen reopening the journal after appending to it")
+		{
+			repo := openJournal(journal, t)
+			defer repo.Close()
+			expectStatus(repo, "1.0.0", "ios", time.Now(), "supported", t)
+			expectStatus(repo, "3.0.0", "ios", time.Now(), "supported", t)
+		}
+	}
+
+	t.Logf("Given a journal corrupt in the middle")
+	{
+		data, err := ioutil.ReadFile(journal)
+		test.Ok(err, t)
+		data[10] ^= 0xff
+		test.Ok(ioutil.WriteFile(journal, data, 0644), t)
+
+		t.Logf("\tWhen reopening the journal")
+		{
+			if _, err := NewFileRepository(journal); err != nil {
+				t.Logf("\t\tThe journal should fail to open %v %v", err, test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe journal should fail to open %v", test.BallotX)
+			}
+		}
+	}
+}
+
+// TestFileRepository_ShouldRejectACorruptLength checks a record whose corrupt length runs past the end of the journal
+// fails the replay rather than being discarded as torn along with the records following it
+func TestFileRepository_ShouldRejectACorruptLength(t *testing.T) {
+	journal := tempJournal(t)
+	defer os.RemoveAll(filepath.Dir(journal))
+
+	t.Logf("Given a journal whose second record declares a length running past the end of the journal")
+	{
+		repo := openJournal(journal, t)
+		mustInsert(repo, model.ReleaseDAO{Version: "1.0.0", Platform: "ios", Status: "supported", Released: time.Now()}, t)
+		info, err := os.Stat(journal)
+		test.Ok(err, t)
+		mustInsert(repo, model.ReleaseDAO{Version: "2.0.0", Platform: "ios", Status: "supported", Released: time.Now()}, t)
+		mustInsert(repo, model.ReleaseDAO{Version: "3.0.0", Platform: "ios", Status: "supported", Released: time.Now()}, t)
+		repo.Close()
+		data, err := ioutil.ReadFile(journal)
+		test.Ok(err, t)
+		binary.LittleEndian.PutUint32(data[info.Size():], uint32(len(data)))
+		test.Ok(ioutil.WriteFile(journal, data, 0644), t)
+
+		t.Logf("\tWhen reopening the journal")
+		{
+			if _, err := NewFileRepository(journal); err != nil {
+				t.Logf("\t\tThe journal should fail to open %v %v", err, test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe journal should fail to open %v", test.BallotX)
+			}
+			if kept, err := ioutil.ReadFile(journal); err == nil && len(kept) == len(data) {
+				t.Logf("\t\tThe records should have been kept %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe records should have been kept %d %v %v", len(kept), err, test.BallotX)
+			}
+		}
+	}
+}
+
+// TestFileRepository_ShouldCompactTheJournal checks compacting drops the records of the deleted releases only
+func TestFileRepository_ShouldCompactTheJournal(t *testing.T) {
+	journal := tempJournal(t)
+	defer os.RemoveAll(filepath.Dir(journal))
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	t.Logf("Given a journal holding the history of a deleted release")
+	{
+		repo := openJournal(journal, t)
+		for i := 0; i < 20; i++ {
+			mustInsert(repo, model.ReleaseDAO{Version: "1.0.0", Platform: "ios", Status: "supported", Released: base.Add(time.Duration(i) * time.Second)}, t)
+		}
+		mustInsert(repo, model.ReleaseDAO{Version: "2.0.0", Platform: "ios", Status: "supported", Released: base}, t)
+		mustInsert(repo, model.ReleaseDAO{Version: "2.0.0", Platform: "ios", Status: "deprecated", Released: base.Add(time.Second)}, t)
+		test.Ok(repo.Delete(context.Background(), model.ReleaseKey{Platform: "ios", Version: "1.0.0"}), t)
+		before, err := os.Stat(journal)
+		test.Ok(err, t)
+
+		t.Logf("\tWhen compacting the journal")
+		{
+			test.Ok(repo.Compact(), t)
+			after, err := os.Stat(journal)
+			test.Ok(err, t)
+			if after.Size() < before.Size() {
+				t.Logf("\t\tThe journal should have shrunk %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe journal should have shrunk %d %d %v", before.Size(), after.Size(), test.BallotX)
+			}
+			mustInsert(repo, model.ReleaseDAO{Version: "3.0.0", Platform: "ios", Status: "supported", Released: base}, t)
+			repo.Close()
+
+			repo := openJournal(journal, t)
+			defer repo.Close()
+			expectStatus(repo, "1.0.0", "ios", time.Now(), "", t)
+			expectStatus(repo, "2.0.0", "ios", time.Now(), "deprecated", t)
+			expectStatus(repo, "3.0.0", "ios", time.Now(), "supported", t)
+		}
+	}
+}
+
+// tempJournal returns the path of a journal in a new temporary directory
+func tempJournal(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("Failed to create the journal directory %v %v", err, test.BallotX)
+	}
+	return filepath.Join(dir, DefaultJournalPath)
+}
+
+// openJournal opens the file repository journaled to the given file
+func openJournal(journal string, t *testing.T) *FileRepository {
+	repo, err := NewFileRepository(journal)
+	if err != nil {
+		t.Fatalf("\t\tThe journal should have been opened %v %v", err, test.BallotX)
+	}
+	return repo
+}
@@ -1,9 +1,10 @@
-package repository
+package repository_test
 
 import (
 	"context"
 	"flag"
 	"fmt"
+	. "github.com/akhettar/app-features-manager/repository"
 	"github.com/akhettar/docker-db"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -18,16 +19,27 @@ const ProfileEnvVar = "PROFILE"
 
 var (
 	HttpServer          *httptest.Server
-	RepositoryUnderTest *MongoRepository
+	RepositoryUnderTest Repository
+
+	// MongoClient the client of the mongo instance the tests run against, nil when running against the in-memory
+	// repository
+	MongoClient *mongo.Client
 )
 
 // TestMain wraps all tests with the needed initialized mock DB and fixtures
 // This test runs before other integration test. It starts an instance of mongo db in the background (provided you have mongo
-// installed on the server on which this test will be running) and shuts it down.
+// installed on the server on which this test will be running) and shuts it down. When the in-memory backend is
+// selected through the REPOSITORY environment variable, the tests run against the in-memory repository instead.
 func TestMain(m *testing.M) {
 
 	flag.Parse()
 
+	if os.Getenv(Backend) == MemoryBackend {
+		log.Printf("running against the in-memory repository")
+		RepositoryUnderTest = NewMemoryRepository()
+		os.Exit(m.Run())
+	}
+
 	c := dbtest.StartMongoContainer()
 	log.Printf("running mongo %s:%d", c.Host(), c.Port())
 
@@ -47,7 +59,8 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 
-	RepositoryUnderTest = &MongoRepository{client, DBInfo{uri, DefaultDBName, DefaultCollection}}
+	MongoClient = client
+	RepositoryUnderTest = &MongoRepository{Client: client, DBInfo: DBInfo{uri, DefaultDBName, DefaultCollection}}
 
 	// Run the test suite
 	retCode := m.Run()
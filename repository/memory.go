@@ -0,0 +1,364 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/akhettar/app-features-manager/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryRepository a thread safe in-memory repository for local runs and tests. It follows the ordering and the
+// not found semantics of the mongo repository, times being stored with the millisecond precision of the document
+// store, but its data is lost on shutdown.
+type MemoryRepository struct {
+	*memoryStore
+
+	// App the identifier of the app the data is scoped to, the default app when empty
+	App string
+}
+
+// memoryStore the data shared by the views of the repository scoped to each app, in order of insertion
+type memoryStore struct {
+	sync.RWMutex
+	releases   []model.ReleaseDAO
+	thresholds []model.ThresholdsDAO
+	audit      []model.AuditDAO
+	flags      []model.FlagDAO
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository() Repository {
+	return &MemoryRepository{memoryStore: &memoryStore{}}
+}
+
+// ForApp returns a view of the repository scoped to the data of the given app
+func (repo *MemoryRepository) ForApp(app string) Repository {
+	return &MemoryRepository{memoryStore: repo.memoryStore, App: app}
+}
+
+// Insert stores a new state of a release, given either as a model.ReleaseDAO or a pointer to it
+func (repo *MemoryRepository) Insert(ctx context.Context, body interface{}) error {
+	var release model.ReleaseDAO
+	switch value := body.(type) {
+	case model.ReleaseDAO:
+		release = value
+	case *model.ReleaseDAO:
+		value.App = repo.App
+		release = *value
+	default:
+		return fmt.Errorf("%w: unsupported document type %T", ErrInvalid, body)
+	}
+	release.App = repo.App
+	release.Released = millis(release.Released)
+	schedule := make([]model.Transition, 0, len(release.Schedule))
+	for _, transition := range release.Schedule {
+		schedule = append(schedule, model.Transition{Status: transition.Status, Effective: millis(transition.Effective)})
+	}
+	if len(schedule) > 0 {
+		release.Schedule = schedule
+	}
+
+	repo.Lock()
+	defer repo.Unlock()
+	repo.releases = append(repo.releases, release)
+	return nil
+}
+
+// Find query the status of the app for given version, as resolved by MongoRepository.Find
+func (repo *MemoryRepository) Find(ctx context.Context, version, platform string) (model.ReleaseResponse, error) {
+	return repo.FindAt(ctx, version, platform, time.Now())
+}
+
+// FindAt query the status the app had for given version at the given time, ignoring anything released afterwards
+func (repo *MemoryRepository) FindAt(ctx context.Context, version, platform string, at time.Time) (model.ReleaseResponse, error) {
+	releases := repo.sortedReleases(func(release model.ReleaseDAO) bool {
+		return release.Platform == platform && !release.Released.After(at) &&
+			(release.Version == version || release.Range != "")
+	})
+	history := make([]*model.ReleaseDAO, 0, len(releases))
+	for i := range releases {
+		history = append(history, &releases[i])
+	}
+	return status(version, at, history, func() (model.ThresholdsDAO, error) {
+		return repo.FindThresholds(ctx, platform, at)
+	})
+}
+
+// InsertThresholds stores new version thresholds for a platform, superseding the previous ones
+func (repo *MemoryRepository) InsertThresholds(ctx context.Context, thresholds model.ThresholdsDAO) error {
+	thresholds.App = repo.App
+	thresholds.Released = millis(thresholds.Released)
+
+	repo.Lock()
+	defer repo.Unlock()
+	repo.thresholds = append(repo.thresholds, thresholds)
+	return nil
+}
+
+// FindThresholds query the version thresholds of the given platform in effect at the given time
+func (repo *MemoryRepository) FindThresholds(ctx context.Context, platform string, at time.Time) (model.ThresholdsDAO, error) {
+	repo.RLock()
+	defer repo.RUnlock()
+
+	var result *model.ThresholdsDAO
+	for i, thresholds := range repo.thresholds {
+		if thresholds.App != repo.App || thresholds.Platform != platform || thresholds.Released.After(at) {
+			continue
+		}
+		if result == nil || !thresholds.Released.Before(result.Released) {
+			result = &repo.thresholds[i]
+		}
+	}
+	if result == nil {
+		return model.ThresholdsDAO{}, ErrNotFound
+	}
+	return *result, nil
+}
+
+// List query the releases matching the filter in their current state, i.e. the latest state each was published with
+func (repo *MemoryRepository) List(ctx context.Context, filter model.ReleaseFilter) ([]model.ReleaseDAO, int64, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = model.DefaultPageLimit
+	}
+	skip := 0
+	if filter.Page > 1 {
+		skip = (filter.Page - 1) * filter.Limit
+	}
+
+	releases := []model.ReleaseDAO{}
+	for _, release := range repo.currentState(filter.Platform) {
+		if filter.Status == "" || release.Status == filter.Status {
+			releases = append(releases, release)
+		}
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		if !releases[i].Released.Equal(releases[j].Released) {
+			return releases[i].Released.Before(releases[j].Released) == filter.Ascending
+		}
+		return lessKey(releases[i].Key(), releases[j].Key())
+	})
+
+	total := int64(len(releases))
+	if skip >= len(releases) {
+		return []model.ReleaseDAO{}, total, nil
+	}
+	releases = releases[skip:]
+	if len(releases) > filter.Limit {
+		releases = releases[:filter.Limit]
+	}
+	return releases, total, nil
+}
+
+// Scheduled query the releases in their current state with transitions scheduled after the given time, the releases
+// of every platform when none is given
+func (repo *MemoryRepository) Scheduled(ctx context.Context, platform string, after time.Time) ([]model.ReleaseDAO, error) {
+	var results []model.ReleaseDAO
+	for _, release := range repo.currentState(platform) {
+		if len(release.Upcoming(after)) > 0 {
+			results = append(results, release)
+		}
+	}
+	return results, nil
+}
+
+// History query every state the given release was published with, sorted by released date
+func (repo *MemoryRepository) History(ctx context.Context, key model.ReleaseKey) ([]model.ReleaseDAO, error) {
+	results := repo.sortedReleases(func(release model.ReleaseDAO) bool {
+		return matchesKey(release, key)
+	})
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+	return results, nil
+}
+
+// Delete removes the given release along with its whole history
+func (repo *MemoryRepository) Delete(ctx context.Context, key model.ReleaseKey) error {
+	repo.Lock()
+	defer repo.Unlock()
+
+	kept := repo.releases[:0]
+	for _, release := range repo.releases {
+		if release.App != repo.App || !matchesKey(release, key) {
+			kept = append(kept, release)
+		}
+	}
+	deleted := len(repo.releases) - len(kept)
+	repo.releases = kept
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// InsertAudit appends a record to the audit trail. Audit records are never updated nor deleted.
+func (repo *MemoryRepository) InsertAudit(ctx context.Context, record model.AuditDAO) error {
+	record.App = repo.App
+	record.Timestamp = millis(record.Timestamp)
+
+	repo.Lock()
+	defer repo.Unlock()
+	repo.audit = append(repo.audit, record)
+	return nil
+}
+
+// FindAudit query the audit records matching the filter, the most recent first
+func (repo *MemoryRepository) FindAudit(ctx context.Context, filter model.AuditFilter) ([]model.AuditDAO, int64, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = model.DefaultPageLimit
+	}
+	skip := 0
+	if filter.Page > 1 {
+		skip = (filter.Page - 1) * filter.Limit
+	}
+
+	repo.RLock()
+	results := []model.AuditDAO{}
+	// the latest records first, so records sharing a timestamp are sorted by descending insertion order
+	for i := len(repo.audit) - 1; i >= 0; i-- {
+		record := repo.audit[i]
+		if record.App != repo.App ||
+			(filter.Version != "" && record.Version != filter.Version) ||
+			(filter.Range != "" && record.Range != filter.Range) ||
+			(filter.Platform != "" && record.Platform != filter.Platform) ||
+			(filter.Flag != "" && record.Flag != filter.Flag) ||
+			(filter.Actor != "" && record.Actor != filter.Actor) ||
+			(!filter.From.IsZero() && record.Timestamp.Before(filter.From)) ||
+			(!filter.To.IsZero() && !record.Timestamp.Before(filter.To)) {
+			continue
+		}
+		results = append(results, record)
+	}
+	repo.RUnlock()
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Timestamp.After(results[j].Timestamp) })
+	total := int64(len(results))
+	if skip >= len(results) {
+		return []model.AuditDAO{}, total, nil
+	}
+	results = results[skip:]
+	if len(results) > filter.Limit {
+		results = results[:filter.Limit]
+	}
+	return results, total, nil
+}
+
+// SaveFlag stores the definition of a flag, replacing the previous one
+func (repo *MemoryRepository) SaveFlag(ctx context.Context, flag model.FlagDAO) error {
+	flag.App = repo.App
+	flag.Updated = millis(flag.Updated)
+
+	repo.Lock()
+	defer repo.Unlock()
+	for i, existing := range repo.flags {
+		if existing.App == repo.App && existing.Name == flag.Name {
+			repo.flags[i] = flag
+			return nil
+		}
+	}
+	repo.flags = append(repo.flags, flag)
+	return nil
+}
+
+// FindFlag query the definition of the given flag
+func (repo *MemoryRepository) FindFlag(ctx context.Context, name string) (model.FlagDAO, error) {
+	repo.RLock()
+	defer repo.RUnlock()
+	for _, flag := range repo.flags {
+		if flag.App == repo.App && flag.Name == name {
+			return flag, nil
+		}
+	}
+	return model.FlagDAO{}, ErrNotFound
+}
+
+// FindFlags query the definitions of every flag, sorted by name
+func (repo *MemoryRepository) FindFlags(ctx context.Context) ([]model.FlagDAO, error) {
+	repo.RLock()
+	results := []model.FlagDAO{}
+	for _, flag := range repo.flags {
+		if flag.App == repo.App {
+			results = append(results, flag)
+		}
+	}
+	repo.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results, nil
+}
+
+// DeleteFlag removes the definition of the given flag
+func (repo *MemoryRepository) DeleteFlag(ctx context.Context, name string) error {
+	repo.Lock()
+	defer repo.Unlock()
+	for i, flag := range repo.flags {
+		if flag.App == repo.App && flag.Name == name {
+			repo.flags = append(repo.flags[:i], repo.flags[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// sortedReleases returns the releases of the app matching the predicate, sorted by released date
+func (repo *MemoryRepository) sortedReleases(match func(model.ReleaseDAO) bool) []model.ReleaseDAO {
+	repo.RLock()
+	var results []model.ReleaseDAO
+	for _, release := range repo.releases {
+		if release.App == repo.App && match(release) {
+			results = append(results, release)
+		}
+	}
+	repo.RUnlock()
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Released.Before(results[j].Released) })
+	return results
+}
+
+// currentState returns the releases of the given platform, or of every platform, in their current state: the last
+// one each was published with
+func (repo *MemoryRepository) currentState(platform string) []model.ReleaseDAO {
+	var keys []model.ReleaseKey
+	latest := make(map[model.ReleaseKey]model.ReleaseDAO)
+	for _, release := range repo.sortedReleases(func(release model.ReleaseDAO) bool {
+		return platform == "" || release.Platform == platform
+	}) {
+		key := release.Key()
+		if _, ok := latest[key]; !ok {
+			keys = append(keys, key)
+		}
+		latest[key] = release
+	}
+
+	results := make([]model.ReleaseDAO, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, latest[key])
+	}
+	return results
+}
+
+// matchesKey tells whether the document is a state of the given release
+func matchesKey(release model.ReleaseDAO, key model.ReleaseKey) bool {
+	if key.Range != "" {
+		return release.Platform == key.Platform && release.Range == key.Range
+	}
+	return release.Platform == key.Platform && release.Version == key.Version && release.Range == ""
+}
+
+// lessKey orders the release keys by platform, version then range
+func lessKey(a, b model.ReleaseKey) bool {
+	if a.Platform != b.Platform {
+		return a.Platform < b.Platform
+	}
+	if a.Version != b.Version {
+		return a.Version < b.Version
+	}
+	return a.Range < b.Range
+}
+
+// millis truncates the time to the millisecond precision of the dates stored in the document store
+func millis(t time.Time) time.Time {
+	return t.Truncate(time.Millisecond)
+}
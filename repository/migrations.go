@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"time"
+)
+
+const (
+
+	// lockID the identifier of the document of the migrations collection held by the replica migrating the schema
+	lockID = "lock"
+
+	// lockLease the time the lock is held for before another replica may take it over, renewed every lockRenewal
+	// while the migrations run
+	lockLease = time.Minute
+
+	// lockRenewal the interval the replica migrating the schema renews the lease of the lock at
+	lockRenewal = lockLease / 3
+
+	// lockPoll the interval the replicas waiting for the lock poll it at
+	lockPoll = time.Second
+)
+
+// Migration a change of the schema of the data store, applied once. The migrations are applied in the order of their
+// version and must never be changed once released: changing the schema again takes a new migration.
+type Migration struct {
+
+	// Version the version of the schema once the migration is applied, starting from 1
+	Version int
+
+	// Description what the migration changes, recorded along with it
+	Description string
+
+	// Up applies the migration to the given database holding the collections of the given info
+	Up func(ctx context.Context, db *mongo.Database, info DBInfo) error
+}
+
+// Migrations the migrations of the schema, by version. The releases stored before the migrations need no reshaping:
+// lacking an app and a range, they are read as the exact releases of the default app.
+var Migrations = []Migration{
+	{Version: 1, Description: "index the releases, thresholds and audit records by the fields they are queried by", Up: createIndexes},
+	{Version: 2, Description: "index the flags of each app by their unique name", Up: createFlagIndexes},
+}
+
+// migrationRecord the document recording an applied migration
+type migrationRecord struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	Applied     time.Time `bson:"applied"`
+}
+
+// Migrate applies the migrations not applied yet, in the order of their version, and records them in the migrations
+// collection. The replicas migrating the schema concurrently take turns through a lock held in the same collection,
+// whose lease is renewed for as long as the migrations run: the replicas waiting for the lock find the migrations
+// applied once they get it. Migrating stops at the first migration failing, the next one resuming from it.
+func (repo *MongoRepository) Migrate(ctx context.Context, migrations []Migration) error {
+	db := repo.Client.Database(repo.DBInfo.Database)
+	collection := db.Collection(repo.DBInfo.MigrationsCollection())
+	owner, err := repo.lock(ctx, collection)
+	if err != nil {
+		return err
+	}
+	defer repo.unlock(collection, owner)
+
+	ctx, stop := context.WithCancel(ctx)
+	held := make(chan error, 1)
+	go func() { held <- repo.hold(ctx, stop, collection, owner) }()
+	err = repo.migrate(ctx, db, collection, migrations)
+	stop()
+	if lost := <-held; lost != nil {
+		return lost
+	}
+	return err
+}
+
+// migrate applies the migrations newer than the version of the schema, recording them in the given collection
+func (repo *MongoRepository) migrate(ctx context.Context, db *mongo.Database, collection *mongo.Collection,
+	migrations []Migration) error {
+	version, err := repo.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	for _, migration := range sortMigrations(migrations) {
+		if migration.Version <= version {
+			continue
+		}
+		log.Infof("Migrating the schema to version %d: %s", migration.Version, migration.Description)
+		if err := migration.Up(ctx, db, repo.DBInfo); err != nil {
+			return fmt.Errorf("failed to migrate the schema to version %d: %w", migration.Version, failure(ctx, "migrate", err))
+		}
+		record := migrationRecord{Version: migration.Version, Description: migration.Description, Applied: time.Now()}
+		if _, err := collection.InsertOne(ctx, record); err != nil {
+			return failure(ctx, "record migration", err)
+		}
+	}
+	return nil
+}
+
+// SchemaVersion query the version of the schema, i.e. the version of the last migration applied, 0 when none was
+func (repo *MongoRepository) SchemaVersion(ctx context.Context) (int, error) {
+	var record migrationRecord
+	err := repo.Client.Database(repo.DBInfo.Database).Collection(repo.DBInfo.MigrationsCollection()).FindOne(ctx,
+		bson.M{"_id": bson.M{"$ne": lockID}}, options.FindOne().SetSort(bson.M{"_id": -1})).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, failure(ctx, "find schema version", err)
+	}
+	return record.Version, nil
+}
+
+// lock takes the lock of the migrations, waiting for the replica holding it to release it or for its lease to
+// expire. The owner of the lock is returned.
+func (repo *MongoRepository) lock(ctx context.Context, collection *mongo.Collection) (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	owner := hex.EncodeToString(id)
+	for {
+		now := time.Now()
+		err := collection.FindOneAndUpdate(ctx, bson.M{"_id": lockID, "expires": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": owner, "expires": now.Add(lockLease)}},
+			options.FindOneAndUpdate().SetUpsert(true)).Err()
+		if err == nil || err == mongo.ErrNoDocuments {
+			return owner, nil
+		}
+		if !duplicateKey(err) {
+			return "", failure(ctx, "lock migrations", err)
+		}
+		log.Infof("Waiting for another replica to migrate the schema")
+		select {
+		case <-ctx.Done():
+			return "", failure(ctx, "lock migrations", ctx.Err())
+		case <-time.After(lockPoll):
+		}
+	}
+}
+
+// renew extends the lease of the lock held by the given owner, failing when another replica took it over
+func (repo *MongoRepository) renew(ctx context.Context, collection *mongo.Collection, owner string) error {
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": lockID, "owner": owner},
+		bson.M{"$set": bson.M{"expires": time.Now().Add(lockLease)}})
+	if err != nil {
+		return failure(ctx, "lock migrations", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: the lock of the migrations was taken over", ErrConflict)
+	}
+	return nil
+}
+
+// hold renews the lease of the lock held by the given owner every lockRenewal until the given context is done. The
+// migrations are cancelled through the given function when the lease cannot be renewed, before it expires, the error
+// being returned.
+func (repo *MongoRepository) hold(ctx context.Context, cancel context.CancelFunc, collection *mongo.Collection,
+	owner string) error {
+	ticker := time.NewTicker(lockRenewal)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if err := repo.renew(ctx, collection, owner); err != nil && ctx.Err() == nil {
+			cancel()
+			return err
+		}
+	}
+}
+
+// unlock releases the lock held by the given owner, even when the context of the migrations is done
+func (repo *MongoRepository) unlock(collection *mongo.Collection, owner string) {
+	ctx, cancel := context.WithTimeout(context.Background(), repo.Timeouts.Write+time.Second)
+	defer cancel()
+	if _, err := collection.DeleteOne(ctx, bson.M{"_id": lockID, "owner": owner}); err != nil {
+		log.Warnf("Failed to release the lock of the migrations, it expires in %v: %v", lockLease, err)
+	}
+}
+
+// sortMigrations sorts a copy of the given migrations by version
+func sortMigrations(migrations []Migration) []Migration {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return sorted
+}
+
+// createIndexes creates the compound indexes serving the queries of the releases, of the thresholds and of the audit
+// trail, all of them scoped to an app
+func createIndexes(ctx context.Context, db *mongo.Database, info DBInfo) error {
+	indexes := map[string][]mongo.IndexModel{
+		info.Collection: {
+			{Keys: bson.D{{Key: "app", Value: 1}, {Key: "platform", Value: 1}, {Key: "version", Value: 1}, {Key: "released", Value: 1}},
+				Options: options.Index().SetName("app_platform_version_released")},
+			{Keys: bson.D{{Key: "app", Value: 1}, {Key: "platform", Value: 1}, {Key: "range", Value: 1}, {Key: "released", Value: 1}},
+				Options: options.Index().SetName("app_platform_range_released")},
+		},
+		info.ThresholdsCollection(): {
+			{Keys: bson.D{{Key: "app", Value: 1}, {Key: "platform", Value: 1}, {Key: "released", Value: -1}},
+				Options: options.Index().SetName("app_platform_released")},
+		},
+		info.AuditCollection(): {
+			{Keys: bson.D{{Key: "app", Value: 1}, {Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}},
+				Options: options.Index().SetName("app_timestamp")},
+		},
+	}
+	for collection, models := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createFlagIndexes creates the unique index of the flags of each app by name
+func createFlagIndexes(ctx context.Context, db *mongo.Database, info DBInfo) error {
+	_, err := db.Collection(info.FlagsCollection()).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "app", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetName("app_name").SetUnique(true)})
+	return err
+}
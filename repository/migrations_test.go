@@ -0,0 +1,65 @@
+package repository_test
+
+import (
+	"context"
+	. "github.com/akhettar/app-features-manager/repository"
+	"github.com/akhettar/app-features-manager/test"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestMongoRepository_ShouldMigrateTheSchemaOnce checks the migrations are applied once, however many replicas run them
+func TestMongoRepository_ShouldMigrateTheSchemaOnce(t *testing.T) {
+	if MongoClient == nil {
+		t.Skip("the migrations only apply to the mongo repository")
+	}
+	repo := &MongoRepository{Client: MongoClient, DBInfo: DBInfo{Database: DefaultDBName, Collection: "migrations_once"}}
+
+	t.Logf("Given the schema was never migrated")
+	{
+		var applied int32
+		migrations := append(Migrations, Migration{Version: len(Migrations) + 1, Description: "count the runs",
+			Up: func(ctx context.Context, db *mongo.Database, info DBInfo) error {
+				atomic.AddInt32(&applied, 1)
+				return nil
+			}})
+
+		t.Logf("\tWhen several replicas migrate the schema concurrently")
+		{
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					test.Ok(repo.Migrate(context.Background(), migrations), t)
+				}()
+			}
+			wg.Wait()
+
+			if applied == 1 {
+				t.Logf("\t\tThe migration should have been applied once %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe migration should have been applied once %d %v", applied, test.BallotX)
+			}
+			version, err := repo.SchemaVersion(context.Background())
+			test.Ok(err, t)
+			if version == len(migrations) {
+				t.Logf("\t\tThe schema should be at version %d %v", version, test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe schema should be at version %d %d %v", len(migrations), version, test.BallotX)
+			}
+			cursor, err := MongoClient.Database(DefaultDBName).Collection(repo.Collection).Indexes().List(context.Background())
+			test.Ok(err, t)
+			var indexes []bson.M
+			test.Ok(cursor.All(context.Background(), &indexes), t)
+			if len(indexes) == 3 {
+				t.Logf("\t\tThe releases should have been indexed %v", test.CheckMark)
+			} else {
+				t.Errorf("\t\tThe releases should have been indexed %v %v", indexes, test.BallotX)
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"github.com/akhettar/app-features-manager/model"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+// status resolves the status of the given version at the given time from the release history of its platform, sorted
+// by released date. Explicit releases take precedence over the thresholds of the platform, which are only queried
+// when no release governs the version.
+func status(version string, at time.Time, history []*model.ReleaseDAO,
+	thresholds func() (model.ThresholdsDAO, error)) (model.ReleaseResponse, error) {
+
+	if release, ok := resolve(version, history); ok {
+		response := model.ReleaseResponse{Status: release.StatusAt(at), Messaging: release.Messaging}
+		if upcoming := release.Upcoming(at); len(upcoming) > 0 {
+			response.Until = upcoming[0].Effective
+		}
+		return response, nil
+	}
+	t, err := thresholds()
+	if err != nil {
+		return model.ReleaseResponse{}, err
+	}
+	v, err := model.ParseVersion(version)
+	if err != nil {
+		return model.ReleaseResponse{}, ErrNotFound
+	}
+	return model.ReleaseResponse{Status: t.Status(v), Messaging: t.Messaging}, nil
+}
+
+// resolve finds the release governing the given version in the release history of a platform, which must be
+// sorted by released date. A release published for the exact version always wins. Otherwise the range policies
+// are considered: only the latest release of each range is effective, and among the ranges the version satisfies
+// the most specific one wins, the latest published breaking ties.
+func resolve(version string, history []*model.ReleaseDAO) (*model.ReleaseDAO, bool) {
+
+	// the latest release of each range, in order of first publication
+	var ranges []string
+	latest := make(map[string]*model.ReleaseDAO)
+
+	var exact *model.ReleaseDAO
+	for _, release := range history {
+		if release.Range == "" {
+			if release.Version == version {
+				exact = release
+			}
+			continue
+		}
+		if _, ok := latest[release.Range]; !ok {
+			ranges = append(ranges, release.Range)
+		}
+		latest[release.Range] = release
+	}
+	if exact != nil {
+		return exact, true
+	}
+
+	v, err := model.ParseVersion(version)
+	if err != nil {
+		return nil, false
+	}
+
+	var best *model.ReleaseDAO
+	var bestConstraint model.Constraint
+	for _, r := range ranges {
+		release := latest[r]
+		constraint, err := model.ParseConstraint(release.Range)
+		if err != nil {
+			log.Warnf("Ignoring release with invalid version range \"%s\": %v", release.Range, err)
+			continue
+		}
+		if !constraint.Check(v) {
+			continue
+		}
+		if best != nil {
+			cmp := constraint.CompareSpecificity(bestConstraint)
+			if cmp < 0 || (cmp == 0 && release.Released.Before(best.Released)) {
+				continue
+			}
+		}
+		best, bestConstraint = release, constraint
+	}
+	return best, best != nil
+}
@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"time"
+)
+
+const (
+
+	// WatchPollInterval environment variable setting the interval the changes are polled at when change streams are
+	// unavailable, e.g. "5s"
+	WatchPollInterval = "WATCH_POLL_INTERVAL"
+
+	// WatcherID environment variable identifying the replica the resume token of its change stream is stored for,
+	// the host name by default
+	WatcherID = "WATCHER_ID"
+
+	// DefaultWatchPollInterval the default interval the changes are polled at when change streams are unavailable
+	DefaultWatchPollInterval = 5 * time.Second
+
+	// ResumeTokensSuffix the suffix of the collection holding the resume tokens of the change streams of the replicas
+	ResumeTokensSuffix = "_resume_tokens"
+
+	// watchRetry the time waited before watching the changes again once the change stream failed
+	watchRetry = time.Second
+)
+
+// the codes of the errors of the mongo servers not supporting change streams, e.g. a standalone server
+var changeStreamsUnsupported = map[int32]bool{40573: true, 40324: true}
+
+// ChangeEvent a change of the releases or of the thresholds of a platform of an app, of the flag definitions of an app
+// when Flags is set, or of anything when All is set, e.g. when a release was deleted or changes may have been missed
+type ChangeEvent struct {
+
+	// App the app whose data changed, the default app when empty
+	App string
+
+	// Platform the platform whose data changed, every platform of the app when empty
+	Platform string
+
+	// Flags tells whether the flag definitions of the app changed rather than its releases or thresholds
+	Flags bool
+
+	// All tells whether the data of every app may have changed
+	All bool
+}
+
+// Watcher watches the changes of the releases, thresholds and flag definitions stored in mongo, whichever replica made
+// them, and pushes them to its subscribers, e.g. to refresh the statuses or the flag definitions they cached. The changes are watched through a change stream
+// resuming from the last change seen before a restart, or polled when the server does not support change streams.
+type Watcher struct {
+	repo        *MongoRepository
+	id          string
+	interval    time.Duration
+	subscribers []func(ChangeEvent)
+}
+
+// resumeToken the document holding the resume token of the change stream of a replica
+type resumeToken struct {
+	ID      string    `bson:"_id"`
+	Token   bson.Raw  `bson:"token"`
+	Updated time.Time `bson:"updated"`
+}
+
+// changeDocument the fields of the change events the subscribers are notified of
+type changeDocument struct {
+	OperationType string `bson:"operationType"`
+	Namespace     struct {
+		Collection string `bson:"coll"`
+	} `bson:"ns"`
+	FullDocument struct {
+		App      string `bson:"app"`
+		Platform string `bson:"platform"`
+	} `bson:"fullDocument"`
+}
+
+// NewWatcher creates a watcher of the changes of the data of the given repository, polling them at the given interval
+// when change streams are unavailable. The resume token is stored for the replica identified by the WATCHER_ID
+// environment variable, or by the host name.
+func NewWatcher(repo *MongoRepository, interval time.Duration) *Watcher {
+	id, err := os.Hostname()
+	if err != nil {
+		id = "default"
+	}
+	return &Watcher{repo: repo, id: GetEnv(WatcherID, id), interval: interval}
+}
+
+// Subscribe registers a function notified of every change. The subscribers are notified in turn by the goroutine
+// running the watcher, and must be registered before it runs.
+func (w *Watcher) Subscribe(subscriber func(ChangeEvent)) {
+	w.subscribers = append(w.subscribers, subscriber)
+}
+
+// Run watches the changes until the given context is done. The change stream is watched again after it fails, the
+// subscribers being told anything may have changed meanwhile, and the changes are polled from then on when the server
+// does not support change streams.
+func (w *Watcher) Run(ctx context.Context) error {
+	for {
+		err := w.stream(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var commandErr mongo.CommandError
+		if errors.As(err, &commandErr) && changeStreamsUnsupported[commandErr.Code] {
+			log.Warnf("Change streams are unavailable, polling the changes every %v: %v", w.interval, err)
+			return w.poll(ctx)
+		}
+		log.Warnf("Failed to watch the changes, watching them again in %v: %v", watchRetry, err)
+		w.notify(ChangeEvent{All: true})
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watchRetry):
+		}
+	}
+}
+
+// stream watches the changes through a change stream, resuming after the last change seen by the replica when its
+// resume token is still valid
+func (w *Watcher) stream(ctx context.Context) error {
+	db := w.repo.Client.Database(w.repo.DBInfo.Database)
+	tokens := db.Collection(w.repo.DBInfo.ResumeTokensCollection())
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"ns.coll": bson.M{"$in": w.collections()}}}}}
+
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	var saved resumeToken
+	err := tokens.FindOne(ctx, bson.M{"_id": w.id}).Decode(&saved)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+	if saved.Token != nil {
+		opts.SetResumeAfter(saved.Token)
+	}
+	stream, err := db.Watch(ctx, pipeline, opts)
+	if err != nil && saved.Token != nil {
+		log.Warnf("Failed to resume the change stream, the changes since %v are lost: %v", saved.Updated, err)
+		w.notify(ChangeEvent{All: true})
+		stream, err = db.Watch(ctx, pipeline, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	}
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var change changeDocument
+		if err := stream.Decode(&change); err != nil {
+			return err
+		}
+		switch change.OperationType {
+		case "insert", "update", "replace":
+			if change.Namespace.Collection == w.repo.DBInfo.FlagsCollection() {
+				w.notify(ChangeEvent{App: change.FullDocument.App, Flags: true})
+			} else {
+				w.notify(ChangeEvent{App: change.FullDocument.App, Platform: change.FullDocument.Platform})
+			}
+		default:
+			// the deleted documents are not known, nor what the collection held once dropped or renamed
+			w.notify(ChangeEvent{All: true})
+		}
+		_, err := tokens.ReplaceOne(ctx, bson.M{"_id": w.id},
+			resumeToken{ID: w.id, Token: stream.ResumeToken(), Updated: time.Now()}, options.Replace().SetUpsert(true))
+		if err != nil {
+			log.Warnf("Failed to store the resume token of the change stream: %v", err)
+		}
+	}
+	return stream.Err()
+}
+
+// poll polls the changes at the interval of the watcher until the given context is done. A change of the number of
+// documents, of the last document inserted into a collection or of the latest time its documents were released or
+// updated at, e.g. a flag redefined in place, is notified as a change of anything.
+func (w *Watcher) poll(ctx context.Context) error {
+	collections := w.collections()
+	last := make(map[string]bson.M)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		changed := false
+		for _, name := range collections {
+			state, err := w.state(ctx, name)
+			if err != nil {
+				log.Warnf("Failed to poll the changes of %s: %v", name, err)
+				changed = true
+				continue
+			}
+			previous, ok := last[name]
+			if ok && (previous["count"] != state["count"] || previous["last"] != state["last"] ||
+				previous["latest"] != state["latest"]) {
+				changed = true
+			}
+			last[name] = state
+		}
+		if changed {
+			w.notify(ChangeEvent{All: true})
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// collections the names of the collections watched: the releases, the thresholds and the flag definitions
+func (w *Watcher) collections() []string {
+	return []string{w.repo.DBInfo.Collection, w.repo.DBInfo.ThresholdsCollection(), w.repo.DBInfo.FlagsCollection()}
+}
+
+// timestamp the field of the documents of the given collection holding the time they were released or updated at
+func (w *Watcher) timestamp(name string) string {
+	if name == w.repo.DBInfo.FlagsCollection() {
+		return "updated"
+	}
+	return "released"
+}
+
+// state the number of documents of the given collection, the identifier of the last one inserted and the latest time
+// one was released or updated at
+func (w *Watcher) state(ctx context.Context, name string) (bson.M, error) {
+	ctx, cancel := w.repo.read(ctx)
+	defer cancel()
+	collection := w.repo.Client.Database(w.repo.DBInfo.Database).Collection(name)
+	count, err := collection.EstimatedDocumentCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	last, err := greatest(ctx, collection, "_id")
+	if err != nil {
+		return nil, err
+	}
+	latest, err := greatest(ctx, collection, w.timestamp(name))
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"count": count, "last": last, "latest": latest}, nil
+}
+
+// greatest the greatest value of the given field among the documents of the collection, nil when it is empty
+func greatest(ctx context.Context, collection *mongo.Collection, field string) (interface{}, error) {
+	var document bson.M
+	err := collection.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{field: -1}).
+		SetProjection(bson.M{field: 1})).Decode(&document)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	return document[field], nil
+}
+
+// notify notifies the subscribers of the given change
+func (w *Watcher) notify(event ChangeEvent) {
+	for _, subscriber := range w.subscribers {
+		subscriber(event)
+	}
+}
@@ -0,0 +1,80 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/akhettar/app-features-manager/model"
+	. "github.com/akhettar/app-features-manager/repository"
+	"github.com/akhettar/app-features-manager/test"
+	"testing"
+	"time"
+)
+
+// TestWatcher_ShouldNotifyTheChanges checks the writes are notified to the subscribers, through a change stream or by
+// polling when the server does not support change streams
+func TestWatcher_ShouldNotifyTheChanges(t *testing.T) {
+	if MongoClient == nil {
+		t.Skip("the changes are only watched in mongo")
+	}
+	repo := &MongoRepository{Client: MongoClient, DBInfo: DBInfo{Database: DefaultDBName, Collection: "watched"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Logf("Given a watcher of the changes of the releases and of the flags")
+	{
+		events := make(chan ChangeEvent, 100)
+		watcher := NewWatcher(repo, 50*time.Millisecond)
+		watcher.Subscribe(func(event ChangeEvent) {
+			events <- event
+		})
+		go watcher.Run(ctx)
+		time.Sleep(500 * time.Millisecond)
+
+		t.Logf("\tWhen publishing a release")
+		{
+			mustInsert(repo.ForApp("com.acme.banking"), model.ReleaseDAO{Version: "1.0.0", Platform: "ios",
+				Status: model.Supported, Released: time.Now()}, t)
+			select {
+			case event := <-events:
+				if event.All || (event.App == "com.acme.banking" && event.Platform == "ios") {
+					t.Logf("\t\tThe change should have been notified %v %v", event, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe change should have been notified %v %v", event, test.BallotX)
+				}
+			case <-time.After(5 * time.Second):
+				t.Errorf("\t\tThe change should have been notified %v", test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen defining a flag")
+		{
+			test.Ok(repo.ForApp("com.acme.banking").SaveFlag(context.Background(), model.FlagDAO{Name: "Pay",
+				Enabled: true, Updated: time.Now()}), t)
+			select {
+			case event := <-events:
+				if event.All || (event.App == "com.acme.banking" && event.Flags) {
+					t.Logf("\t\tThe change of the flags should have been notified %v %v", event, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe change of the flags should have been notified %v %v", event, test.BallotX)
+				}
+			case <-time.After(5 * time.Second):
+				t.Errorf("\t\tThe change of the flags should have been notified %v", test.BallotX)
+			}
+		}
+
+		t.Logf("\tWhen redefining the flag in place")
+		{
+			test.Ok(repo.ForApp("com.acme.banking").SaveFlag(context.Background(), model.FlagDAO{Name: "Pay",
+				Enabled: false, Updated: time.Now()}), t)
+			select {
+			case event := <-events:
+				if event.All || (event.App == "com.acme.banking" && event.Flags) {
+					t.Logf("\t\tThe edit of the flag should have been notified %v %v", event, test.CheckMark)
+				} else {
+					t.Errorf("\t\tThe edit of the flag should have been notified %v %v", event, test.BallotX)
+				}
+			case <-time.After(5 * time.Second):
+				t.Errorf("\t\tThe edit of the flag should have been notified %v", test.BallotX)
+			}
+		}
+	}
+}
@@ -7,4 +7,4 @@ mkdir -p ../mocks
 
 
 $GOPATH/bin/mockgen -destination=mocks/mock_repository.go -package=mocks github.com/akhettar/app-features-manager/repository Repository
-$GOPATH/bin/mockgen -destination=mocks/mock_unleash.go -package=mocks github.com/akhettar/app-features-manager/features UnleashService
+$GOPATH/bin/mockgen -destination=mocks/mock_provider.go -package=mocks github.com/akhettar/app-features-manager/features Provider
@@ -53,15 +53,16 @@ func Ok(err error, t *testing.T) {
 	}
 }
 
-// GetMockUnleashClient initialises an instance of the unleash mock client
-func GetMockUnleashClient(t *testing.T) *mocks.MockUnleashService {
+// GetMockProvider initialises an instance of the mock provider of the feature flags
+func GetMockProvider(t *testing.T) *mocks.MockProvider {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
-	mockUnleash := mocks.NewMockUnleashService(mockCtrl)
+	mockProvider := mocks.NewMockProvider(mockCtrl)
 	results := make(map[string]bool)
 	results["BANK_AGGREGATION"] = false
 	results["MIF"] = false
 	results["MIF_LIMITED_COMPANY"] = false
-	mockUnleash.EXPECT().FetchFeatureFlags(gomock.Any()).Return(results).AnyTimes()
-	return mockUnleash
+	mockProvider.EXPECT().FetchFeatureFlags(gomock.Any(), gomock.Any()).Return(results, nil).AnyTimes()
+	mockProvider.EXPECT().ForApp(gomock.Any()).Return(mockProvider).AnyTimes()
+	return mockProvider
 }